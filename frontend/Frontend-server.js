@@ -57,10 +57,32 @@ app.post("/api/login", async (req, res) => {
   }
 });
 
-// Add below other /api/* routes
+// Add below other /api/* routes. Forwards the user's token, orders-service
+// only lists the orders of its subject (all of them for admins)
 app.get("/api/orders", async (req, res) => {
   try {
-    const upstream = await fetch("http://orders-service:3003/orders");
+    const query = new URLSearchParams(req.query).toString();
+    const upstream = await fetch(
+      `http://orders-service:3003/orders${query ? `?${query}` : ""}`,
+      { headers: { Authorization: req.headers.authorization || "" } }
+    );
+    const data = await upstream.json();
+    res.status(upstream.status).json(data);
+  } catch (e) {
+    res.status(500).json({ error: "proxy error" });
+  }
+});
+
+// Cancellation: forwards the user's token, orders-service checks ownership
+app.post("/api/orders/:id/cancel", async (req, res) => {
+  try {
+    const upstream = await fetch(
+      `http://orders-service:3003/orders/${encodeURIComponent(req.params.id)}/cancel`,
+      {
+        method: "POST",
+        headers: { Authorization: req.headers.authorization || "" },
+      }
+    );
     const data = await upstream.json();
     res.status(upstream.status).json(data);
   } catch (e) {
@@ -80,7 +102,10 @@ app.post("/api/pay", async (req, res) => {
 
     const upstream = await fetch("http://payments-service:3002/pay", {
       method: "POST",
-      headers: { "Content-Type": "application/x-www-form-urlencoded" },
+      headers: {
+        Authorization: req.headers.authorization || "",
+        "Content-Type": "application/x-www-form-urlencoded",
+      },
       body: new URLSearchParams({
         itemName,
         itemQuantity: String(itemQuantity),
@@ -94,6 +119,58 @@ app.post("/api/pay", async (req, res) => {
   }
 });
 
+// Notification bell: forwards the user's token to notifications-service
+async function proxyNotifications(req, res, method, path, body) {
+  try {
+    const upstream = await fetch(`http://notifications-service:3004${path}`, {
+      method,
+      headers: {
+        Authorization: req.headers.authorization || "",
+        "Content-Type": "application/json",
+      },
+      body: body ? JSON.stringify(body) : undefined,
+    });
+
+    const text = await upstream.text();
+    res
+      .status(upstream.status)
+      .type(upstream.headers.get("content-type") || "text/plain")
+      .send(text);
+  } catch (e) {
+    res.status(500).json({ error: "proxy error" });
+  }
+}
+
+app.get("/api/notifications", (req, res) => {
+  const query = new URLSearchParams(req.query).toString();
+  proxyNotifications(req, res, "GET", `/notifications${query ? `?${query}` : ""}`);
+});
+
+app.get("/api/notifications/unread-count", (req, res) =>
+  proxyNotifications(req, res, "GET", "/notifications/unread-count"),
+);
+
+app.post("/api/notifications/read", (req, res) =>
+  proxyNotifications(req, res, "POST", "/notifications/read"),
+);
+
+app.post("/api/notifications/:id/read", (req, res) =>
+  proxyNotifications(
+    req,
+    res,
+    "POST",
+    `/notifications/${encodeURIComponent(req.params.id)}/read`,
+  ),
+);
+
+app.get("/api/preferences", (req, res) =>
+  proxyNotifications(req, res, "GET", "/preferences"),
+);
+
+app.put("/api/preferences", (req, res) =>
+  proxyNotifications(req, res, "PUT", "/preferences", req.body),
+);
+
 // --- Lancement du serveur HTTP ---
 const PORT = 8080;
 app.listen(PORT, "0.0.0.0", () => {
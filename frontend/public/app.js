@@ -238,12 +238,22 @@ async function checkout() {
       return;
     }
 
+    // Orders belong to the logged in user
+    const token = localStorage.getItem(AUTH_TOKEN_KEY);
+    if (!token) {
+      status.textContent = "🔒 Log in to pay";
+      return;
+    }
+
     // Send one payment call per cart item in parallel
     await Promise.all(
       entries.map(([id, qty]) =>
         fetch("/api/pay", {
           method: "POST",
-          headers: { "Content-Type": "application/json" },
+          headers: {
+            "Content-Type": "application/json",
+            Authorization: `Bearer ${token}`,
+          },
           body: JSON.stringify({ itemName: id, itemQuantity: Number(qty) }),
         }).then((res) => {
           if (!res.ok) throw new Error(`HTTP ${res.status}`);
@@ -1,5 +1,9 @@
 async function fetchOrders() {
-  const res = await fetch("/api/orders");
+  // Orders are listed for the logged in user only
+  const token = localStorage.getItem(AUTH_TOKEN_KEY);
+  const res = await fetch("/api/orders", {
+    headers: token ? { Authorization: `Bearer ${token}` } : {},
+  });
   if (!res.ok) throw new Error(`HTTP ${res.status}`);
   return await res.json();
 }
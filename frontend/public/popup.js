@@ -213,7 +213,14 @@
 
     function connect() {
       try {
-        ws = new WebSocket(wsUrl);
+        // logs-service requires the users-service JWT, sent as subprotocol
+        const token = localStorage.getItem("skateshop_auth_token");
+        if (!token) {
+          statusDot.className = "w-2 h-2 bg-red-400 rounded-full";
+          setTimeout(connect, 2000);
+          return;
+        }
+        ws = new WebSocket(wsUrl, ["bearer", token]);
 
         ws.addEventListener("open", () => {
           statusDot.className =
@@ -0,0 +1,67 @@
+package e2e
+
+import (
+	"bytes"
+	"eda-shared/kafka"
+	"encoding/json"
+	"net/http"
+	"os"
+	"testing"
+)
+
+// Generated from GET /topology, see TestAsyncAPIDocIsUpToDate
+const ASYNCAPI_DOC = "../../ressources/asyncapi.yaml"
+
+func TestAsyncAPIDocIsUpToDate(t *testing.T) {
+	h := Start(t)
+
+	info := kafka.AsyncAPIInfo{
+		Title:       "Skate shop events",
+		Version:     kafka.ASYNCAPI_DOC_VERSION,
+		Description: "Généré depuis GET /topology de chaque service: cd services/e2e && go test -run TestAsyncAPIDocIsUpToDate -update",
+	}
+	var doc bytes.Buffer
+	if err := kafka.NewAsyncAPI(info, h.Topologies()...).WriteYAML(&doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if *update {
+		if err := os.WriteFile(ASYNCAPI_DOC, doc.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	current, err := os.ReadFile(ASYNCAPI_DOC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(current, doc.Bytes()) {
+		t.Errorf("%s is out of date, run go test -run %s -update", ASYNCAPI_DOC, t.Name())
+	}
+}
+
+func TestServicesServeAsyncAPI(t *testing.T) {
+	h := Start(t)
+
+	for _, base := range h.URLs() {
+		req, err := http.NewRequest(http.MethodGet, base+"/asyncapi", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var doc kafka.AsyncAPI
+		if err := json.Unmarshal([]byte(h.do(req, http.StatusOK)), &doc); err != nil {
+			t.Fatal(err)
+		}
+		if doc.AsyncAPI != kafka.ASYNCAPI_VERSION || len(doc.Channels) == 0 {
+			t.Errorf("%s/asyncapi = %+v", base, doc)
+		}
+		// Every event type is described
+		for id, msg := range doc.Components.Messages {
+			if msg.Payload == nil || msg.Payload.Type != "object" {
+				t.Errorf("%s: message %s has no payload schema", doc.Info.Title, id)
+			}
+		}
+	}
+}
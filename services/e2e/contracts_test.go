@@ -0,0 +1,93 @@
+package e2e
+
+import (
+	"context"
+	"eda-shared/kafka"
+	"orders"
+	"slices"
+	"testing"
+)
+
+// Contracts published by the consumers, see kafka.Contract
+const CONTRACTS = "../../ressources/contracts"
+
+func TestConsumersPublishContracts(t *testing.T) {
+	h := Start(t)
+
+	contracts, err := kafka.LoadContracts(CONTRACTS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var consumed []kafka.Contract
+	for _, topology := range h.Topologies() {
+		for _, e := range topology.Endpoints {
+			if e.Role != kafka.ROLE_CONSUMER {
+				continue
+			}
+			consumed = append(consumed, kafka.Contract{Consumer: topology.Service, Topic: e.Topic, Event: e.Event})
+		}
+	}
+
+	same := func(a, b kafka.Contract) bool {
+		return a.Consumer == b.Consumer && a.Topic == b.Topic && a.Event == b.Event
+	}
+	for _, c := range consumed {
+		if !slices.ContainsFunc(contracts, func(other kafka.Contract) bool { return same(c, other) }) {
+			t.Errorf("%s reads %s from %s without a contract in %s", c.Consumer, c.Event, c.Topic, CONTRACTS)
+		}
+	}
+	for _, c := range contracts {
+		if !slices.ContainsFunc(consumed, func(other kafka.Contract) bool { return same(c, other) }) {
+			t.Errorf("contract of %s on %s for %s, which it doesn't read", c.Consumer, c.Topic, c.Event)
+		}
+	}
+}
+
+func TestEventsHonourContracts(t *testing.T) {
+	contracts, err := kafka.LoadContracts(CONTRACTS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name string
+		run  func(h *Harness)
+	}{
+		{"cancelled after reservation", func(h *Harness) {
+			h.Register("alice", "secret")
+			token := h.Login("alice", "secret")
+			orderID := h.Pay(token, "pro-street", 2)
+			h.WaitOrder(orderID, orders.STATUS_RESERVED)
+
+			h.Cancel(token, orderID)
+			h.WaitOrder(orderID, orders.STATUS_REFUNDED)
+			Await(h, "stock.released", func(e stockEvent) bool { return e.OrderID == orderID })
+			h.WaitInbox(token, "order.cancelled")
+		}},
+		{"out of stock", func(h *Harness) {
+			h.Register("bob", "secret")
+			orderID := h.Pay(h.Login("bob", "secret"), "pro-street", 100)
+			h.WaitOrder(orderID, orders.STATUS_REFUNDED)
+			Await(h, "notifications.central", notificationFor(orderID, "payment.refunded"))
+		}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			h := Start(t)
+			tt.run(h)
+			h.WaitLog("Received Notification")
+
+			topics, err := h.Broker.Topics(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			var messages []kafka.Message
+			for topic := range topics {
+				messages = append(messages, h.Broker.Messages(topic)...)
+			}
+			if err := kafka.VerifyContracts(contracts, messages...); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package e2e
+
+import (
+	"orders"
+	"testing"
+)
+
+// Events as published on the topics, only the fields the tests check
+
+type orderPlaced struct {
+	OrderID string `json:"orderId"`
+	UserID  string `json:"userId"`
+	Items   []struct {
+		SKU string `json:"sku"`
+		Qty int    `json:"qty"`
+	} `json:"items"`
+}
+
+type stockEvent struct {
+	OrderID  string `json:"orderId"`
+	Reserved []struct {
+		SKU string `json:"sku"`
+		Qty int    `json:"qty"`
+	} `json:"reserved"`
+}
+
+type notification struct {
+	Type   string         `json:"type"`
+	Params map[string]any `json:"params"`
+}
+
+func notificationFor(orderID, kind string) func(notification) bool {
+	return func(n notification) bool {
+		return n.Type == kind && n.Params["orderId"] == orderID
+	}
+}
+
+func TestPurchaseFlow(t *testing.T) {
+	h := Start(t)
+
+	h.Register("alice", "secret")
+	token := h.Login("alice", "secret")
+	orderID := h.Pay(token, "pro-street", 2)
+
+	placed := Await(h, "payment.done", func(e orderPlaced) bool { return e.OrderID == orderID })
+	if len(placed.Items) != 1 || placed.Items[0].SKU != "pro-street" || placed.Items[0].Qty != 2 {
+		t.Errorf("payment.done items = %+v", placed.Items)
+	}
+	// The order belongs to the user of the token
+	if placed.UserID != "alice" {
+		t.Errorf("payment.done user = %q, want alice", placed.UserID)
+	}
+
+	reserved := Await(h, "order.central", func(e stockEvent) bool { return e.OrderID == orderID })
+	if len(reserved.Reserved) != 1 || reserved.Reserved[0].Qty != 2 {
+		t.Errorf("stock reserved = %+v", reserved.Reserved)
+	}
+
+	o := h.WaitOrder(orderID, orders.STATUS_RESERVED)
+	if o.PaidAt == nil || o.Total != 179.98 || len(o.Reserved) != 1 || o.UserID != "alice" {
+		t.Errorf("order = %+v", o)
+	}
+
+	Await(h, "notifications.central", notificationFor(orderID, "order.created"))
+	h.WaitInbox(token, "payment.processed")
+	h.WaitInbox(token, "order.created")
+
+	h.WaitLog("User alice registered")
+	h.WaitLog("New order created: " + orderID)
+}
+
+func TestPurchaseFlowOutOfStock(t *testing.T) {
+	h := Start(t)
+
+	h.Register("bob", "secret")
+	token := h.Login("bob", "secret")
+	orderID := h.Pay(token, "pro-street", 100)
+
+	Await(h, "stock.failed", func(e stockEvent) bool { return e.OrderID == orderID })
+
+	// Cancelled for lack of stock, then refunded by payments-service
+	o := h.WaitOrder(orderID, orders.STATUS_REFUNDED)
+	if len(o.Reserved) != 0 {
+		t.Errorf("reserved %+v for an order out of stock", o.Reserved)
+	}
+
+	Await(h, "payment.refunded", func(e stockEvent) bool { return e.OrderID == orderID })
+	Await(h, "notifications.central", notificationFor(orderID, "stock.failed"))
+	h.WaitInbox(token, "stock.failed")
+	h.WaitInbox(token, "payment.refunded")
+}
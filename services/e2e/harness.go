@@ -0,0 +1,415 @@
+// Package e2e runs every service in one process, on the in-memory broker
+// and in-memory stores, to test the purchase flow end to end without
+// Docker. Each service is served by an httptest server.
+package e2e
+
+import (
+	"bufio"
+	"context"
+	"eda-shared/auth"
+	"eda-shared/kafka"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	logs "eda-logs"
+	notifications "eda-notifications"
+	payments "eda-payments"
+	users "eda-users"
+	"inventories"
+	"orders"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// Default of Harness.Timeout
+	DEFAULT_TIMEOUT = 5 * time.Second
+
+	// Key of the tokens in every service, see Token
+	SIGNING_KEY = "signing-key"
+)
+
+// Harness is one running instance of the whole system.
+type Harness struct {
+	t testing.TB
+
+	Broker *kafka.MemoryBroker
+	// Base URL of each HTTP API
+	Users         string
+	Payments      string
+	Orders        string
+	Notifications string
+	Logs          string
+	Inventories   string
+
+	// How long the Await and Wait helpers wait before failing the test
+	Timeout time.Duration
+
+	started time.Time
+}
+
+type service interface {
+	Handler() http.Handler
+	Close()
+}
+
+// Start boots every service and stops them when the test ends.
+func Start(t testing.TB) *Harness {
+	t.Helper()
+
+	broker := kafka.NewMemoryBroker(3)
+	config := kafka.Config{Broker: broker}
+
+	h := &Harness{t: t, Broker: broker, Timeout: DEFAULT_TIMEOUT, started: time.Now()}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var running sync.WaitGroup
+	var closers []func()
+
+	t.Cleanup(func() {
+		cancel()
+		running.Wait()
+		// Servers first: their handlers use the services
+		for i := len(closers) - 1; i >= 0; i-- {
+			closers[i]()
+		}
+	})
+
+	// serve starts the consumers of a service, if any, and its HTTP API
+	serve := func(name string, s service, err error, run func(context.Context) error) string {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("starting %s: %v", name, err)
+		}
+		closers = append(closers, s.Close)
+
+		if run != nil {
+			running.Go(func() {
+				// The test may be over, log instead of t.Log
+				if err := run(ctx); err != nil {
+					log.Printf("%s stopped: %v", name, err)
+				}
+			})
+		}
+
+		server := httptest.NewServer(s.Handler())
+		closers = append(closers, server.Close)
+		return server.URL
+	}
+
+	logsConfig := logs.DefaultConfig()
+	logsConfig.Kafka = config
+	logsConfig.Database = logs.NewMemoryDatabase()
+	logsConfig.SigningKey = SIGNING_KEY
+	logsService, err := logs.New(logsConfig)
+	h.Logs = serve("logs", logsService, err, logsService.Run)
+
+	notificationsConfig := notifications.DefaultConfig()
+	notificationsConfig.Kafka = config
+	notificationsConfig.SigningKey = []byte(SIGNING_KEY)
+	notificationsService, err := notifications.New(notificationsConfig)
+	h.Notifications = serve("notifications", notificationsService, err, notificationsService.Run)
+
+	ordersConfig := orders.DefaultConfig()
+	ordersConfig.Kafka = config
+	ordersConfig.Store = orders.NewMemoryStore()
+	ordersConfig.SigningKey = []byte(SIGNING_KEY)
+	ordersService, err := orders.New(ordersConfig)
+	h.Orders = serve("orders", ordersService, err, ordersService.Run)
+
+	inventoriesConfig := inventories.DefaultConfig()
+	inventoriesConfig.Kafka = config
+	inventoriesService, err := inventories.New(inventoriesConfig)
+	h.Inventories = serve("inventories", inventoriesService, err, inventoriesService.Run)
+
+	paymentsConfig := payments.DefaultConfig()
+	paymentsConfig.Kafka = config
+	paymentsConfig.Delay = 0
+	paymentsConfig.SigningKey = []byte(SIGNING_KEY)
+	paymentsService, err := payments.New(paymentsConfig)
+	h.Payments = serve("payments", paymentsService, err, paymentsService.Run)
+
+	usersService, err := users.New(users.Config{Kafka: config, Database: users.NewMemoryDatabase()})
+	h.Users = serve("users", usersService, err, nil)
+
+	return h
+}
+
+// URLs returns the base URL of every HTTP API.
+func (h *Harness) URLs() []string {
+	return []string{h.Users, h.Payments, h.Orders, h.Notifications, h.Logs, h.Inventories}
+}
+
+// Topologies returns what every service serves at GET /topology.
+func (h *Harness) Topologies() []kafka.Topology {
+	h.t.Helper()
+
+	var topologies []kafka.Topology
+	for _, base := range h.URLs() {
+		req, err := http.NewRequest(http.MethodGet, base+"/topology", nil)
+		if err != nil {
+			h.t.Fatal(err)
+		}
+
+		var topology kafka.Topology
+		if err := json.Unmarshal([]byte(h.do(req, http.StatusOK)), &topology); err != nil {
+			h.t.Fatal(err)
+		}
+		topologies = append(topologies, topology)
+	}
+	return topologies
+}
+
+// post submits a form and fails the test unless the status is want.
+func (h *Harness) post(rawURL, token string, form url.Values, want int) string {
+	h.t.Helper()
+
+	req, err := http.NewRequest(http.MethodPost, rawURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return h.do(req, want)
+}
+
+func (h *Harness) do(req *http.Request, want int) string {
+	h.t.Helper()
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	if res.StatusCode != want {
+		h.t.Fatalf("%s %s: status %d, want %d: %s", req.Method, req.URL.Path, res.StatusCode, want, body)
+	}
+	return string(body)
+}
+
+// Register creates a user on users-service.
+func (h *Harness) Register(username, password string) {
+	h.t.Helper()
+	h.post(h.Users+"/register", "", url.Values{"username": {username}, "password": {password}}, http.StatusCreated)
+}
+
+// Login returns the token of a registered user.
+func (h *Harness) Login(username, password string) string {
+	h.t.Helper()
+	return h.post(h.Users+"/login", "", url.Values{"username": {username}, "password": {password}}, http.StatusOK)
+}
+
+// Pay pays for an item on payments-service and returns the id of the
+// order, read from the payment.done it publishes.
+func (h *Harness) Pay(token, item string, quantity int) string {
+	h.t.Helper()
+
+	placed := map[string]bool{}
+	for _, id := range h.orderIDs() {
+		placed[id] = true
+	}
+
+	h.post(h.Payments+"/pay", token, url.Values{"itemName": {item}, "itemQuantity": {strconv.Itoa(quantity)}}, http.StatusOK)
+
+	// payment.done is sent before /pay answers
+	for _, id := range h.orderIDs() {
+		if !placed[id] {
+			return id
+		}
+	}
+	h.t.Fatal("no payment.done published by /pay")
+	return ""
+}
+
+func (h *Harness) orderIDs() []string {
+	var ids []string
+	for _, m := range h.Broker.Messages("payment.done") {
+		var order struct {
+			OrderID string `json:"orderId"`
+		}
+		if err := m.Decode(&order); err == nil {
+			ids = append(ids, order.OrderID)
+		}
+	}
+	return ids
+}
+
+// Cancel cancels an order on orders-service as its owner.
+func (h *Harness) Cancel(token, orderID string) {
+	h.t.Helper()
+	h.post(h.Orders+"/orders/"+orderID+"/cancel", token, nil, http.StatusOK)
+}
+
+// GetOrder returns an order from orders-service, read as admin, ok=false
+// when it doesn't exist (yet).
+func (h *Harness) GetOrder(orderID string) (o orders.Order, ok bool) {
+	h.t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, h.Orders+"/orders/"+url.PathEscape(orderID), nil)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+h.Token("e2e", "admin"))
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return o, false
+	}
+	if res.StatusCode != http.StatusOK {
+		h.t.Fatalf("GET /orders/%s: status %d", orderID, res.StatusCode)
+	}
+	if err := json.NewDecoder(res.Body).Decode(&o); err != nil {
+		h.t.Fatal(err)
+	}
+	return o, true
+}
+
+// WaitOrder polls orders-service until the order has the status.
+func (h *Harness) WaitOrder(orderID string, status orders.Status) orders.Order {
+	h.t.Helper()
+
+	deadline := time.Now().Add(h.Timeout)
+	for {
+		o, ok := h.GetOrder(orderID)
+		if ok && o.Status == status {
+			return o
+		}
+		if time.Now().After(deadline) {
+			h.t.Fatalf("order %s is %q after %s, want %q", orderID, o.Status, h.Timeout, status)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// WaitInbox polls the notifications-service inbox of the token's user until
+// it holds an item of the notification type, and returns its subject.
+func (h *Harness) WaitInbox(token, notificationType string) string {
+	h.t.Helper()
+
+	deadline := time.Now().Add(h.Timeout)
+	for {
+		req, err := http.NewRequest(http.MethodGet, h.Notifications+"/notifications", nil)
+		if err != nil {
+			h.t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		var page struct {
+			Items []struct {
+				Type    string `json:"type"`
+				Subject string `json:"subject"`
+			} `json:"items"`
+		}
+		if err := json.Unmarshal([]byte(h.do(req, http.StatusOK)), &page); err != nil {
+			h.t.Fatal(err)
+		}
+		for _, item := range page.Items {
+			if item.Type == notificationType {
+				return item.Subject
+			}
+		}
+
+		if time.Now().After(deadline) {
+			h.t.Fatalf("no %s notification in the inbox after %s: %+v", notificationType, h.Timeout, page.Items)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// Await waits for the first message of a topic that match accepts,
+// published at any time since the harness started.
+func Await[T any](h *Harness, topic string, match func(T) bool) T {
+	h.t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.Timeout)
+	defer cancel()
+
+	seen := 0
+	for {
+		messages, err := h.Broker.WaitMessages(ctx, topic, seen+1)
+		if err != nil {
+			h.t.Fatalf("no matching message: %v", err)
+		}
+
+		for _, m := range messages[seen:] {
+			var v T
+			if err := m.Decode(&v); err != nil {
+				continue
+			}
+			if match == nil || match(v) {
+				return v
+			}
+		}
+		seen = len(messages)
+	}
+}
+
+// Token signs a token like users-service does, for roles that can't
+// register such as admin.
+func (h *Harness) Token(subject, role string) string {
+	h.t.Helper()
+
+	token, err := auth.Sign([]byte(SIGNING_KEY), subject, role, time.Now().Add(time.Hour))
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	return token
+}
+
+// WaitLog reads the logs-service SSE stream as admin, from the start of
+// the harness, until a line contains text.
+func (h *Harness) WaitLog(text string) string {
+	h.t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.Timeout)
+	defer cancel()
+
+	since := primitive.NewObjectIDFromTimestamp(h.started.Add(-time.Second)).Hex()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.Logs+"/events?since="+since, nil)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+h.Token("e2e", "admin"))
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		h.t.Fatalf("GET /events: status %d", res.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(res.Body)
+	for scanner.Scan() {
+		line, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if ok && strings.Contains(line, text) {
+			return line
+		}
+	}
+	h.t.Fatalf("no log line containing %q after %s", text, h.Timeout)
+	return ""
+}
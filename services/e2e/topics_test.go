@@ -0,0 +1,42 @@
+package e2e
+
+import (
+	"context"
+	"eda-shared/kafka"
+	"maps"
+	"orders"
+	"slices"
+	"testing"
+)
+
+// Topics file used by kafka-init
+const TOPICS_SPEC = "../../ressources/topics.yaml"
+
+func TestTopicsAreDeclared(t *testing.T) {
+	specs, err := kafka.LoadTopicSpecs(TOPICS_SPEC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Some consumers only join their group once running: an order going
+	// through the whole flow shows they all are
+	h := Start(t)
+	h.Register("alice", "secret")
+	orderID := h.Pay(h.Login("alice", "secret"), "pro-street", 1)
+	h.WaitOrder(orderID, orders.STATUS_RESERVED)
+
+	topics, err := h.Broker.Topics(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	used := slices.Collect(maps.Keys(topics))
+
+	for _, topic := range specs.Undeclared(used...) {
+		t.Errorf("%s is used by the services but not declared in %s", topic, TOPICS_SPEC)
+	}
+	for _, topic := range specs.Names() {
+		if !slices.Contains(used, topic) {
+			t.Errorf("%s is declared in %s but no service uses it", topic, TOPICS_SPEC)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package e2e
+
+import (
+	"eda-shared/kafka"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the generated docs of ressources")
+
+// Generated from GET /topology, see TestTopologyDocIsUpToDate
+const TOPOLOGY_DOC = "../../ressources/topology.md"
+
+// topologyDoc renders the diagram and the table of topics of the readme.
+func topologyDoc(topologies []kafka.Topology) string {
+	var b strings.Builder
+	b.WriteString("# Topologie des événements\n\n")
+	b.WriteString("Généré depuis `GET /topology` de chaque service: `cd services/e2e && go test -run TestTopologyDocIsUpToDate -update`.\n\n")
+	fmt.Fprintf(&b, "```mermaid\n%s```\n\n", kafka.Mermaid(topologies...))
+
+	b.WriteString("| Topic | Service | Rôle | Événement | Consumer group |\n")
+	b.WriteString("| --- | --- | --- | --- | --- |\n")
+	var rows []string
+	for _, t := range topologies {
+		for _, e := range t.Endpoints {
+			rows = append(rows, fmt.Sprintf("| `%s` | %s | %s | `%s` | %s |\n", e.Topic, t.Service, e.Role, e.Event, e.Group))
+		}
+	}
+	slices.Sort(rows)
+	b.WriteString(strings.Join(rows, ""))
+	return b.String()
+}
+
+func TestTopologyDocIsUpToDate(t *testing.T) {
+	h := Start(t)
+	doc := topologyDoc(h.Topologies())
+
+	if *update {
+		if err := os.WriteFile(TOPOLOGY_DOC, []byte(doc), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	current, err := os.ReadFile(TOPOLOGY_DOC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(current) != doc {
+		t.Errorf("%s is out of date, run go test -run %s -update", TOPOLOGY_DOC, t.Name())
+	}
+}
+
+func TestTopologyMatchesTopics(t *testing.T) {
+	h := Start(t)
+
+	specs, err := kafka.LoadTopicSpecs(TOPICS_SPEC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every service declares the event type of its topics
+	var topics []string
+	for _, topology := range h.Topologies() {
+		for _, e := range topology.Endpoints {
+			if e.Event == "" {
+				t.Errorf("%s doesn't declare the events it reads from %s", topology.Service, e.Topic)
+			}
+			topics = append(topics, e.Topic)
+		}
+	}
+	for _, topic := range specs.Undeclared(topics...) {
+		t.Errorf("%s is in the topology but not declared in %s", topic, TOPICS_SPEC)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"eda-shared/env"
+	"eda-shared/kafka"
+	"inventories"
+	"log"
+	"net/http"
+	"os"
+)
+
+func main() {
+	config := inventories.DefaultConfig()
+
+	// Configuration: KAFKA_BROKER et les options de connexion du module partagé
+	var err error
+	config.Kafka, err = kafka.ConfigFromEnv("inventory-service")
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	config.TopicIn = env.Get("TOPIC_INVENTORY_IN", config.TopicIn)
+	config.TopicOK = env.Get("TOPIC_STOCK_RESERVED", config.TopicOK)
+	config.TopicFailed = env.Get("TOPIC_STOCK_FAILED", config.TopicFailed)
+	config.TopicCancelled = env.Get("TOPIC_ORDER_CANCELLED", config.TopicCancelled)
+	config.TopicReleased = env.Get("TOPIC_STOCK_RELEASED", config.TopicReleased)
+	config.TopicNotifications = env.Get("TOPIC_NOTIFICATIONS", config.TopicNotifications)
+	config.GroupID = env.Get("KAFKA_GROUP_ID", config.GroupID)
+
+	// Stock conservé dans MongoDB si INVENTORY_MONGO_URI est défini, en
+	// mémoire sinon
+	if uri := os.Getenv("INVENTORY_MONGO_URI"); uri != "" {
+		inventory, err := inventories.ConnectMongoInventory(context.Background(), uri, env.Get("INVENTORY_MONGO_DATABASE", "inventory-db"))
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if err := inventory.EnsureStock(context.Background(), inventories.DefaultStock()); err != nil {
+			log.Fatalln(err)
+		}
+		config.Inventory = inventory
+	}
+
+	service, err := inventories.New(config)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer service.Close()
+
+	go func() {
+		log.Fatal(http.ListenAndServe(inventories.PORT, service.Handler()))
+	}()
+
+	err = service.Run(context.Background())
+	if err != nil {
+		log.Fatalf("Error consuming %s: %v\n", config.TopicIn, err)
+	}
+}
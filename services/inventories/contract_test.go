@@ -0,0 +1,78 @@
+package inventories
+
+import (
+	"eda-shared/kafka"
+	"encoding/json"
+	"flag"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "réécrit les contrats publiés dans ressources/contracts")
+
+// Contrats des consumers, voir kafka.Contract
+const CONTRACTS = "../../ressources/contracts"
+
+// Ce qu'inventory-service lit des événements qu'il consomme
+func consumerContracts(t *testing.T) []kafka.Contract {
+	t.Helper()
+
+	placed, err := kafka.NewContract[OrderPlaced]("inventory-service", testTopicIn,
+		`{"orderId": "123", "userId": "456", "items": [{"sku": "pro-street", "qty": 3}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancelled, err := kafka.NewContract[OrderCancelled]("inventory-service", testTopicCancelled,
+		`{"orderId": "123", "reason": "customer"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []kafka.Contract{placed, cancelled}
+}
+
+func TestConsumerContracts(t *testing.T) {
+	ctx, config, broker, _ := startInventory(t, map[string]int{"pro-street": 10})
+	contracts := consumerContracts(t)
+
+	// Les exemples réservent puis rendent le stock
+	for _, c := range contracts {
+		for _, m := range c.Messages() {
+			publish(t, ctx, config, c.Topic, "123", json.RawMessage(m.Value))
+		}
+	}
+	if _, err := broker.WaitMessages(ctx, testTopicReleased, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := kafka.PublishContracts(CONTRACTS, *update, contracts...); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestProducerContracts(t *testing.T) {
+	ctx, config, broker, _ := startInventory(t, map[string]int{"pro-street": 10})
+
+	// Réservation puis annulation, stock insuffisant puis annulation
+	publish(t, ctx, config, testTopicIn, "o-1", OrderPlaced{OrderID: "o-1", UserID: "u-1", Items: []Item{{SKU: "pro-street", Qty: 3}}, Total: 90})
+	publish(t, ctx, config, testTopicCancelled, "o-1", OrderCancelled{OrderID: "o-1", Reason: "customer"})
+	publish(t, ctx, config, testTopicIn, "o-2", OrderPlaced{OrderID: "o-2", UserID: "u-1", Items: []Item{{SKU: "pro-street", Qty: 30}}, Total: 900})
+	if _, err := broker.WaitMessages(ctx, testTopicFailed, 1); err != nil {
+		t.Fatal(err)
+	}
+	publish(t, ctx, config, testTopicCancelled, "o-2", OrderCancelled{OrderID: "o-2", Reason: "insufficient stock"})
+	if _, err := broker.WaitMessages(ctx, testTopicReleased, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	topics := []string{testTopicOK, testTopicFailed, testTopicReleased, testTopicNotifications}
+	contracts, err := kafka.LoadContracts(CONTRACTS, topics...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var messages []kafka.Message
+	for _, topic := range topics {
+		messages = append(messages, broker.Messages(topic)...)
+	}
+	if err := kafka.VerifyContracts(contracts, messages...); err != nil {
+		t.Error(err)
+	}
+}
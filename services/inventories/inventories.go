@@ -0,0 +1,319 @@
+// Package inventories est inventory-service: il réserve le stock des
+// commandes payées et le rend à leur annulation. cmd/inventories le lance
+// avec la configuration de l'environnement, les tests dans le processus.
+package inventories
+
+import (
+	"context"
+	"eda-shared/kafka"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	PORT = ":3005"
+)
+
+type Item struct {
+	SKU string `json:"sku" example:"elite-deck"`
+	Qty int    `json:"qty" example:"2"`
+}
+
+// Structure attendue pour l'événement 'payment.done' (qui déclenche la Tâche 1.5)
+type OrderPlaced struct {
+	OrderID string  `json:"orderId"`
+	UserID  string  `json:"userId"`
+	Items   []Item  `json:"items"`
+	Total   float64 `json:"total"`
+}
+
+type Missing struct {
+	SKU       string `json:"sku" example:"elite-deck"`
+	Required  int    `json:"required" example:"10"`
+	Available int    `json:"available" example:"8"`
+}
+
+type StockReserved struct { // Événement de sortie: stock.reserve
+	OrderID   string `json:"orderId" example:"5f2b8c1e9a7d4e3f8b6a0c2d4e6f8a1b"`
+	Reserved  []Item `json:"reserved"`
+	Timestamp string `json:"timestamp" example:"2025-06-01T10:00:00Z"`
+}
+
+// Événement d'entrée: order.cancelled (publié par orders-service)
+type OrderCancelled struct {
+	OrderID string `json:"orderId"`
+	Reason  string `json:"reason"`
+}
+
+type StockReleased struct { // Événement de sortie: stock.released
+	OrderID   string `json:"orderId" example:"5f2b8c1e9a7d4e3f8b6a0c2d4e6f8a1b"`
+	Released  []Item `json:"released"`
+	Timestamp string `json:"timestamp" example:"2025-06-01T10:00:00Z"`
+}
+
+type StockFailed struct { // Événement de sortie: stock.echec
+	OrderID   string    `json:"orderId" example:"5f2b8c1e9a7d4e3f8b6a0c2d4e6f8a1b"`
+	Reason    string    `json:"reason" example:"insufficient stock"`
+	Missing   []Missing `json:"missing"`
+	Timestamp string    `json:"timestamp" example:"2025-06-01T10:00:00Z"`
+}
+
+// Types de notification
+const (
+	STOCK_RESERVED = "stock.reserved"
+	STOCK_FAILED   = "stock.failed"
+)
+
+// Notification rendue par notifications-service à partir du type et des paramètres
+type Notification struct {
+	// Identifiant unique de l'événement, dérivé de la commande: un payment.done
+	// retraité produit le même et notifications-service l'ignore
+	EventID string         `json:"event_id" example:"stock.reserved:5f2b8c1e9a7d4e3f8b6a0c2d4e6f8a1b"`
+	Type    string         `json:"type" example:"stock.reserved"`
+	UserID  string         `json:"user_id,omitempty" example:"456"`
+	Params  map[string]any `json:"params,omitempty" example:"{\"orderId\":\"5f2b8c1e9a7d4e3f8b6a0c2d4e6f8a1b\"}"`
+}
+
+// Catalogue de départ
+func DefaultStock() map[string]int {
+	return map[string]int{
+		"pro-street":   10,
+		"elite-deck":   8,
+		"sunset-rider": 5,
+		"park-master":  12,
+	}
+}
+
+// Consomme order.cancelled et publie stock.released
+func (s *Service) consumeCancellations(ctx context.Context) error {
+	return kafka.Run(ctx, s.rCancelled, func(ctx context.Context, m kafka.Message, evt OrderCancelled) error {
+		if evt.OrderID == "" {
+			log.Printf("Unrecognized message: %s", string(m.Value))
+			return nil
+		}
+
+		released, ok, err := s.inventory.Release(ctx, evt.OrderID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			log.Printf("Order %s already released. Idempotence success.", evt.OrderID)
+			return nil
+		}
+		log.Printf("Order %s cancelled (%s), released: %v", evt.OrderID, evt.Reason, released)
+		if released == nil {
+			// Rien n'était réservé (stock insuffisant): une liste vide, pas null
+			released = []Item{}
+		}
+
+		out := StockReleased{
+			OrderID:   evt.OrderID,
+			Released:  released,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		}
+		if err := s.w.released.Send(ctx, evt.OrderID, out); err != nil {
+			log.Printf("Error writing stock.released: %v", err)
+		}
+		return nil
+	})
+}
+
+// Producteurs des événements de stock
+type producers struct {
+	reserved      *kafka.Producer[StockReserved]
+	failed        *kafka.Producer[StockFailed]
+	released      *kafka.Producer[StockReleased]
+	notifications *kafka.Producer[Notification]
+}
+
+func newProducers(config kafka.Config, topicOK, topicFailed, topicReleased, topicNotifications string) (w producers, err error) {
+	if w.reserved, err = kafka.NewProducer[StockReserved](config, kafka.DefaultProducerConfig(topicOK)); err != nil {
+		return w, err
+	}
+	if w.failed, err = kafka.NewProducer[StockFailed](config, kafka.DefaultProducerConfig(topicFailed)); err != nil {
+		return w, err
+	}
+	if w.released, err = kafka.NewProducer[StockReleased](config, kafka.DefaultProducerConfig(topicReleased)); err != nil {
+		return w, err
+	}
+	w.notifications, err = kafka.NewProducer[Notification](config, kafka.DefaultProducerConfig(topicNotifications))
+	return w, err
+}
+
+func (w producers) Close() {
+	_ = w.reserved.Close()
+	_ = w.failed.Close()
+	_ = w.released.Close()
+	_ = w.notifications.Close()
+}
+
+// Consomme payment.done: réserve le stock et publie stock.reserve ou stock.echec
+func (s *Service) handlePaymentDone(ctx context.Context, m kafka.Message, evt OrderPlaced) error {
+	log.Println("Received message: ", string(m.Value))
+	// Événement payment.done sans commande
+	if evt.OrderID == "" {
+		log.Printf("Unrecognized message: %s", string(m.Value))
+		return nil
+	}
+
+	log.Printf("Received payment.done for order %s. Processing stock check...", evt.OrderID)
+
+	// Logique de la Tâche 1.5: Vérifie et réserve le stock
+	ok, reserved, missing, err := s.inventory.Reserve(ctx, evt.OrderID, evt.Items)
+	if err != nil {
+		return err
+	}
+
+	if !ok && len(missing) == 0 {
+		// Commande annulée entre-temps
+		log.Printf("Order %s already cancelled. Skipping reservation.", evt.OrderID)
+		return nil
+	}
+
+	if ok {
+		// Stock réservé: Produit stock.reserve
+		out := StockReserved{
+			OrderID:   evt.OrderID,
+			Reserved:  reserved,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		}
+		if err := s.w.reserved.Send(ctx, evt.OrderID, out); err != nil {
+			log.Printf("Error writing stock.reserve: %v", err)
+		} else {
+			log.Printf("stock.reserve sent for order %s", evt.OrderID)
+		}
+
+		notif := Notification{EventID: STOCK_RESERVED + ":" + evt.OrderID, Type: STOCK_RESERVED, UserID: evt.UserID, Params: map[string]any{"orderId": evt.OrderID}}
+		if err := s.w.notifications.Send(ctx, evt.OrderID, notif); err != nil {
+			log.Printf("Error writing notification: %v\n", err)
+		}
+	} else {
+		// Stock insuffisant: Produit stock.echec
+		out := StockFailed{
+			OrderID:   evt.OrderID,
+			Reason:    "insufficient stock",
+			Missing:   missing,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		}
+		if err := s.w.failed.Send(ctx, evt.OrderID, out); err != nil {
+			log.Printf("Error writing stock.echec: %v", err)
+		} else {
+			log.Printf("stock.echec sent for order %s", evt.OrderID)
+		}
+
+		notif := Notification{EventID: STOCK_FAILED + ":" + evt.OrderID, Type: STOCK_FAILED, UserID: evt.UserID, Params: map[string]any{"orderId": evt.OrderID, "missing": missing}}
+		if err := s.w.notifications.Send(ctx, evt.OrderID, notif); err != nil {
+			log.Printf("Error writing notification: %v\n", err)
+		}
+	}
+	return nil
+}
+
+type Config struct {
+	Kafka kafka.Config
+
+	// Tâche 1.5: Consomme l'événement 'payment.done'
+	TopicIn string
+	// Tâche 1.5: Produit les événements 'stock.reserve' et 'stock.echec'
+	TopicOK     string
+	TopicFailed string
+	// Annulations: rend le stock réservé
+	TopicCancelled     string
+	TopicReleased      string
+	TopicNotifications string
+	GroupID            string
+
+	// MemoryInventory sur Stock si nil
+	Inventory Inventory
+	// Stock de départ, DefaultStock si nil
+	Stock map[string]int
+}
+
+func DefaultConfig() Config {
+	return Config{
+		TopicIn:            "payment.done",
+		TopicOK:            "order.central",
+		TopicFailed:        "stock.failed",
+		TopicCancelled:     "order.cancelled",
+		TopicReleased:      "stock.released",
+		TopicNotifications: "notifications.central",
+		GroupID:            "inventory-group",
+	}
+}
+
+type Service struct {
+	config Config
+	// Stock et réservations, voir Config.Inventory
+	inventory  Inventory
+	r          *kafka.Consumer
+	rCancelled *kafka.Consumer
+	w          producers
+	mux        *http.ServeMux
+}
+
+func New(config Config) (*Service, error) {
+	if config.Kafka.Registry == nil {
+		config.Kafka.Registry = kafka.NewRegistry("inventory-service")
+	}
+
+	s := &Service{config: config, inventory: config.Inventory, mux: http.NewServeMux()}
+	if s.inventory == nil {
+		initial := config.Stock
+		if initial == nil {
+			initial = DefaultStock()
+		}
+		s.inventory = NewMemoryInventory(initial)
+	}
+	s.mux.Handle("/topology", config.Kafka.Registry)
+	s.mux.Handle("/asyncapi", config.Kafka.Registry.AsyncAPIHandler())
+	var err error
+
+	// Kafka consumers
+	cc := kafka.DefaultConsumerConfig(config.GroupID, config.TopicIn) // Écoute payment.done
+	cc.MinBytes = 10e3
+	if s.r, err = kafka.NewConsumer(config.Kafka, cc); err != nil {
+		return nil, err
+	}
+	kafka.Consumes[OrderPlaced](config.Kafka.Registry, config.GroupID, config.TopicIn)
+
+	cc = kafka.DefaultConsumerConfig(config.GroupID, config.TopicCancelled)
+	cc.MinBytes = 10e3
+	if s.rCancelled, err = kafka.NewConsumer(config.Kafka, cc); err != nil {
+		_ = s.r.Close()
+		return nil, err
+	}
+	kafka.Consumes[OrderCancelled](config.Kafka.Registry, config.GroupID, config.TopicCancelled)
+
+	// Kafka producers
+	if s.w, err = newProducers(config.Kafka, config.TopicOK, config.TopicFailed, config.TopicReleased, config.TopicNotifications); err != nil {
+		_ = s.r.Close()
+		_ = s.rCancelled.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Handler sert GET /topology et GET /asyncapi, inventory-service n'a pas d'autre API
+func (s *Service) Handler() http.Handler {
+	return s.mux
+}
+
+// Run consomme les paiements et les annulations jusqu'à l'annulation de ctx
+func (s *Service) Run(ctx context.Context) error {
+	go func() {
+		if err := s.consumeCancellations(ctx); err != nil {
+			log.Printf("Error consuming %s: %v\n", s.config.TopicCancelled, err)
+		}
+	}()
+
+	log.Printf("Inventory service started (listening on: %s, %s)\n", s.config.TopicIn, s.config.TopicCancelled)
+
+	return kafka.Run(ctx, s.r, s.handlePaymentDone)
+}
+
+func (s *Service) Close() {
+	_ = s.r.Close()
+	_ = s.rCancelled.Close()
+	s.w.Close()
+}
@@ -0,0 +1,181 @@
+package inventories
+
+import (
+	"context"
+	"eda-shared/kafka"
+	"testing"
+	"time"
+)
+
+const (
+	testTopicIn            = "payment.done"
+	testTopicOK            = "order.central"
+	testTopicFailed        = "stock.failed"
+	testTopicCancelled     = "order.cancelled"
+	testTopicReleased      = "stock.released"
+	testTopicNotifications = "notifications.central"
+)
+
+func stockOf(s *Service, sku string) int {
+	n, _ := s.inventory.Stock(context.Background(), sku)
+	return n
+}
+
+// Lance inventory-service sur un broker en mémoire avec le stock initial
+func startInventory(t *testing.T, initial map[string]int) (context.Context, kafka.Config, *kafka.MemoryBroker, *Service) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	broker := kafka.NewMemoryBroker(3)
+	config := DefaultConfig()
+	config.Kafka = kafka.Config{Broker: broker}
+	config.Stock = initial
+
+	s, err := New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Close)
+	go s.Run(ctx)
+
+	return ctx, config.Kafka, broker, s
+}
+
+func publish[T any](t *testing.T, ctx context.Context, config kafka.Config, topic, key string, value T) {
+	t.Helper()
+
+	p, err := kafka.NewProducer[T](config, kafka.DefaultProducerConfig(topic))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	if err := p.Send(ctx, key, value); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func decode[T any](t *testing.T, m kafka.Message) T {
+	t.Helper()
+
+	var v T
+	if err := m.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestPaymentDoneReservesStock(t *testing.T) {
+	ctx, config, broker, s := startInventory(t, map[string]int{"pro-street": 10})
+
+	order := OrderPlaced{OrderID: "o-1", UserID: "u-1", Items: []Item{{SKU: "pro-street", Qty: 3}}, Total: 90}
+	publish(t, ctx, config, testTopicIn, order.OrderID, order)
+
+	messages, err := broker.WaitMessages(ctx, testTopicOK, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reserved := decode[StockReserved](t, messages[0])
+	if reserved.OrderID != "o-1" || len(reserved.Reserved) != 1 || reserved.Reserved[0].Qty != 3 {
+		t.Errorf("stock reserved = %+v", reserved)
+	}
+	if messages[0].Key != "o-1" {
+		t.Errorf("key = %q, want the order id", messages[0].Key)
+	}
+
+	notifications, err := broker.WaitMessages(ctx, testTopicNotifications, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notif := decode[Notification](t, notifications[0])
+	if notif.Type != STOCK_RESERVED || notif.UserID != "u-1" || notif.EventID != STOCK_RESERVED+":o-1" {
+		t.Errorf("notification = %+v", notif)
+	}
+
+	if n := stockOf(s, "pro-street"); n != 7 {
+		t.Errorf("stock = %d, want 7", n)
+	}
+}
+
+func TestPaymentDoneRedeliveryReservesOnce(t *testing.T) {
+	ctx, config, broker, s := startInventory(t, map[string]int{"pro-street": 10})
+
+	order := OrderPlaced{OrderID: "o-1", UserID: "u-1", Items: []Item{{SKU: "pro-street", Qty: 3}}}
+	publish(t, ctx, config, testTopicIn, order.OrderID, order)
+	publish(t, ctx, config, testTopicIn, order.OrderID, order)
+
+	if _, err := broker.WaitMessages(ctx, testTopicOK, 2); err != nil {
+		t.Fatal(err)
+	}
+	if n := stockOf(s, "pro-street"); n != 7 {
+		t.Errorf("stock = %d, want 7", n)
+	}
+}
+
+func TestPaymentDoneInsufficientStock(t *testing.T) {
+	ctx, config, broker, s := startInventory(t, map[string]int{"pro-street": 1})
+
+	order := OrderPlaced{OrderID: "o-1", UserID: "u-1", Items: []Item{{SKU: "pro-street", Qty: 3}}}
+	publish(t, ctx, config, testTopicIn, order.OrderID, order)
+
+	messages, err := broker.WaitMessages(ctx, testTopicFailed, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed := decode[StockFailed](t, messages[0])
+	if len(failed.Missing) != 1 || failed.Missing[0] != (Missing{SKU: "pro-street", Required: 3, Available: 1}) {
+		t.Errorf("stock failed = %+v", failed)
+	}
+
+	notifications, err := broker.WaitMessages(ctx, testTopicNotifications, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if notif := decode[Notification](t, notifications[0]); notif.Type != STOCK_FAILED {
+		t.Errorf("notification type = %s, want %s", notif.Type, STOCK_FAILED)
+	}
+
+	if n := stockOf(s, "pro-street"); n != 1 {
+		t.Errorf("stock = %d, want 1", n)
+	}
+	if len(broker.Messages(testTopicOK)) != 0 {
+		t.Error("stock reserved published for a failed order")
+	}
+}
+
+func TestCancellationReleasesStock(t *testing.T) {
+	ctx, config, broker, s := startInventory(t, map[string]int{"pro-street": 10})
+
+	order := OrderPlaced{OrderID: "o-1", UserID: "u-1", Items: []Item{{SKU: "pro-street", Qty: 3}}}
+	publish(t, ctx, config, testTopicIn, order.OrderID, order)
+	if _, err := broker.WaitMessages(ctx, testTopicOK, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	cancel := OrderCancelled{OrderID: "o-1", Reason: "customer"}
+	publish(t, ctx, config, testTopicCancelled, cancel.OrderID, cancel)
+	publish(t, ctx, config, testTopicCancelled, cancel.OrderID, cancel)
+
+	messages, err := broker.WaitMessages(ctx, testTopicReleased, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	released := decode[StockReleased](t, messages[0])
+	if len(released.Released) != 1 || released.Released[0].Qty != 3 {
+		t.Errorf("stock released = %+v", released)
+	}
+
+	if err := broker.WaitConsumed(ctx, "inventory-group", testTopicCancelled); err != nil {
+		t.Fatal(err)
+	}
+
+	// La seconde annulation est ignorée, le stock n'est rendu qu'une fois
+	if n := stockOf(s, "pro-street"); n != 10 {
+		t.Errorf("stock = %d, want 10", n)
+	}
+	if n := len(broker.Messages(testTopicReleased)); n != 1 {
+		t.Errorf("%d stock released events, want 1", n)
+	}
+}
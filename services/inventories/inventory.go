@@ -0,0 +1,103 @@
+package inventories
+
+import (
+	"context"
+	"maps"
+	"sync"
+)
+
+// Inventory enregistre le stock et les réservations par commande:
+// MemoryInventory par défaut, MongoInventory pour conserver le stock entre
+// deux démarrages. Les deux passent la même suite de conformité
+// (inventory_test.go).
+type Inventory interface {
+	// Reserve réserve tous les articles de la commande ou aucun. Idempotent:
+	// une commande déjà réservée retourne ok=true sans toucher au stock. Une
+	// commande déjà annulée ne réserve rien (ok=false, missing vide).
+	Reserve(ctx context.Context, orderID string, items []Item) (ok bool, reserved []Item, missing []Missing, err error)
+	// Release annule la commande et rend au stock ce qu'elle avait réservé.
+	// Idempotent: ok=false si la commande avait déjà été annulée.
+	Release(ctx context.Context, orderID string) (released []Item, ok bool, err error)
+	// Stock retourne la quantité disponible d'un article
+	Stock(ctx context.Context, sku string) (int, error)
+}
+
+// MemoryInventory garde le stock en mémoire, perdu à l'arrêt du service
+type MemoryInventory struct {
+	mu    sync.Mutex
+	stock map[string]int
+	// Quantités réservées par commande, rendues au stock à l'annulation
+	reservations map[string][]Item
+	// Commandes annulées: un payment.done reçu ensuite ne réserve rien
+	cancelled map[string]bool
+}
+
+func NewMemoryInventory(stock map[string]int) *MemoryInventory {
+	return &MemoryInventory{
+		stock:        maps.Clone(stock),
+		reservations: map[string][]Item{},
+		cancelled:    map[string]bool{},
+	}
+}
+
+func (inv *MemoryInventory) Reserve(ctx context.Context, orderID string, items []Item) (ok bool, reserved []Item, missing []Missing, err error) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	// Commande annulée avant la réservation: rien à réserver
+	if inv.cancelled[orderID] {
+		return false, nil, nil, nil
+	}
+
+	// Vérification d'idempotence: si la commande a déjà été traitée, on retourne true
+	if reserved, seen := inv.reservations[orderID]; seen {
+		return true, reserved, nil, nil
+	}
+
+	// 1. Vérifie le stock
+	for _, it := range items {
+		if avail := inv.stock[it.SKU]; avail < it.Qty {
+			missing = append(missing, Missing{
+				SKU: it.SKU, Required: it.Qty, Available: avail,
+			})
+		}
+	}
+
+	// Si stock insuffisant, retourne l'échec
+	if len(missing) > 0 {
+		return false, nil, missing, nil
+	}
+
+	// 2. Réserve le stock
+	for _, it := range items {
+		inv.stock[it.SKU] -= it.Qty
+		reserved = append(reserved, it)
+	}
+
+	inv.reservations[orderID] = reserved
+	return true, reserved, nil, nil
+}
+
+func (inv *MemoryInventory) Release(ctx context.Context, orderID string) (released []Item, ok bool, err error) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	if inv.cancelled[orderID] {
+		return nil, false, nil
+	}
+	inv.cancelled[orderID] = true
+
+	released = inv.reservations[orderID]
+	for _, it := range released {
+		inv.stock[it.SKU] += it.Qty
+	}
+	delete(inv.reservations, orderID)
+	return released, true, nil
+}
+
+func (inv *MemoryInventory) Stock(ctx context.Context, sku string) (int, error) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	return inv.stock[sku], nil
+}
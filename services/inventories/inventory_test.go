@@ -0,0 +1,196 @@
+package inventories
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// testInventory est la suite de conformité d'Inventory: open retourne un
+// inventaire sans réservation avec le stock initial
+func testInventory(t *testing.T, open func(t *testing.T, initial map[string]int) Inventory) {
+	ctx := context.Background()
+
+	checkStock := func(t *testing.T, inv Inventory, sku string, want int) {
+		t.Helper()
+		n, err := inv.Stock(ctx, sku)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != want {
+			t.Errorf("stock of %s = %d, want %d", sku, n, want)
+		}
+	}
+
+	t.Run("Reserve", func(t *testing.T) {
+		inv := open(t, map[string]int{"pro-street": 10, "elite-deck": 8})
+
+		items := []Item{{SKU: "pro-street", Qty: 3}, {SKU: "elite-deck", Qty: 8}}
+		ok, reserved, missing, err := inv.Reserve(ctx, "o-1", items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok || len(reserved) != 2 || len(missing) != 0 {
+			t.Errorf("reserve = %v %v %v", ok, reserved, missing)
+		}
+
+		checkStock(t, inv, "pro-street", 7)
+		checkStock(t, inv, "elite-deck", 0)
+	})
+
+	t.Run("ReserveIsIdempotent", func(t *testing.T) {
+		inv := open(t, map[string]int{"pro-street": 10})
+
+		items := []Item{{SKU: "pro-street", Qty: 3}}
+		for range 2 {
+			ok, reserved, _, err := inv.Reserve(ctx, "o-1", items)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !ok || len(reserved) != 1 || reserved[0] != items[0] {
+				t.Errorf("reserve = %v %v", ok, reserved)
+			}
+		}
+
+		checkStock(t, inv, "pro-street", 7)
+	})
+
+	t.Run("ReserveAllOrNothing", func(t *testing.T) {
+		inv := open(t, map[string]int{"pro-street": 10, "elite-deck": 1})
+
+		items := []Item{{SKU: "pro-street", Qty: 3}, {SKU: "elite-deck", Qty: 2}, {SKU: "unknown", Qty: 1}}
+		ok, reserved, missing, err := inv.Reserve(ctx, "o-1", items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok || len(reserved) != 0 {
+			t.Errorf("reserve = %v %v", ok, reserved)
+		}
+
+		want := []Missing{{SKU: "elite-deck", Required: 2, Available: 1}, {SKU: "unknown", Required: 1, Available: 0}}
+		if fmt.Sprint(missing) != fmt.Sprint(want) {
+			t.Errorf("missing = %v, want %v", missing, want)
+		}
+
+		checkStock(t, inv, "pro-street", 10)
+		checkStock(t, inv, "elite-deck", 1)
+	})
+
+	t.Run("ReleaseOnce", func(t *testing.T) {
+		inv := open(t, map[string]int{"pro-street": 10})
+
+		if _, _, _, err := inv.Reserve(ctx, "o-1", []Item{{SKU: "pro-street", Qty: 3}}); err != nil {
+			t.Fatal(err)
+		}
+
+		released, ok, err := inv.Release(ctx, "o-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok || len(released) != 1 || released[0].Qty != 3 {
+			t.Errorf("release = %v %v", released, ok)
+		}
+
+		released, ok, err = inv.Release(ctx, "o-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok || len(released) != 0 {
+			t.Errorf("second release = %v %v", released, ok)
+		}
+
+		checkStock(t, inv, "pro-street", 10)
+	})
+
+	t.Run("ReleaseBeforeReserve", func(t *testing.T) {
+		inv := open(t, map[string]int{"pro-street": 10})
+
+		released, ok, err := inv.Release(ctx, "o-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok || len(released) != 0 {
+			t.Errorf("release = %v %v", released, ok)
+		}
+
+		// payment.done reçu après l'annulation
+		ok, reserved, missing, err := inv.Reserve(ctx, "o-1", []Item{{SKU: "pro-street", Qty: 3}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok || len(reserved) != 0 || len(missing) != 0 {
+			t.Errorf("reserve = %v %v %v", ok, reserved, missing)
+		}
+
+		checkStock(t, inv, "pro-street", 10)
+	})
+
+	t.Run("ConcurrentReservesNeverOversell", func(t *testing.T) {
+		inv := open(t, map[string]int{"pro-street": 5})
+
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		reservedOrders := 0
+		for i := range 8 {
+			wg.Go(func() {
+				ok, _, _, err := inv.Reserve(ctx, fmt.Sprintf("o-%d", i), []Item{{SKU: "pro-street", Qty: 2}})
+				if err != nil {
+					t.Error(err)
+				}
+				if ok {
+					mu.Lock()
+					reservedOrders++
+					mu.Unlock()
+				}
+			})
+		}
+		wg.Wait()
+
+		if reservedOrders != 2 {
+			t.Errorf("%d orders reserved, want 2", reservedOrders)
+		}
+		checkStock(t, inv, "pro-street", 1)
+	})
+}
+
+func TestMemoryInventory(t *testing.T) {
+	testInventory(t, func(t *testing.T, initial map[string]int) Inventory {
+		return NewMemoryInventory(initial)
+	})
+}
+
+// TestMongoInventory a besoin d'un serveur MongoDB, par exemple
+// MONGO_TEST_URI=mongodb://localhost:27017
+func TestMongoInventory(t *testing.T) {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI not set")
+	}
+
+	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = client.Disconnect(context.Background())
+	})
+
+	testInventory(t, func(t *testing.T, initial map[string]int) Inventory {
+		db := client.Database(fmt.Sprintf("inventory_db_test_%d", time.Now().UnixNano()))
+		t.Cleanup(func() {
+			_ = db.Drop(context.Background())
+		})
+
+		inv := NewMongoInventory(db)
+		if err := inv.EnsureStock(context.Background(), initial); err != nil {
+			t.Fatal(err)
+		}
+		return inv
+	})
+}
@@ -0,0 +1,209 @@
+package inventories
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// États d'une réservation
+const (
+	RESERVATION_RESERVED  = "reserved"
+	RESERVATION_CANCELLED = "cancelled"
+)
+
+// Quantité disponible d'un article, un document par SKU
+type stockDocument struct {
+	SKU string `bson:"_id"`
+	Qty int    `bson:"qty"`
+}
+
+// Réservation d'une commande, un document par orderId. L'index unique sur
+// _id départage une réservation et une annulation concurrentes.
+type reservationDocument struct {
+	OrderID string `bson:"_id"`
+	Status  string `bson:"status"`
+	Items   []Item `bson:"items,omitempty"`
+}
+
+// MongoInventory enregistre le stock et les réservations dans MongoDB, sans
+// transaction: chaque article est décrémenté par une mise à jour
+// conditionnelle (qty >= quantité demandée) et les décréments déjà faits
+// sont annulés si un article manque.
+type MongoInventory struct {
+	stock        *mongo.Collection
+	reservations *mongo.Collection
+}
+
+func NewMongoInventory(db *mongo.Database) *MongoInventory {
+	return &MongoInventory{
+		stock:        db.Collection("stock"),
+		reservations: db.Collection("reservations"),
+	}
+}
+
+// ConnectMongoInventory se connecte à uri et utilise la base database
+func ConnectMongoInventory(ctx context.Context, uri, database string) (*MongoInventory, error) {
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := client.Ping(ctx, nil); err != nil {
+		return nil, err
+	}
+
+	log.Println("Connected to MongoDB")
+	return NewMongoInventory(client.Database(database)), nil
+}
+
+// EnsureStock crée les articles absents avec leur quantité initiale, le
+// stock des articles existants est conservé
+func (inv *MongoInventory) EnsureStock(ctx context.Context, initial map[string]int) error {
+	for sku, qty := range initial {
+		_, err := inv.stock.UpdateOne(ctx,
+			bson.M{"_id": sku},
+			bson.M{"$setOnInsert": bson.M{"qty": qty}},
+			options.Update().SetUpsert(true),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reservation retourne la réservation de la commande, nil s'il n'y en a pas
+func (inv *MongoInventory) reservation(ctx context.Context, orderID string) (*reservationDocument, error) {
+	var doc reservationDocument
+	err := inv.reservations.FindOne(ctx, bson.M{"_id": orderID}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// restock rend les quantités au stock
+func (inv *MongoInventory) restock(ctx context.Context, items []Item) error {
+	for _, it := range items {
+		_, err := inv.stock.UpdateOne(ctx, bson.M{"_id": it.SKU}, bson.M{"$inc": bson.M{"qty": it.Qty}})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (inv *MongoInventory) Reserve(ctx context.Context, orderID string, items []Item) (ok bool, reserved []Item, missing []Missing, err error) {
+	doc, err := inv.reservation(ctx, orderID)
+	if err != nil {
+		return false, nil, nil, err
+	}
+	if doc != nil {
+		// Déjà réservée (idempotence) ou annulée
+		return doc.Status == RESERVATION_RESERVED, doc.Items, nil, nil
+	}
+
+	// Décrémente chaque article s'il en reste assez
+	for _, it := range items {
+		res, err := inv.stock.UpdateOne(ctx,
+			bson.M{"_id": it.SKU, "qty": bson.M{"$gte": it.Qty}},
+			bson.M{"$inc": bson.M{"qty": -it.Qty}},
+		)
+		if err == nil && res.MatchedCount == 1 {
+			reserved = append(reserved, it)
+			continue
+		}
+
+		// Stock insuffisant ou erreur: rend ce qui a déjà été pris
+		if rerr := inv.restock(ctx, reserved); rerr != nil {
+			return false, nil, nil, rerr
+		}
+		if err != nil {
+			return false, nil, nil, err
+		}
+
+		missing, err := inv.missing(ctx, items)
+		return false, nil, missing, err
+	}
+
+	// Enregistre la réservation. Échoue si une annulation ou un autre
+	// traitement de la même commande est passé entre-temps.
+	_, err = inv.reservations.InsertOne(ctx, reservationDocument{OrderID: orderID, Status: RESERVATION_RESERVED, Items: reserved})
+	if mongo.IsDuplicateKeyError(err) {
+		if err := inv.restock(ctx, reserved); err != nil {
+			return false, nil, nil, err
+		}
+		return inv.Reserve(ctx, orderID, items)
+	}
+	if err != nil {
+		if rerr := inv.restock(ctx, reserved); rerr != nil {
+			return false, nil, nil, rerr
+		}
+		return false, nil, nil, err
+	}
+
+	return true, reserved, nil, nil
+}
+
+// missing liste les articles dont le stock est insuffisant
+func (inv *MongoInventory) missing(ctx context.Context, items []Item) ([]Missing, error) {
+	var missing []Missing
+	for _, it := range items {
+		avail, err := inv.Stock(ctx, it.SKU)
+		if err != nil {
+			return nil, err
+		}
+		if avail < it.Qty {
+			missing = append(missing, Missing{SKU: it.SKU, Required: it.Qty, Available: avail})
+		}
+	}
+	return missing, nil
+}
+
+func (inv *MongoInventory) Release(ctx context.Context, orderID string) (released []Item, ok bool, err error) {
+	// Passe la réservation en annulée, ou la crée annulée si le paiement
+	// n'est pas encore arrivé. L'upsert échoue sur l'index unique si elle
+	// était déjà annulée.
+	var before reservationDocument
+	err = inv.reservations.FindOneAndUpdate(ctx,
+		bson.M{"_id": orderID, "status": bson.M{"$ne": RESERVATION_CANCELLED}},
+		bson.M{"$set": bson.M{"status": RESERVATION_CANCELLED}, "$unset": bson.M{"items": ""}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.Before),
+	).Decode(&before)
+
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, false, nil
+	}
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// Rien n'était réservé
+		return nil, true, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := inv.restock(ctx, before.Items); err != nil {
+		return nil, false, err
+	}
+	return before.Items, true, nil
+}
+
+func (inv *MongoInventory) Stock(ctx context.Context, sku string) (int, error) {
+	var doc stockDocument
+	err := inv.stock.FindOne(ctx, bson.M{"_id": sku}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return doc.Qty, nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	logs "eda-logs"
+	"eda-logs/internal"
+	"eda-shared/env"
+	"eda-shared/kafka"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func duration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Fatalf("Invalid %s: %v", key, err)
+	}
+	return d
+}
+
+func writerConfig() internal.WriterConfig {
+	config := internal.DefaultWriterConfig()
+
+	if v := os.Getenv("DB_BATCH_SIZE"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil {
+			log.Fatalln("Invalid DB_BATCH_SIZE: ", err)
+		}
+		config.BatchSize = size
+	}
+	config.FlushInterval = duration("DB_FLUSH_INTERVAL", config.FlushInterval)
+
+	return config
+}
+
+func hubConfig() internal.HubConfig {
+	config := internal.DefaultHubConfig()
+
+	if v := os.Getenv("WS_QUEUE_SIZE"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil {
+			log.Fatalln("Invalid WS_QUEUE_SIZE: ", err)
+		}
+		config.QueueSize = size
+	}
+
+	policy, err := internal.ParseOverflowPolicy(env.Get("WS_OVERFLOW_POLICY", string(config.Overflow)))
+	if err != nil {
+		log.Fatalln("Invalid WS_OVERFLOW_POLICY: ", err)
+	}
+	config.Overflow = policy
+
+	if v := os.Getenv("WS_PING_INTERVAL"); v != "" {
+		interval := duration("WS_PING_INTERVAL", config.PingInterval)
+		// A ticker needs a positive interval
+		if interval <= 0 {
+			log.Fatalf("Invalid WS_PING_INTERVAL %s: must be positive", v)
+		}
+		config.PingInterval = interval
+		config.PongWait = interval + interval/5
+	}
+
+	return config
+}
+
+func main() {
+
+	config := logs.DefaultConfig()
+	config.Writer = writerConfig()
+	config.Hub = hubConfig()
+	config.Retention = env.Get("LOG_RETENTION", config.Retention)
+	config.ArchiveDir = os.Getenv("ARCHIVE_DIR")
+	config.ArchiveGrace = duration("ARCHIVE_GRACE", config.ArchiveGrace)
+	config.ArchiveInterval = duration("ARCHIVE_INTERVAL", config.ArchiveInterval)
+	config.SigningKey = env.Get("JWT_SIGNING_KEY", config.SigningKey)
+	config.AllowedOrigins = strings.Split(env.Get("ALLOWED_ORIGINS", strings.Join(config.AllowedOrigins, ",")), ",")
+
+	var err error
+	config.Kafka, err = kafka.ConfigFromEnv("logs-service")
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	service, err := logs.New(config)
+	if err != nil {
+		log.Fatalln("Can't start logs service: ", err)
+	}
+	defer service.Close()
+
+	go func() {
+		if err := service.Run(context.Background()); err != nil {
+			log.Printf("Error reading messages: %v", err)
+		}
+	}()
+
+	log.Println("Serveur up and running...")
+	err = http.ListenAndServe(logs.PORT, service.Handler())
+
+	if err != nil {
+		log.Fatalln("Can't run websocket server: ", err)
+	}
+}
@@ -0,0 +1,87 @@
+// Command replay rebuilds the stored logs from logs.central, e.g. after the
+// logs collection was lost:
+//
+//	replay -database log_db_rebuild -from 2024-01-01 -service users
+//
+// The logs service's consumer group doesn't move. Replaying the same range
+// twice stores each log once.
+package main
+
+import (
+	"context"
+	"eda-logs/internal"
+	"eda-shared/env"
+	"eda-shared/kafka"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+)
+
+// date parses RFC 3339 or YYYY-MM-DD (midnight UTC)
+func date(t *time.Time) func(string) error {
+	return func(s string) error {
+		parsed, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			parsed, err = time.Parse(time.DateOnly, s)
+		}
+		*t = parsed
+		return err
+	}
+}
+
+func main() {
+	config := internal.DefaultReplayConfig()
+	rc := &config.Replay
+
+	topic := flag.String("topic", internal.TOPIC, "topic to replay")
+	flag.Int64Var(&rc.Offset, "offset", rc.Offset, "first offset of every partition, overrides -from")
+	flag.Func("from", "replay the messages written at or after this date (RFC 3339 or YYYY-MM-DD)", date(&rc.From))
+	flag.Func("to", "replay the messages written before this date", date(&rc.To))
+	flag.StringVar(&rc.Key, "key", "", "only the messages with this key")
+	services := flag.String("service", "", "only the logs of these services, comma separated")
+	flag.StringVar(&config.Filter.UserID, "user", "", "only the logs about this user")
+	flag.StringVar(&config.Filter.Contains, "contains", "", "only the logs containing this text")
+	uri := flag.String("uri", env.Get("MONGO_URI", internal.URL), "MongoDB to rebuild into")
+	database := flag.String("database", "log_db_rebuild", "database to rebuild into, logs-service reads "+internal.DATABASE)
+	flag.IntVar(&config.BatchSize, "batch", config.BatchSize, "logs stored per insert")
+	flag.Int64Var(&rc.ProgressEvery, "progress", rc.ProgressEvery, "report progress every n messages")
+	flag.BoolVar(&config.DryRun, "dry-run", false, "count the logs without storing them")
+	flag.Parse()
+
+	rc.Topics = []string{*topic}
+	if *services != "" {
+		config.Filter.Services = strings.Split(*services, ",")
+	}
+	rc.Progress = func(s kafka.ReplayStats) {
+		log.Printf("%s: %s", *topic, s)
+	}
+
+	kc, err := kafka.ConfigFromEnv("logs-replay")
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	var db *internal.Database
+	if !config.DryRun {
+		if db, err = internal.Connect(*uri, *database); err != nil {
+			log.Fatalln("Can't connect to MongoDB: ", err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	stats, err := internal.Replay(ctx, kc, config, db)
+	if err != nil {
+		log.Fatalf("Replay stopped after %s: %v", stats, err)
+	}
+
+	if config.DryRun {
+		log.Printf("Dry run: %d logs would be stored", stats.Handled)
+		return
+	}
+	log.Printf("%d logs stored into %s", stats.Handled, *database)
+}
@@ -0,0 +1,384 @@
+package internal
+
+import (
+	"compress/gzip"
+	"context"
+	"eda-logs/internal/types"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	ARCHIVE_BATCH  = 1000
+	ARCHIVE_PREFIX = "logs-"
+	ARCHIVE_SUFFIX = ".ndjson.gz"
+	// Ids of the logs archived by the last batch until its delete succeeds,
+	// one hex id per line
+	PENDING_FILE = ".pending"
+)
+
+var ErrArchiveRunning = errors.New("archival already running")
+
+type ArchiveReport struct {
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Archived   int64     `json:"archived"`
+	Deleted    int64     `json:"deleted"`
+	Files      []string  `json:"files"`
+	Error      string    `json:"error,omitempty"`
+}
+
+type ArchiveFile struct {
+	Name     string    `json:"name"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+}
+
+// ArchiveStore is the part of the database the Archiver moves logs out of,
+// implemented by Database.
+type ArchiveStore interface {
+	// Expired returns up to limit logs of rule older than cutoff, oldest first.
+	Expired(ctx context.Context, rule RetentionRule, cutoff time.Time, limit int64) ([]types.Log, error)
+	Delete(ctx context.Context, ids []primitive.ObjectID) (int64, error)
+}
+
+// Archiver rolls logs past their retention into daily gzip NDJSON files
+// (one JSON log per line) before deleting them from the database. With an
+// empty dir archival is disabled and the TTL indexes delete logs directly.
+type Archiver struct {
+	db    ArchiveStore
+	rules []RetentionRule
+	dir   string
+
+	running sync.Mutex
+	mu      sync.Mutex
+	last    *ArchiveReport
+}
+
+func NewArchiver(db ArchiveStore, rules []RetentionRule, dir string) *Archiver {
+	return &Archiver{db: db, rules: rules, dir: dir}
+}
+
+func (a *Archiver) Enabled() bool {
+	return a.dir != ""
+}
+
+// Run archives every interval until ctx is cancelled.
+func (a *Archiver) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := a.Archive(ctx); err != nil && !errors.Is(err, ErrArchiveRunning) {
+			log.Printf("Archival error: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Archive moves every log past its rule's MaxAge to the archive files.
+// Logs are only deleted once the file holding them is synced to disk, logs
+// already in their file because a delete failed are not appended again.
+func (a *Archiver) Archive(ctx context.Context) (ArchiveReport, error) {
+	if !a.Enabled() {
+		return ArchiveReport{}, errors.New("archival disabled")
+	}
+	if !a.running.TryLock() {
+		return ArchiveReport{}, ErrArchiveRunning
+	}
+	defer a.running.Unlock()
+
+	report := ArchiveReport{StartedAt: time.Now().UTC()}
+	err := a.archive(ctx, &report)
+	report.FinishedAt = time.Now().UTC()
+	if err != nil {
+		report.Error = err.Error()
+	}
+
+	a.mu.Lock()
+	a.last = &report
+	a.mu.Unlock()
+
+	if report.Archived > 0 {
+		log.Printf("Archived %d logs into %v", report.Archived, report.Files)
+	}
+
+	return report, err
+}
+
+func (a *Archiver) archive(ctx context.Context, report *ArchiveReport) error {
+	if err := os.MkdirAll(a.dir, 0o755); err != nil {
+		return err
+	}
+
+	files := make(map[string]bool)
+
+	// Logs archived by a run whose delete failed, still in the database
+	pending, err := a.pendingIDs()
+	if err != nil {
+		return err
+	}
+
+	for _, rule := range a.rules {
+		cutoff := report.StartedAt.Add(-rule.MaxAge)
+
+		for {
+			logs, err := a.db.Expired(ctx, rule, cutoff, ARCHIVE_BATCH)
+			if err != nil {
+				return err
+			}
+			if len(logs) == 0 {
+				break
+			}
+
+			days := make(map[string][]types.Log)
+			ids := make([]primitive.ObjectID, 0, len(logs))
+			for _, l := range logs {
+				ids = append(ids, l.ID)
+				if pending[l.ID] {
+					continue
+				}
+				day := l.Timestamp.UTC().Format(time.DateOnly)
+				days[day] = append(days[day], l)
+			}
+
+			for day, entries := range days {
+				name := ARCHIVE_PREFIX + day + ARCHIVE_SUFFIX
+				if err := a.append(name, entries); err != nil {
+					return err
+				}
+				files[name] = true
+				report.Archived += int64(len(entries))
+				for _, l := range entries {
+					pending[l.ID] = true
+				}
+			}
+
+			// Recorded before the delete so that a failed one doesn't append
+			// the batch again on the next run
+			if err := a.savePending(pending); err != nil {
+				return err
+			}
+
+			deleted, err := a.db.Delete(ctx, ids)
+			report.Deleted += deleted
+			if err != nil {
+				return err
+			}
+			for _, id := range ids {
+				delete(pending, id)
+			}
+
+			if len(logs) < ARCHIVE_BATCH {
+				break
+			}
+		}
+	}
+
+	for name := range files {
+		report.Files = append(report.Files, name)
+	}
+	sort.Strings(report.Files)
+
+	// Every expired log is deleted, the remaining pending ids are gone
+	return a.savePending(nil)
+}
+
+// append writes entries as a new gzip member at the end of the file, readers
+// such as zcat and gzip.Reader see the concatenation as one stream.
+func (a *Archiver) append(name string, entries []types.Log) error {
+	f, err := os.OpenFile(filepath.Join(a.dir, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	zw := gzip.NewWriter(f)
+	enc := json.NewEncoder(zw)
+	for _, l := range entries {
+		if err := enc.Encode(l); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	return f.Sync()
+}
+
+// pendingIDs reads the ids of PENDING_FILE, archived but maybe not deleted.
+func (a *Archiver) pendingIDs() (map[primitive.ObjectID]bool, error) {
+	ids := make(map[primitive.ObjectID]bool)
+
+	data, err := os.ReadFile(filepath.Join(a.dir, PENDING_FILE))
+	if errors.Is(err, os.ErrNotExist) {
+		return ids, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range strings.Fields(string(data)) {
+		id, err := primitive.ObjectIDFromHex(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", PENDING_FILE, err)
+		}
+		ids[id] = true
+	}
+	return ids, nil
+}
+
+// savePending replaces PENDING_FILE with ids, removing it when empty.
+func (a *Archiver) savePending(ids map[primitive.ObjectID]bool) error {
+	path := filepath.Join(a.dir, PENDING_FILE)
+	if len(ids) == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+
+	var b strings.Builder
+	for id := range ids {
+		b.WriteString(id.Hex())
+		b.WriteByte('\n')
+	}
+
+	f, err := os.OpenFile(path+".tmp", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(b.String()); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+func (a *Archiver) Files() ([]ArchiveFile, error) {
+	if !a.Enabled() {
+		return nil, nil
+	}
+
+	entries, err := os.ReadDir(a.dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var files []ArchiveFile
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasPrefix(e.Name(), ARCHIVE_PREFIX) || !strings.HasSuffix(e.Name(), ARCHIVE_SUFFIX) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, ArchiveFile{Name: e.Name(), Size: info.Size(), Modified: info.ModTime().UTC()})
+	}
+
+	return files, nil
+}
+
+type archiveStatus struct {
+	Enabled bool            `json:"enabled"`
+	Rules   []RetentionRule `json:"rules"`
+	LastRun *ArchiveReport  `json:"last_run"`
+	Files   []ArchiveFile   `json:"files"`
+}
+
+// ArchiveHandler is the admin endpoint: GET inspects the retention rules, the
+// last run and the archive files, POST triggers an archival run.
+type ArchiveHandler struct {
+	archiver *Archiver
+	auth     *Authenticator
+}
+
+func NewArchiveHandler(archiver *Archiver, auth *Authenticator) *ArchiveHandler {
+	return &ArchiveHandler{archiver: archiver, auth: auth}
+}
+
+func (h *ArchiveHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	identity, ok := h.auth.authorize(w, r)
+	if !ok {
+		return
+	}
+	if !identity.Admin {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		files, err := h.archiver.Files()
+		if err != nil {
+			http.Error(w, "Failed to list archives", http.StatusInternalServerError)
+			return
+		}
+
+		h.archiver.mu.Lock()
+		status := archiveStatus{
+			Enabled: h.archiver.Enabled(),
+			Rules:   h.archiver.rules,
+			LastRun: h.archiver.last,
+			Files:   files,
+		}
+		h.archiver.mu.Unlock()
+
+		writeJSON(w, http.StatusOK, status)
+
+	case http.MethodPost:
+		if !h.archiver.Enabled() {
+			http.Error(w, "Archival disabled, set ARCHIVE_DIR", http.StatusConflict)
+			return
+		}
+
+		report, err := h.archiver.Archive(r.Context())
+		if errors.Is(err, ErrArchiveRunning) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, report)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, report)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,234 @@
+package internal
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"eda-logs/internal/types"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// archiveStore is an in memory ArchiveStore whose deletes can be made to fail.
+type archiveStore struct {
+	mu         sync.Mutex
+	logs       []types.Log
+	failDelete error
+}
+
+func (s *archiveStore) add(rule RetentionRule, at time.Time, messages ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, m := range messages {
+		s.logs = append(s.logs, types.Log{
+			ID:          primitive.NewObjectID(),
+			Message:     m,
+			ServiceName: "users",
+			Level:       types.LEVEL_INFO,
+			Timestamp:   at.UTC(),
+			Retention:   rule.Key(),
+		})
+	}
+}
+
+func (s *archiveStore) Expired(ctx context.Context, rule RetentionRule, cutoff time.Time, limit int64) ([]types.Log, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var logs []types.Log
+	for _, l := range s.logs {
+		if l.Retention == rule.Key() && l.Timestamp.Before(cutoff) {
+			logs = append(logs, l)
+		}
+	}
+	sort.SliceStable(logs, func(i, j int) bool { return logs[i].Timestamp.Before(logs[j].Timestamp) })
+
+	return logs[:min(int64(len(logs)), limit)], nil
+}
+
+func (s *archiveStore) Delete(ctx context.Context, ids []primitive.ObjectID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failDelete != nil {
+		return 0, s.failDelete
+	}
+
+	before := len(s.logs)
+	s.logs = slices.DeleteFunc(s.logs, func(l types.Log) bool { return slices.Contains(ids, l.ID) })
+	return int64(before - len(s.logs)), nil
+}
+
+func (s *archiveStore) messages() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var m []string
+	for _, l := range s.logs {
+		m = append(m, l.Message)
+	}
+	return m
+}
+
+// readArchive returns the messages of an archive file, per gzip member.
+func readArchive(t *testing.T, dir, name string) [][]string {
+	t.Helper()
+
+	f, err := os.Open(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// Shared by the readers of each member so none reads past its end
+	r := bufio.NewReader(f)
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zr.Multistream(false)
+
+	var members [][]string
+	for {
+		var member []string
+		scanner := bufio.NewScanner(zr)
+		for scanner.Scan() {
+			var l types.Log
+			if err := json.Unmarshal(scanner.Bytes(), &l); err != nil {
+				t.Fatal(err)
+			}
+			member = append(member, l.Message)
+		}
+		if err := scanner.Err(); err != nil {
+			t.Fatal(err)
+		}
+		members = append(members, member)
+
+		if err := zr.Reset(r); err != nil {
+			break
+		}
+		zr.Multistream(false)
+	}
+	return members
+}
+
+func TestArchiveSplitsByDay(t *testing.T) {
+	rule := RetentionRule{Service: ANY, Level: ANY, MaxAge: 24 * time.Hour}
+	store := &archiveStore{}
+	day := time.Date(2026, 3, 10, 23, 0, 0, 0, time.UTC)
+	store.add(rule, day, "a", "b")
+	store.add(rule, day.Add(2*time.Hour), "c")
+	store.add(rule, time.Now(), "recent")
+	// Logs of another rule are left to it
+	store.add(RetentionRule{Service: "users", Level: ANY}, day, "other")
+
+	dir := t.TempDir()
+	report, err := NewArchiver(store, []RetentionRule{rule}, dir).Archive(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Archived != 3 || report.Deleted != 3 {
+		t.Errorf("archived %d and deleted %d logs, want 3", report.Archived, report.Deleted)
+	}
+	files := []string{"logs-2026-03-10.ndjson.gz", "logs-2026-03-11.ndjson.gz"}
+	if fmt.Sprint(report.Files) != fmt.Sprint(files) {
+		t.Errorf("files = %v, want %v", report.Files, files)
+	}
+	if got := readArchive(t, dir, files[0]); fmt.Sprint(got) != "[[a b]]" {
+		t.Errorf("%s = %v", files[0], got)
+	}
+	if got := readArchive(t, dir, files[1]); fmt.Sprint(got) != "[[c]]" {
+		t.Errorf("%s = %v", files[1], got)
+	}
+	if got := store.messages(); fmt.Sprint(got) != "[recent other]" {
+		t.Errorf("database = %v, want [recent other]", got)
+	}
+}
+
+func TestArchiveAppendsGzipMembers(t *testing.T) {
+	rule := RetentionRule{Service: ANY, Level: ANY, MaxAge: time.Hour}
+	store := &archiveStore{}
+	at := time.Now().Add(-2 * time.Hour)
+	archiver := NewArchiver(store, []RetentionRule{rule}, t.TempDir())
+
+	store.add(rule, at, "a", "b")
+	if _, err := archiver.Archive(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	store.add(rule, at, "c")
+	if _, err := archiver.Archive(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	name := ARCHIVE_PREFIX + at.UTC().Format(time.DateOnly) + ARCHIVE_SUFFIX
+	if got := readArchive(t, archiver.dir, name); fmt.Sprint(got) != "[[a b] [c]]" {
+		t.Errorf("members = %v, want [[a b] [c]]", got)
+	}
+
+	// Readers see the members as one stream
+	f, err := os.Open(filepath.Join(archiver.dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := 0
+	for scanner := bufio.NewScanner(zr); scanner.Scan(); {
+		lines++
+	}
+	if lines != 3 {
+		t.Errorf("%d lines, want 3", lines)
+	}
+}
+
+func TestArchiveFailedDeleteNoDuplicates(t *testing.T) {
+	rule := RetentionRule{Service: ANY, Level: ANY, MaxAge: time.Hour}
+	store := &archiveStore{failDelete: errors.New("connection reset")}
+	at := time.Now().Add(-2 * time.Hour)
+	archiver := NewArchiver(store, []RetentionRule{rule}, t.TempDir())
+
+	store.add(rule, at, "a", "b")
+	if _, err := archiver.Archive(context.Background()); err == nil {
+		t.Fatal("archival succeeded despite the failed delete")
+	}
+
+	if _, err := os.Stat(filepath.Join(archiver.dir, PENDING_FILE)); err != nil {
+		t.Errorf("ids of the undeleted logs not recorded: %v", err)
+	}
+
+	store.failDelete = nil
+	store.add(rule, at, "c")
+	report, err := archiver.Archive(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Archived != 1 || report.Deleted != 3 {
+		t.Errorf("archived %d and deleted %d logs, want 1 and 3", report.Archived, report.Deleted)
+	}
+
+	name := ARCHIVE_PREFIX + at.UTC().Format(time.DateOnly) + ARCHIVE_SUFFIX
+	if got := readArchive(t, archiver.dir, name); fmt.Sprint(got) != "[[a b] [c]]" {
+		t.Errorf("members = %v, want [[a b] [c]]", got)
+	}
+	if got := store.messages(); len(got) != 0 {
+		t.Errorf("database = %v, want empty", got)
+	}
+	if _, err := os.Stat(filepath.Join(archiver.dir, PENDING_FILE)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("pending ids kept after the delete: %v", err)
+	}
+}
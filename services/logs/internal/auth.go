@@ -0,0 +1,119 @@
+package internal
+
+import (
+	"eda-logs/internal/types"
+	"eda-shared/auth"
+	"net/http"
+	"strings"
+)
+
+const (
+	// Websocket subprotocol used to carry the token from browsers, which
+	// can't set headers: new WebSocket(url, ["bearer", token])
+	BEARER_PROTOCOL = "bearer"
+)
+
+type Identity struct {
+	Subject string
+	Admin   bool
+}
+
+type Authenticator struct {
+	key     []byte
+	origins map[string]struct{}
+	anyOrig bool
+}
+
+// NewAuthenticator validates users-service tokens signed with key and only
+// accepts browser connections from the given origins ("*" accepts any).
+func NewAuthenticator(key string, origins []string) *Authenticator {
+	a := &Authenticator{
+		key:     []byte(key),
+		origins: make(map[string]struct{}),
+	}
+
+	for _, o := range origins {
+		o = strings.TrimRight(strings.TrimSpace(o), "/")
+		if o == "*" {
+			a.anyOrig = true
+		} else if o != "" {
+			a.origins[o] = struct{}{}
+		}
+	}
+
+	return a
+}
+
+// CheckOrigin accepts requests without an Origin header (curl, server side
+// consumers) and browser requests from an allowed origin.
+func (a *Authenticator) CheckOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || a.anyOrig {
+		return true
+	}
+	_, ok := a.origins[origin]
+	return ok
+}
+
+// token looks for the JWT in the Authorization header, the access_token query
+// parameter, then the websocket subprotocols.
+func token(r *http.Request) string {
+	if t := auth.BearerToken(r); t != "" {
+		return t
+	}
+
+	if t := r.URL.Query().Get("access_token"); t != "" {
+		return t
+	}
+
+	protocols := websocketProtocols(r)
+	for i, p := range protocols {
+		if p == BEARER_PROTOCOL && i+1 < len(protocols) {
+			return protocols[i+1]
+		}
+	}
+
+	return ""
+}
+
+func websocketProtocols(r *http.Request) []string {
+	var protocols []string
+	for _, h := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, p := range strings.Split(h, ",") {
+			protocols = append(protocols, strings.TrimSpace(p))
+		}
+	}
+	return protocols
+}
+
+func (a *Authenticator) Authenticate(r *http.Request) (Identity, error) {
+	claims, err := auth.Verify(token(r), a.key)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	return Identity{Subject: claims.Subject, Admin: claims.Admin()}, nil
+}
+
+// authorize checks origin and token, writing the error response on failure.
+// Non-admin users are restricted to the logs about themselves.
+func (a *Authenticator) authorize(w http.ResponseWriter, r *http.Request) (Identity, bool) {
+	if !a.CheckOrigin(r) {
+		http.Error(w, "Origin not allowed", http.StatusForbidden)
+		return Identity{}, false
+	}
+
+	id, err := a.Authenticate(r)
+	if err != nil {
+		http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+		return Identity{}, false
+	}
+
+	return id, true
+}
+
+func (id Identity) restrict(filter *types.Filter) {
+	if !id.Admin {
+		filter.UserID = id.Subject
+	}
+}
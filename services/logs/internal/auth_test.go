@@ -0,0 +1,142 @@
+package internal
+
+import (
+	"eda-logs/internal/types"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"eda-shared/auth"
+)
+
+const testKey = "test-key"
+
+// signToken signs a token like users-service does on /login.
+func signToken(t *testing.T, subject, role string) string {
+	t.Helper()
+
+	signed, err := auth.Sign([]byte(testKey), subject, role, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestCheckOrigin(t *testing.T) {
+	a := NewAuthenticator(testKey, []string{" http://shop.local/ ", ""})
+
+	for origin, want := range map[string]bool{
+		"":                   true,
+		"http://shop.local":  true,
+		"http://evil.local":  false,
+		"http://shop.local/": false,
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/events", nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		if got := a.CheckOrigin(r); got != want {
+			t.Errorf("origin %q allowed = %v, want %v", origin, got, want)
+		}
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/events", nil)
+	r.Header.Set("Origin", "http://evil.local")
+	if !NewAuthenticator(testKey, []string{"*"}).CheckOrigin(r) {
+		t.Error("* doesn't accept any origin")
+	}
+}
+
+func TestAuthenticateTokenSources(t *testing.T) {
+	a := NewAuthenticator(testKey, nil)
+	token := signToken(t, "alice", "user")
+
+	for name, set := range map[string]func(r *http.Request){
+		"header":      func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+token) },
+		"query":       func(r *http.Request) { r.URL.RawQuery = "access_token=" + token },
+		"subprotocol": func(r *http.Request) { r.Header.Set("Sec-WebSocket-Protocol", BEARER_PROTOCOL+", "+token) },
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		set(r)
+
+		id, err := a.Authenticate(r)
+		if err != nil || id != (Identity{Subject: "alice"}) {
+			t.Errorf("%s: identity = %+v, %v", name, id, err)
+		}
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if _, err := a.Authenticate(r); !errors.Is(err, auth.ErrMissingToken) {
+		t.Errorf("no token: err = %v", err)
+	}
+
+	r.Header.Set("Authorization", "Bearer "+signToken(t, "root", auth.ROLE_ADMIN))
+	if id, err := a.Authenticate(r); err != nil || !id.Admin {
+		t.Errorf("admin: identity = %+v, %v", id, err)
+	}
+}
+
+func TestAuthenticateRejectsInvalidTokens(t *testing.T) {
+	a := NewAuthenticator(testKey, nil)
+
+	// The token checks themselves are tested in eda-shared/auth
+	sign := func(key string, expiresAt time.Time) string {
+		signed, err := auth.Sign([]byte(key), "alice", "user", expiresAt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+
+	for name, token := range map[string]string{
+		"garbage":   "not-a-jwt",
+		"other key": sign("other-key", time.Now().Add(time.Hour)),
+		"expired":   sign(testKey, time.Now().Add(-time.Minute)),
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		if id, err := a.Authenticate(r); err == nil {
+			t.Errorf("%s: accepted as %+v", name, id)
+		}
+	}
+}
+
+func TestRestrictFiltersNonAdmins(t *testing.T) {
+	filter := types.Filter{Services: []string{"orders"}}
+	Identity{Subject: "alice"}.restrict(&filter)
+	if filter.UserID != "alice" || len(filter.Services) != 1 {
+		t.Errorf("user filter = %+v", filter)
+	}
+
+	filter = types.Filter{}
+	Identity{Subject: "root", Admin: true}.restrict(&filter)
+	if filter.UserID != "" {
+		t.Errorf("admin filter = %+v", filter)
+	}
+}
+
+func TestAuthorizeResponses(t *testing.T) {
+	a := NewAuthenticator(testKey, []string{"http://shop.local"})
+
+	for _, tt := range []struct {
+		name, origin, token string
+		want                int
+	}{
+		{"origin not allowed", "http://evil.local", signToken(t, "alice", "user"), http.StatusForbidden},
+		{"no token", "http://shop.local", "", http.StatusUnauthorized},
+		{"allowed", "http://shop.local", signToken(t, "alice", "user"), http.StatusOK},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/events", nil)
+		r.Header.Set("Origin", tt.origin)
+		if tt.token != "" {
+			r.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		w := httptest.NewRecorder()
+
+		if _, ok := a.authorize(w, r); ok != (tt.want == http.StatusOK) || w.Code != tt.want {
+			t.Errorf("%s: ok = %v, status %d, want %d", tt.name, ok, w.Code, tt.want)
+		}
+	}
+}
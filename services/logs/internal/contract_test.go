@@ -0,0 +1,38 @@
+package internal
+
+import (
+	"eda-logs/internal/types"
+	"eda-shared/kafka"
+	"flag"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the contracts published in ressources/contracts")
+
+// Contracts of the consumers, see kafka.Contract
+const CONTRACTS = "../../../ressources/contracts"
+
+func TestConsumerContracts(t *testing.T) {
+	// The level and the time are filled in by complete
+	logs, err := kafka.NewContract[types.Log]("logs-service", TOPIC,
+		`{"message": "[Notification] Received Notification: payment.processed", "service_name": "notifications", "user_id": "456"}`,
+		`{"message": "[Notification] Received Notification: payment.processed", "service_name": "notifications"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, m := range logs.Messages() {
+		var l types.Log
+		if err := m.Decode(&l); err != nil {
+			t.Fatal(err)
+		}
+		complete(m, &l)
+		if l.Level != types.LEVEL_INFO || l.Timestamp.IsZero() {
+			t.Errorf("%s completed as %+v", m.Value, l)
+		}
+	}
+
+	if err := kafka.PublishContracts(CONTRACTS, *update, logs); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,162 @@
+package internal
+
+import (
+	"context"
+	"eda-logs/internal/types"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// testDatabase is the conformance suite of types.IDatabase: open returns an
+// empty database.
+func testDatabase(t *testing.T, open func(t *testing.T) types.IDatabase) {
+	start := time.Now().Truncate(time.Second)
+
+	// Ids in the order of the slice, one second apart
+	newLogs := func(logs ...types.Log) []types.Log {
+		for i := range logs {
+			logs[i].ID = primitive.NewObjectIDFromTimestamp(start.Add(time.Duration(i) * time.Second))
+			logs[i].Level = types.LEVEL_INFO
+			logs[i].Timestamp = start.Add(time.Duration(i) * time.Second).UTC()
+		}
+		return logs
+	}
+
+	messages := func(logs []types.Log) []string {
+		var m []string
+		for _, l := range logs {
+			m = append(m, l.Message)
+		}
+		return m
+	}
+
+	check := func(t *testing.T, logs []types.Log, err error, want ...string) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := messages(logs); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("logs = %q, want %q", got, want)
+		}
+	}
+
+	t.Run("SinceOldestFirst", func(t *testing.T) {
+		db := open(t)
+		logs := newLogs(
+			types.Log{Message: "a", ServiceName: "users"},
+			types.Log{Message: "b", ServiceName: "orders"},
+			types.Log{Message: "c", ServiceName: "users"},
+		)
+
+		// Stored out of order
+		if err := db.SaveMany([]types.Log{logs[2], logs[0]}); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.SaveMany(logs[1:2]); err != nil {
+			t.Fatal(err)
+		}
+
+		all, err := db.Since(primitive.NilObjectID, types.Filter{}, 0)
+		check(t, all, err, "a", "b", "c")
+
+		after, err := db.Since(logs[0].ID, types.Filter{}, 0)
+		check(t, after, err, "b", "c")
+
+		limited, err := db.Since(primitive.NilObjectID, types.Filter{}, 2)
+		check(t, limited, err, "a", "b")
+	})
+
+	t.Run("SaveManyIgnoresStoredIDs", func(t *testing.T) {
+		db := open(t)
+		logs := newLogs(types.Log{Message: "a"}, types.Log{Message: "b"})
+
+		if err := db.SaveMany(logs[:1]); err != nil {
+			t.Fatal(err)
+		}
+		// Retried batch: the first log is already stored
+		if err := db.SaveMany(logs); err != nil {
+			t.Fatal(err)
+		}
+
+		all, err := db.Since(primitive.NilObjectID, types.Filter{}, 0)
+		check(t, all, err, "a", "b")
+	})
+
+	t.Run("SinceFilter", func(t *testing.T) {
+		db := open(t)
+		logs := newLogs(
+			types.Log{Message: "user alice registered", ServiceName: "users", UserID: "alice"},
+			types.Log{Message: "order 1 created", ServiceName: "orders", UserID: "alice"},
+			types.Log{Message: "user bob registered", ServiceName: "users", UserID: "bob"},
+			types.Log{Message: "payment (1.*) done", ServiceName: "payments"},
+		)
+		if err := db.SaveMany(logs); err != nil {
+			t.Fatal(err)
+		}
+
+		byService, err := db.Since(primitive.NilObjectID, types.Filter{Services: []string{"orders", "payments"}}, 0)
+		check(t, byService, err, "order 1 created", "payment (1.*) done")
+
+		byUser, err := db.Since(primitive.NilObjectID, types.Filter{UserID: "alice"}, 0)
+		check(t, byUser, err, "user alice registered", "order 1 created")
+
+		contains, err := db.Since(primitive.NilObjectID, types.Filter{Services: []string{"users"}, Contains: "registered"}, 0)
+		check(t, contains, err, "user alice registered", "user bob registered")
+
+		// Contains is a substring, not a pattern
+		literal, err := db.Since(primitive.NilObjectID, types.Filter{Contains: "(1.*)"}, 0)
+		check(t, literal, err, "payment (1.*) done")
+	})
+
+	t.Run("SinceKeepsFields", func(t *testing.T) {
+		db := open(t)
+		logs := newLogs(types.Log{Message: "a", ServiceName: "users", UserID: "alice"})
+		if err := db.SaveMany(logs); err != nil {
+			t.Fatal(err)
+		}
+
+		all, err := db.Since(primitive.NilObjectID, types.Filter{}, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(all) != 1 {
+			t.Fatalf("%d logs, want 1", len(all))
+		}
+
+		got, want := all[0], logs[0]
+		if got.ID != want.ID || got.ServiceName != want.ServiceName || got.UserID != want.UserID ||
+			got.Level != want.Level || !got.Timestamp.Equal(want.Timestamp) {
+			t.Errorf("log = %+v, want %+v", got, want)
+		}
+	})
+}
+
+func TestMemoryDatabase(t *testing.T) {
+	testDatabase(t, func(t *testing.T) types.IDatabase {
+		return NewMemoryDatabase()
+	})
+}
+
+// TestMongoDatabase needs a MongoDB server, e.g.
+// MONGO_TEST_URI=mongodb://localhost:27017
+func TestMongoDatabase(t *testing.T) {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI not set")
+	}
+
+	testDatabase(t, func(t *testing.T) types.IDatabase {
+		db, err := Connect(uri, fmt.Sprintf("log_db_test_%d", time.Now().UnixNano()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			_ = db.conn.Drop(context.Background())
+		})
+		return db
+	})
+}
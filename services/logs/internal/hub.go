@@ -0,0 +1,234 @@
+package internal
+
+import (
+	"bytes"
+	"eda-logs/internal/types"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// Maximum number of stored logs replayed to a resuming subscriber
+	REPLAY_LIMIT = 1000
+)
+
+type OverflowPolicy string
+
+const (
+	// Drop the oldest queued message to make room for the new one.
+	DROP_OLDEST OverflowPolicy = "drop-oldest"
+	// Close the connection of a client that can't keep up.
+	DISCONNECT OverflowPolicy = "disconnect"
+)
+
+func ParseOverflowPolicy(s string) (OverflowPolicy, error) {
+	switch OverflowPolicy(s) {
+	case DROP_OLDEST, DISCONNECT:
+		return OverflowPolicy(s), nil
+	}
+	return "", errors.New("unknown overflow policy: " + s)
+}
+
+type HubConfig struct {
+	QueueSize    int
+	Overflow     OverflowPolicy
+	WriteWait    time.Duration
+	PongWait     time.Duration
+	PingInterval time.Duration
+}
+
+func DefaultHubConfig() HubConfig {
+	return HubConfig{
+		QueueSize:    64,
+		Overflow:     DROP_OLDEST,
+		WriteWait:    5 * time.Second,
+		PongWait:     60 * time.Second,
+		PingInterval: 50 * time.Second,
+	}
+}
+
+// subscriber is a single streaming client (websocket or SSE) with its own
+// bounded send queue, drained by the goroutine serving the connection.
+type subscriber struct {
+	name   string
+	filter types.Filter
+	send   chan types.Log
+
+	// closed once the subscriber is removed from the hub
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func (s *subscriber) stop() {
+	s.closeOnce.Do(func() { close(s.done) })
+}
+
+// Hub fans out incoming logs to every subscriber, whatever the transport.
+type Hub struct {
+	in     <-chan types.Log
+	db     types.IDatabase
+	config HubConfig
+
+	mu          sync.Mutex
+	subscribers map[*subscriber]struct{}
+}
+
+func NewHub(in <-chan types.Log, db types.IDatabase, config HubConfig) *Hub {
+	if config.QueueSize < 1 {
+		config.QueueSize = 1
+	}
+
+	return &Hub{
+		in:          in,
+		db:          db,
+		config:      config,
+		subscribers: make(map[*subscriber]struct{}),
+	}
+}
+
+// Run fans every incoming log out to the subscriber queues. It never writes to
+// a connection itself, so a slow client can't stall the others.
+func (h *Hub) Run() {
+	for entry := range h.in {
+		h.mu.Lock()
+		for s := range h.subscribers {
+			if s.filter.Match(entry) {
+				h.enqueue(s, entry)
+			}
+		}
+		h.mu.Unlock()
+	}
+}
+
+// enqueue must be called with h.mu held.
+func (h *Hub) enqueue(s *subscriber, entry types.Log) {
+	select {
+	case s.send <- entry:
+		return
+	default:
+	}
+
+	switch h.config.Overflow {
+	case DISCONNECT:
+		log.Printf("client %s too slow, disconnecting", s.name)
+		h.remove(s)
+	default:
+		// Drop the oldest message, then retry once. The writer may have
+		// drained the queue meanwhile, in which case nothing is dropped.
+		select {
+		case <-s.send:
+			log.Printf("client %s too slow, dropping oldest message", s.name)
+		default:
+		}
+		select {
+		case s.send <- entry:
+		default:
+		}
+	}
+}
+
+func (h *Hub) subscribe(name string, filter types.Filter) *subscriber {
+	s := &subscriber{
+		name:   name,
+		filter: filter,
+		send:   make(chan types.Log, h.config.QueueSize),
+		done:   make(chan struct{}),
+	}
+
+	h.mu.Lock()
+	h.subscribers[s] = struct{}{}
+	h.mu.Unlock()
+
+	return s
+}
+
+// remove must be called with h.mu held.
+func (h *Hub) remove(s *subscriber) {
+	if _, ok := h.subscribers[s]; !ok {
+		return
+	}
+	delete(h.subscribers, s)
+	s.stop()
+}
+
+func (h *Hub) unsubscribe(s *subscriber) {
+	h.mu.Lock()
+	h.remove(s)
+	h.mu.Unlock()
+}
+
+// stream replays the stored logs after since, then delivers live logs until
+// the subscriber is removed or write/ping fails. Live logs already covered by
+// the replay are skipped so a resuming client sees each log exactly once.
+func (h *Hub) stream(s *subscriber, since primitive.ObjectID, write func(types.Log) error, ping func() error) error {
+	var last primitive.ObjectID
+
+	if !since.IsZero() {
+		backlog, err := h.db.Since(since, s.filter, REPLAY_LIMIT)
+		if err != nil {
+			log.Printf("error loading logs since %s: %v", since.Hex(), err)
+		}
+		for _, entry := range backlog {
+			if err := write(entry); err != nil {
+				return err
+			}
+			last = entry.ID
+		}
+	}
+
+	ticker := time.NewTicker(h.config.PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case entry := <-s.send:
+			if !last.IsZero() && bytes.Compare(entry.ID[:], last[:]) <= 0 {
+				continue
+			}
+			if err := write(entry); err != nil {
+				return err
+			}
+		case <-ticker.C:
+			if err := ping(); err != nil {
+				return err
+			}
+		case <-s.done:
+			return nil
+		}
+	}
+}
+
+// parseFilter reads the subscription filter from the query string:
+// ?service=users,payments&q=registered
+func parseFilter(r *http.Request) types.Filter {
+	var filter types.Filter
+
+	for _, v := range r.URL.Query()["service"] {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				filter.Services = append(filter.Services, name)
+			}
+		}
+	}
+	filter.Contains = r.URL.Query().Get("q")
+
+	return filter
+}
+
+// parseSince reads the id of the last log seen by a resuming client.
+func parseSince(raw string) (primitive.ObjectID, error) {
+	if raw == "" {
+		return primitive.NilObjectID, nil
+	}
+	return primitive.ObjectIDFromHex(raw)
+}
+
+func render(entry types.Log) string {
+	return "[Logs] Received " + entry.String()
+}
@@ -0,0 +1,129 @@
+package internal
+
+import (
+	"eda-logs/internal/types"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// runHub feeds logs to a hub and waits until it has enqueued them all.
+func runHub(h *Hub, in chan types.Log, logs ...types.Log) {
+	done := make(chan struct{})
+	go func() {
+		h.Run()
+		close(done)
+	}()
+	for _, l := range logs {
+		in <- l
+	}
+	close(in)
+	<-done
+}
+
+// queued drains the queue of a subscriber.
+func queued(s *subscriber) []string {
+	var messages []string
+	for {
+		select {
+		case l := <-s.send:
+			messages = append(messages, l.Message)
+		default:
+			return messages
+		}
+	}
+}
+
+func newLog(service, message string) types.Log {
+	return types.Log{ID: primitive.NewObjectID(), ServiceName: service, Message: message}
+}
+
+func TestHubDropOldest(t *testing.T) {
+	in := make(chan types.Log)
+	h := NewHub(in, NewMemoryDatabase(), HubConfig{QueueSize: 2, Overflow: DROP_OLDEST})
+	s := h.subscribe("slow", types.Filter{})
+
+	runHub(h, in, newLog("users", "1"), newLog("users", "2"), newLog("users", "3"))
+
+	if got := queued(s); !slices.Equal(got, []string{"2", "3"}) {
+		t.Errorf("queue = %v, want the 2 newest", got)
+	}
+	select {
+	case <-s.done:
+		t.Error("slow subscriber disconnected with drop-oldest")
+	default:
+	}
+}
+
+func TestHubDisconnect(t *testing.T) {
+	in := make(chan types.Log)
+	h := NewHub(in, NewMemoryDatabase(), HubConfig{QueueSize: 1, Overflow: DISCONNECT})
+	slow := h.subscribe("slow", types.Filter{})
+	other := h.subscribe("other", types.Filter{Services: []string{"orders"}})
+
+	runHub(h, in, newLog("users", "1"), newLog("users", "2"), newLog("orders", "3"))
+
+	select {
+	case <-slow.done:
+	default:
+		t.Fatal("slow subscriber still connected")
+	}
+	if got := queued(slow); !slices.Equal(got, []string{"1"}) {
+		t.Errorf("slow queue = %v", got)
+	}
+	// Filtered out logs don't count against the queue of the others
+	if got := queued(other); !slices.Equal(got, []string{"3"}) {
+		t.Errorf("other queue = %v", got)
+	}
+	if _, ok := h.subscribers[other]; !ok || len(h.subscribers) != 1 {
+		t.Errorf("subscribers = %v", h.subscribers)
+	}
+}
+
+func TestHubStreamResumes(t *testing.T) {
+	db := NewMemoryDatabase()
+	stored := []types.Log{newLog("users", "1"), newLog("orders", "2"), newLog("users", "3")}
+	if err := db.SaveMany(stored); err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewHub(nil, db, HubConfig{QueueSize: 8, PingInterval: time.Hour})
+	s := h.subscribe("resuming", types.Filter{Services: []string{"users"}})
+
+	// Live logs already in the backlog are skipped
+	s.send <- stored[2]
+	s.send <- newLog("users", "4")
+
+	var got []string
+	stop := errors.New("stop")
+	err := h.stream(s, stored[0].ID, func(l types.Log) error {
+		got = append(got, l.Message)
+		if l.Message == "4" {
+			return stop
+		}
+		return nil
+	}, func() error { return nil })
+
+	if !errors.Is(err, stop) || !slices.Equal(got, []string{"3", "4"}) {
+		t.Errorf("streamed %v, %v", got, err)
+	}
+}
+
+func TestHubStreamPings(t *testing.T) {
+	h := NewHub(nil, NewMemoryDatabase(), HubConfig{QueueSize: 1, PingInterval: time.Millisecond})
+	s := h.subscribe("idle", types.Filter{})
+
+	pings := 0
+	err := h.stream(s, primitive.NilObjectID, func(types.Log) error { return nil }, func() error {
+		if pings++; pings == 3 {
+			h.unsubscribe(s)
+		}
+		return nil
+	})
+	if err != nil || pings < 3 {
+		t.Errorf("%d pings, %v", pings, err)
+	}
+}
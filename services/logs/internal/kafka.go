@@ -3,68 +3,102 @@ package internal
 import (
 	"context"
 	"eda-logs/internal/types"
-	"encoding/json"
-	"fmt"
+	"eda-shared/kafka"
+	"encoding/binary"
+	"errors"
 	"log"
+	"time"
 
-	"github.com/segmentio/kafka-go"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 const (
-	TOPIC          = "logs.central"
-	BROKER_ADDRESS = "kafka:29092"
-	GROUP_ID       = "logs-group"
+	TOPIC    = "logs.central"
+	GROUP_ID = "logs-group"
 )
 
-type KafkaClient struct {
-	reader *kafka.Reader
-	db     types.IDatabase
+// LogConsumer hands the consumed logs to the batch writer
+type LogConsumer struct {
+	consumer *kafka.Consumer
 }
 
-func NewKafkaClient(db types.IDatabase) *KafkaClient {
-	reader := kafka.NewReader(kafka.ReaderConfig{
-		Brokers: []string{BROKER_ADDRESS},
-		Topic:   TOPIC,
-		GroupID: GROUP_ID,
-	})
-
-	return &KafkaClient{
-		reader,
-		db,
+func NewLogConsumer(config kafka.Config) (*LogConsumer, error) {
+	consumer, err := kafka.NewConsumer(config, kafka.DefaultConsumerConfig(GROUP_ID, TOPIC))
+	if err != nil {
+		return nil, err
 	}
+	kafka.Consumes[types.Log](config.Registry, GROUP_ID, TOPIC)
+
+	return &LogConsumer{consumer}, nil
 }
 
-func (k KafkaClient) Read(logger chan<- string) error {
+// Commit marks the messages as processed for the consumer group.
+func (k LogConsumer) Commit(ctx context.Context, msgs ...kafka.Message) error {
+	return k.consumer.Commit(ctx, msgs...)
+}
+
+// Read hands every message to the writer. Offsets aren't committed here but
+// by the writer, once the batch holding the message is stored.
+func (k LogConsumer) Read(ctx context.Context, writer *BatchWriter) error {
 
 	for {
-		m, err := k.reader.ReadMessage(context.Background())
-		if err != nil {
+		m, err := k.consumer.Fetch(ctx)
+		if errors.Is(err, kafka.ErrClosed) || ctx.Err() != nil {
+			return nil
+		}
+		if err != nil && m.Topic == "" {
 			return err
 		}
 
 		var message types.Log
-		if err := json.Unmarshal(m.Value, &message); err != nil {
-			log.Printf("Error unmarshaling JSON: %v\n", err)
+		if err == nil {
+			err = m.Decode(&message)
+		}
+		if err != nil {
+			log.Printf("Error decoding log: %v\n", err)
+			// Still committed in order with the rest of the batch
+			if err := writer.Add(ctx, m, nil); err != nil {
+				return err
+			}
 			continue
 		}
 
-		output := fmt.Sprintf("[Logs] Received %s", message)
-		log.Println(output)
+		// The id is assigned here so live subscribers and stored logs share it
+		message.ID = logID(m)
+		complete(m, &message)
 
-		select {
-		case logger <- output:
-		default:
-			// avoid blocking if no client yet; drop when buffer is full
-			log.Println("logger channel full, dropping message")
-		}
-
-		if err := k.db.Save(message); err != nil {
-			log.Printf("DB save error: %v", err)
+		if err := writer.Add(ctx, m, &message); err != nil {
+			return err
 		}
 	}
 
 }
 
-func (k KafkaClient) Close() {
-	k.Close()
+// logID derives the id of a log from its position in the topic: a message
+// read again, after a restart before its offset was committed or by a
+// replay, is stored once since SaveMany ignores the ids already stored.
+// Like generated ids, it starts with the time.
+func logID(m kafka.Message) primitive.ObjectID {
+	var id primitive.ObjectID
+	binary.BigEndian.PutUint32(id[0:4], uint32(m.Time.Unix()))
+	binary.BigEndian.PutUint16(id[4:6], uint16(m.Partition))
+	binary.BigEndian.PutUint32(id[6:10], uint32(m.Offset>>16))
+	binary.BigEndian.PutUint16(id[10:12], uint16(m.Offset))
+	return id
+}
+
+// complete fills in what producers leave out: the time comes from the
+// Kafka message, the level defaults to info.
+func complete(m kafka.Message, l *types.Log) {
+	l.Timestamp = m.Time.UTC()
+	if l.Timestamp.IsZero() {
+		l.Timestamp = time.Now().UTC()
+	}
+	if l.Level == "" {
+		l.Level = types.LEVEL_INFO
+	}
+}
+
+func (k LogConsumer) Close() {
+	_ = k.consumer.Close()
 }
@@ -0,0 +1,58 @@
+package internal
+
+import (
+	"bytes"
+	"eda-logs/internal/types"
+	"slices"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MemoryDatabase keeps the logs in memory, ordered by id like the Mongo
+// collection. It has no retention: logs are kept until the process exits.
+type MemoryDatabase struct {
+	mu   sync.Mutex
+	logs []types.Log
+	ids  map[primitive.ObjectID]bool
+}
+
+func NewMemoryDatabase() *MemoryDatabase {
+	return &MemoryDatabase{ids: make(map[primitive.ObjectID]bool)}
+}
+
+func compareIDs(a, b primitive.ObjectID) int {
+	return bytes.Compare(a[:], b[:])
+}
+
+func (db *MemoryDatabase) SaveMany(data []types.Log) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for _, l := range data {
+		if db.ids[l.ID] {
+			continue
+		}
+		db.ids[l.ID] = true
+		db.logs = append(db.logs, l)
+	}
+
+	slices.SortFunc(db.logs, func(a, b types.Log) int { return compareIDs(a.ID, b.ID) })
+	return nil
+}
+
+func (db *MemoryDatabase) Since(id primitive.ObjectID, filter types.Filter, limit int64) ([]types.Log, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var logs []types.Log
+	for _, l := range db.logs {
+		if limit > 0 && int64(len(logs)) >= limit {
+			break
+		}
+		if compareIDs(l.ID, id) > 0 && filter.Match(l) {
+			logs = append(logs, l)
+		}
+	}
+	return logs, nil
+}
@@ -0,0 +1,84 @@
+package internal
+
+import (
+	"context"
+	"eda-logs/internal/types"
+	"eda-shared/kafka"
+)
+
+// ReplayConfig rebuilds stored logs from the logs topic
+type ReplayConfig struct {
+	// Topics default to TOPIC
+	Replay kafka.ReplayConfig
+	// Only the logs matching the filter
+	Filter types.Filter
+	// Logs stored per insert
+	BatchSize int
+	// Decode and count the logs without storing them
+	DryRun bool
+}
+
+func DefaultReplayConfig() ReplayConfig {
+	return ReplayConfig{
+		Replay:    kafka.DefaultReplayConfig(TOPIC),
+		BatchSize: DefaultWriterConfig().BatchSize,
+	}
+}
+
+// Replay reads the logs topic again and stores the logs into db, without
+// moving the consumer group of the service. Undecodable messages are
+// reported and skipped, a failed insert stops the replay.
+func Replay(ctx context.Context, config kafka.Config, rc ReplayConfig, db types.IDatabase) (kafka.ReplayStats, error) {
+	if len(rc.Replay.Topics) == 0 {
+		rc.Replay.Topics = []string{TOPIC}
+	}
+	if rc.BatchSize < 1 {
+		rc.BatchSize = 1
+	}
+
+	filter := rc.Replay.Filter
+	rc.Replay.Filter = func(m kafka.Message) bool {
+		if filter != nil && !filter(m) {
+			return false
+		}
+		// Undecodable logs go on to fail in the handler
+		var l types.Log
+		return m.Decode(&l) != nil || rc.Filter.Match(l)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var saveErr error
+	batch := make([]types.Log, 0, rc.BatchSize)
+	flush := func() {
+		if len(batch) == 0 || rc.DryRun || saveErr != nil {
+			batch = batch[:0]
+			return
+		}
+		if saveErr = db.SaveMany(batch); saveErr != nil {
+			cancel()
+		}
+		batch = batch[:0]
+	}
+
+	stats, err := kafka.Replay(ctx, config, rc.Replay, func(ctx context.Context, m kafka.Message, l types.Log) error {
+		l.ID = logID(m)
+		complete(m, &l)
+
+		batch = append(batch, l)
+		if len(batch) == rc.BatchSize {
+			flush()
+		}
+		return nil
+	})
+	if err == nil {
+		flush()
+	}
+
+	// The replay was cancelled by the failed insert
+	if saveErr != nil {
+		return stats, saveErr
+	}
+	return stats, err
+}
@@ -0,0 +1,119 @@
+package internal
+
+import (
+	"context"
+	"eda-logs/internal/types"
+	"eda-shared/kafka"
+	"fmt"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestReplayRebuildsLogs(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	config := kafka.Config{Broker: kafka.NewMemoryBroker(2)}
+	producer, err := kafka.NewProducer[types.Log](config, kafka.DefaultProducerConfig(TOPIC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer producer.Close()
+
+	for i, service := range []string{"users", "orders", "users"} {
+		l := types.Log{ServiceName: service, Message: fmt.Sprint("log ", i)}
+		if err := producer.Send(ctx, service, l); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	db := NewMemoryDatabase()
+	rc := DefaultReplayConfig()
+	rc.BatchSize = 1
+	rc.Filter.Services = []string{"users"}
+
+	// Replaying twice stores every log once
+	for range 2 {
+		stats, err := Replay(ctx, config, rc, db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.Handled != 2 || stats.Skipped != 1 {
+			t.Errorf("stats = %+v", stats)
+		}
+	}
+
+	logs, err := db.Since(primitive.NilObjectID, types.Filter{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 2 || logs[0].Level != types.LEVEL_INFO || logs[0].Timestamp.IsZero() {
+		t.Errorf("stored %+v", logs)
+	}
+
+	rc.DryRun = true
+	dry := NewMemoryDatabase()
+	if _, err := Replay(ctx, config, rc, dry); err != nil {
+		t.Fatal(err)
+	}
+	if logs, _ := dry.Since(primitive.NilObjectID, types.Filter{}, 0); len(logs) != 0 {
+		t.Errorf("dry run stored %d logs", len(logs))
+	}
+}
+
+func TestReplayKeepsConsumedIDs(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	config := kafka.Config{Broker: kafka.NewMemoryBroker(2)}
+	producer, err := kafka.NewProducer[types.Log](config, kafka.DefaultProducerConfig(TOPIC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer producer.Close()
+
+	for i, service := range []string{"users", "orders"} {
+		if err := producer.Send(ctx, service, types.Log{ServiceName: service, Message: fmt.Sprint("log ", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	consumer, err := NewLogConsumer(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer consumer.Close()
+
+	db := NewMemoryDatabase()
+	published := make(chan types.Log, 2)
+	writerConfig := DefaultWriterConfig()
+	writerConfig.FlushInterval = time.Millisecond
+	writer := NewBatchWriter(db, consumer.Commit, published, writerConfig)
+
+	readCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	go writer.Run(readCtx)
+	go func() {
+		if err := consumer.Read(readCtx, writer); err != nil {
+			t.Error(err)
+		}
+	}()
+	for range 2 {
+		select {
+		case <-published:
+		case <-ctx.Done():
+			t.Fatal("logs not consumed")
+		}
+	}
+	stop()
+
+	// Ids come from the position in the topic, the replay stores nothing new
+	if _, err := Replay(ctx, config, DefaultReplayConfig(), db); err != nil {
+		t.Fatal(err)
+	}
+	if logs, _ := db.Since(primitive.NilObjectID, types.Filter{}, 0); len(logs) != 2 {
+		t.Errorf("stored %d logs, want 2", len(logs))
+	}
+}
@@ -0,0 +1,122 @@
+package internal
+
+import (
+	"eda-logs/internal/types"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Matches any service or level in a retention rule
+	ANY = "*"
+)
+
+// RetentionRule keeps the logs of a service and level for MaxAge.
+type RetentionRule struct {
+	Service string        `json:"service"`
+	Level   string        `json:"level"`
+	MaxAge  time.Duration `json:"max_age"`
+}
+
+// Key identifies the rule, it is stored on each log it applies to.
+func (r RetentionRule) Key() string {
+	return r.Service + ":" + r.Level
+}
+
+func (r RetentionRule) match(l types.Log) bool {
+	return (r.Service == ANY || r.Service == l.ServiceName) && (r.Level == ANY || r.Level == l.Level)
+}
+
+func (r RetentionRule) specificity() int {
+	n := 0
+	if r.Service != ANY {
+		n += 2
+	}
+	if r.Level != ANY {
+		n++
+	}
+	return n
+}
+
+// ParseRetention reads rules like "users:info=3d,*:error=90d,*:*=30d".
+// The most specific rule matching a log applies, service before level.
+func ParseRetention(spec string) ([]RetentionRule, error) {
+	var rules []RetentionRule
+	seen := make(map[string]bool)
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		selector, age, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid retention rule %q: expected service:level=duration", part)
+		}
+
+		service, level, ok := strings.Cut(selector, ":")
+		if !ok || service == "" || level == "" {
+			return nil, fmt.Errorf("invalid retention selector %q: expected service:level", selector)
+		}
+
+		maxAge, err := parseAge(age)
+		if err != nil {
+			return nil, fmt.Errorf("invalid retention duration %q: %v", age, err)
+		}
+
+		rule := RetentionRule{Service: service, Level: level, MaxAge: maxAge}
+		if seen[rule.Key()] {
+			return nil, fmt.Errorf("duplicate retention rule for %s", rule.Key())
+		}
+		seen[rule.Key()] = true
+
+		rules = append(rules, rule)
+	}
+
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].specificity() > rules[j].specificity()
+	})
+
+	return rules, nil
+}
+
+// parseAge accepts Go durations plus a day suffix ("30d").
+func parseAge(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+
+	var age time.Duration
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		age = time.Duration(n) * 24 * time.Hour
+	} else {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return 0, err
+		}
+		age = d
+	}
+
+	if age < time.Second {
+		return 0, fmt.Errorf("must be at least 1s")
+	}
+
+	return age, nil
+}
+
+// classify returns the key of the rule applying to l, "" if none does.
+// rules must be sorted most specific first, as returned by ParseRetention.
+func classify(rules []RetentionRule, l types.Log) string {
+	for _, r := range rules {
+		if r.match(l) {
+			return r.Key()
+		}
+	}
+	return ""
+}
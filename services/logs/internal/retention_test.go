@@ -0,0 +1,72 @@
+package internal
+
+import (
+	"eda-logs/internal/types"
+	"testing"
+	"time"
+)
+
+func TestParseRetention(t *testing.T) {
+	rules, err := ParseRetention("*:*=30d, *:error=90d,users:*=12h,users:info=3d")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Most specific first, service before level
+	want := []RetentionRule{
+		{Service: "users", Level: "info", MaxAge: 72 * time.Hour},
+		{Service: "users", Level: ANY, MaxAge: 12 * time.Hour},
+		{Service: ANY, Level: "error", MaxAge: 90 * 24 * time.Hour},
+		{Service: ANY, Level: ANY, MaxAge: 30 * 24 * time.Hour},
+	}
+	if len(rules) != len(want) {
+		t.Fatalf("rules = %v, want %v", rules, want)
+	}
+	for i := range want {
+		if rules[i] != want[i] {
+			t.Errorf("rules[%d] = %v, want %v", i, rules[i], want[i])
+		}
+	}
+}
+
+func TestParseRetentionErrors(t *testing.T) {
+	for _, spec := range []string{
+		"users:info",
+		"users=3d",
+		":info=3d",
+		"users:info=3x",
+		"users:info=500ms",
+		"users:info=0d",
+		"users:info=3d,users:info=4d",
+	} {
+		if _, err := ParseRetention(spec); err == nil {
+			t.Errorf("ParseRetention(%q) succeeded", spec)
+		}
+	}
+}
+
+func TestClassify(t *testing.T) {
+	rules, err := ParseRetention("*:*=30d,*:error=90d,users:*=12h,users:info=3d")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		service, level, want string
+	}{
+		{"users", types.LEVEL_INFO, "users:info"},
+		{"users", types.LEVEL_ERROR, "users:*"},
+		{"orders", types.LEVEL_ERROR, "*:error"},
+		{"orders", types.LEVEL_DEBUG, "*:*"},
+	} {
+		l := types.Log{ServiceName: tc.service, Level: tc.level}
+		if got := classify(rules, l); got != tc.want {
+			t.Errorf("classify(%s, %s) = %q, want %q", tc.service, tc.level, got, tc.want)
+		}
+	}
+
+	// No catch-all rule
+	if got := classify(rules[:1], types.Log{ServiceName: "orders", Level: types.LEVEL_INFO}); got != "" {
+		t.Errorf("classify without matching rule = %q", got)
+	}
+}
@@ -0,0 +1,95 @@
+package internal
+
+import (
+	"eda-logs/internal/types"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SSEHandler streams logs as Server-Sent Events for clients that can't use
+// websockets. Each event carries the stored log id so the browser's automatic
+// reconnection resumes through the Last-Event-ID header.
+type SSEHandler struct {
+	hub  *Hub
+	auth *Authenticator
+}
+
+func NewSSEHandler(hub *Hub, auth *Authenticator) *SSEHandler {
+	return &SSEHandler{hub: hub, auth: auth}
+}
+
+func (h *SSEHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	identity, ok := h.auth.authorize(w, r)
+	if !ok {
+		return
+	}
+
+	filter := parseFilter(r)
+	identity.restrict(&filter)
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("since")
+	}
+
+	since, err := parseSince(lastEventID)
+	if err != nil {
+		http.Error(w, "Invalid Last-Event-ID", http.StatusBadRequest)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+
+	// EventSource from the shop frontend is a cross-origin request
+	if origin := r.Header.Get("Origin"); origin != "" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Vary", "Origin")
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// Disable response buffering in nginx-like proxies
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(format string, args ...any) error {
+		_ = rc.SetWriteDeadline(time.Now().Add(h.hub.config.WriteWait))
+		if _, err := fmt.Fprintf(w, format, args...); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	if err := send("retry: %d\n: connected\n\n", time.Second.Milliseconds()*3); err != nil {
+		return
+	}
+
+	s := h.hub.subscribe(identity.Subject+"@"+r.RemoteAddr, filter)
+	defer h.hub.unsubscribe(s)
+
+	go func() {
+		<-r.Context().Done()
+		h.hub.unsubscribe(s)
+	}()
+
+	err = h.hub.stream(s, since,
+		func(entry types.Log) error {
+			// Multi-line messages need one data field per line
+			data := strings.ReplaceAll(render(entry), "\n", "\ndata: ")
+			return send("id: %s\ndata: %s\n\n", entry.ID.Hex(), data)
+		},
+		func() error { return send(": ping\n\n") },
+	)
+
+	if err != nil {
+		log.Printf("sse write error: %v (dropping conn)", err)
+	}
+}
@@ -0,0 +1,150 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"eda-logs/internal/types"
+	"eda-shared/auth"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// startStreams serves the websocket and SSE handlers of a hub fed by in.
+func startStreams(t *testing.T, db types.IDatabase) (*httptest.Server, chan<- types.Log) {
+	t.Helper()
+
+	in := make(chan types.Log)
+	hub := NewHub(in, db, DefaultHubConfig())
+	go hub.Run()
+	t.Cleanup(func() { close(in) })
+
+	a := NewAuthenticator(testKey, []string{"http://shop.local"})
+	mux := http.NewServeMux()
+	mux.Handle("/", NewWebsocketHandler(hub, a))
+	mux.Handle("/events", NewSSEHandler(hub, a))
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, in
+}
+
+// readEvents returns the ids and data of the next n SSE events.
+func readEvents(t *testing.T, r *bufio.Reader, n int) (ids, data []string) {
+	t.Helper()
+
+	for len(data) < n {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("after %v: %v", data, err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if id, ok := strings.CutPrefix(line, "id: "); ok {
+			ids = append(ids, id)
+		}
+		if d, ok := strings.CutPrefix(line, "data: "); ok {
+			data = append(data, d)
+		}
+	}
+	return ids, data
+}
+
+func TestSSEResumesAfterLastEventID(t *testing.T) {
+	db := NewMemoryDatabase()
+	stored := []types.Log{newLog("users", "1"), newLog("users", "2"), newLog("users", "3")}
+	stored[1].UserID = "alice"
+	stored[2].UserID = "alice"
+	if err := db.SaveMany(stored); err != nil {
+		t.Fatal(err)
+	}
+	server, in := startStreams(t, db)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/events", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+signToken(t, "alice", "user"))
+	req.Header.Set("Last-Event-ID", stored[0].ID.Hex())
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK || res.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("status %d, content type %s", res.StatusCode, res.Header.Get("Content-Type"))
+	}
+	body := bufio.NewReader(res.Body)
+
+	// The backlog after the last event, then live logs: only alice's
+	ids, data := readEvents(t, body, 2)
+	if ids[0] != stored[1].ID.Hex() || ids[1] != stored[2].ID.Hex() {
+		t.Errorf("ids = %v", ids)
+	}
+	if data[1] != render(stored[2]) {
+		t.Errorf("data = %v", data)
+	}
+
+	live := newLog("orders", "4")
+	live.UserID = "alice"
+	in <- newLog("orders", "about bob")
+	in <- live
+	if ids, _ := readEvents(t, body, 1); ids[0] != live.ID.Hex() {
+		t.Errorf("live id = %v, want %s", ids, live.ID.Hex())
+	}
+}
+
+func TestSSERejectsInvalidLastEventID(t *testing.T) {
+	server, _ := startStreams(t, NewMemoryDatabase())
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/events?since=nope", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+signToken(t, "alice", "user"))
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("status %d, want 400", res.StatusCode)
+	}
+}
+
+func TestWebsocketStream(t *testing.T) {
+	server, in := startStreams(t, NewMemoryDatabase())
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/?service=orders"
+
+	// Browsers carry the token in the subprotocols
+	dialer := websocket.Dialer{Subprotocols: []string{BEARER_PROTOCOL, signToken(t, "root", auth.ROLE_ADMIN)}}
+	header := http.Header{"Origin": {"http://shop.local"}}
+	conn, _, err := dialer.Dial(url, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	if _, msg, err := conn.ReadMessage(); err != nil || string(msg) != "[Logs] websocket connected" {
+		t.Fatalf("first message %q, %v", msg, err)
+	}
+
+	in <- newLog("users", "filtered out")
+	live := newLog("orders", "Order 123 created")
+	in <- live
+	if _, msg, err := conn.ReadMessage(); err != nil || string(msg) != render(live) {
+		t.Errorf("message %q, %v", msg, err)
+	}
+
+	header.Set("Origin", "http://evil.local")
+	if _, res, err := dialer.Dial(url, header); err == nil || res.StatusCode != http.StatusForbidden {
+		t.Errorf("foreign origin: %v", err)
+	}
+}
@@ -1,16 +1,71 @@
 package types
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	LEVEL_DEBUG = "debug"
+	LEVEL_INFO  = "info"
+	LEVEL_WARN  = "warn"
+	LEVEL_ERROR = "error"
+)
 
 type Log struct {
-	Message     string `json:"message" bson:"message"`
-	ServiceName string `json:"service_name" bson:"service_name"`
+	ID          primitive.ObjectID `json:"id" bson:"_id"`
+	Message     string             `json:"message" bson:"message"`
+	ServiceName string             `json:"service_name" bson:"service_name"`
+	UserID      string             `json:"user_id,omitempty" bson:"user_id,omitempty"`
+	Level       string             `json:"level" bson:"level"`
+	Timestamp   time.Time          `json:"timestamp" bson:"timestamp"`
+	// Key of the retention rule applying to the log, see EnsureRetention
+	Retention string `json:"-" bson:"retention,omitempty"`
 }
 
 func (l Log) String() string {
 	return fmt.Sprintf("from %s: %s", l.ServiceName, l.Message)
 }
 
+// Filter selects which logs a subscriber receives. Zero value matches everything.
+type Filter struct {
+	Services []string
+	Contains string
+	// Only logs about this user, set for non-admin subscribers
+	UserID string
+}
+
+func (f Filter) Match(l Log) bool {
+	if f.UserID != "" && f.UserID != l.UserID {
+		return false
+	}
+
+	if len(f.Services) > 0 {
+		found := false
+		for _, s := range f.Services {
+			if s == l.ServiceName {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return f.Contains == "" || strings.Contains(l.Message, f.Contains)
+}
+
+// IDatabase stores the logs. internal.Database (MongoDB) and
+// internal.MemoryDatabase pass the same conformance suite.
 type IDatabase interface {
-	Save(data Log) error
+	// SaveMany stores the logs, already stored ids are ignored so a batch
+	// can be retried after a partial failure.
+	SaveMany(data []Log) error
+	// Since returns up to limit logs matching filter stored after id, oldest
+	// first. A limit of 0 returns them all.
+	Since(id primitive.ObjectID, filter Filter, limit int64) ([]Log, error)
 }
@@ -1,64 +1,99 @@
 package internal
 
 import (
+	"eda-logs/internal/types"
 	"log"
 	"net/http"
-	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
 )
 
-type WebsocketHub struct {
+type WebsocketHandler struct {
+	hub     *Hub
+	auth    *Authenticator
 	upgrade websocket.Upgrader
-	in      <-chan string
-
-	mu    sync.Mutex
-	conns map[*websocket.Conn]struct{}
 }
 
-func NewWebsocketHub(in <-chan string) *WebsocketHub {
-	return &WebsocketHub{
+func NewWebsocketHandler(hub *Hub, auth *Authenticator) *WebsocketHandler {
+	return &WebsocketHandler{
+		hub:  hub,
+		auth: auth,
 		upgrade: websocket.Upgrader{
-			CheckOrigin: func(r *http.Request) bool { return true },
+			CheckOrigin:  auth.CheckOrigin,
+			Subprotocols: []string{BEARER_PROTOCOL},
 		},
-		in:    in,
-		conns: make(map[*websocket.Conn]struct{}),
 	}
 }
 
-func (h *WebsocketHub) Run() {
-	for msg := range h.in {
-		h.mu.Lock()
-		for c := range h.conns {
-			_ = c.SetWriteDeadline(time.Now().Add(5 * time.Second))
-			if err := c.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
-				log.Printf("websocket write error: %v (dropping conn)", err)
-				_ = c.Close()
-				delete(h.conns, c)
+// reader consumes incoming frames so control frames (pong, close) are
+// processed. Returns once the peer goes away or stops answering pings.
+func (h *WebsocketHandler) reader(conn *websocket.Conn) {
+	_ = conn.SetReadDeadline(time.Now().Add(h.hub.config.PongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(h.hub.config.PongWait))
+	})
+
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.Printf("websocket read error: %v", err)
 			}
+			return
 		}
-		h.mu.Unlock()
 	}
 }
 
-func (h *WebsocketHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	c, err := h.upgrade.Upgrade(w, r, nil)
+// ServeHTTP streams logs over a websocket. Supports the same query parameters
+// as the SSE endpoint: service, q, and since to resume after a stored log id.
+func (h *WebsocketHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	identity, ok := h.auth.authorize(w, r)
+	if !ok {
+		return
+	}
+
+	filter := parseFilter(r)
+	identity.restrict(&filter)
+
+	since, err := parseSince(r.URL.Query().Get("since"))
+	if err != nil {
+		http.Error(w, "Invalid since parameter", http.StatusBadRequest)
+		return
+	}
+
+	conn, err := h.upgrade.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("error upgrading to websocket: %v", err)
 		return
 	}
+	defer conn.Close()
 
-	_ = c.WriteMessage(websocket.TextMessage, []byte("[Logs] websocket connected"))
+	s := h.hub.subscribe(identity.Subject+"@"+conn.RemoteAddr().String(), filter)
+	defer h.hub.unsubscribe(s)
 
-	h.mu.Lock()
-	h.conns[c] = struct{}{}
-	h.mu.Unlock()
+	go func() {
+		h.reader(conn)
+		h.hub.unsubscribe(s)
+	}()
 
-	<-r.Context().Done()
+	write := func(messageType int, data []byte) error {
+		_ = conn.SetWriteDeadline(time.Now().Add(h.hub.config.WriteWait))
+		return conn.WriteMessage(messageType, data)
+	}
+
+	if err := write(websocket.TextMessage, []byte("[Logs] websocket connected")); err != nil {
+		return
+	}
+
+	err = h.hub.stream(s, since,
+		func(entry types.Log) error { return write(websocket.TextMessage, []byte(render(entry))) },
+		func() error { return write(websocket.PingMessage, nil) },
+	)
+
+	if err != nil {
+		log.Printf("websocket write error: %v (dropping conn)", err)
+		return
+	}
 
-	h.mu.Lock()
-	delete(h.conns, c)
-	h.mu.Unlock()
-	_ = c.Close()
+	_ = write(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
 }
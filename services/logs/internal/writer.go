@@ -0,0 +1,154 @@
+package internal
+
+import (
+	"context"
+	"eda-logs/internal/types"
+	"eda-shared/kafka"
+	"log"
+	"time"
+)
+
+type WriterConfig struct {
+	BatchSize     int
+	FlushInterval time.Duration
+	// Backoff between retries of a failed batch, doubled up to MaxBackoff
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+func DefaultWriterConfig() WriterConfig {
+	return WriterConfig{
+		BatchSize:     500,
+		FlushInterval: 200 * time.Millisecond,
+		MinBackoff:    100 * time.Millisecond,
+		MaxBackoff:    10 * time.Second,
+	}
+}
+
+// pending is a consumed message waiting for its batch to be stored. Messages
+// that couldn't be decoded have no entry, they are only committed.
+type pending struct {
+	msg   kafka.Message
+	entry *types.Log
+}
+
+// BatchWriter stores logs with one insert per batch, flushed when full or
+// every FlushInterval. A failed batch is retried until it succeeds, meanwhile
+// Add blocks so the Kafka reader slows down instead of dropping logs. Offsets
+// are committed and logs published to the hub only once their batch is stored.
+type BatchWriter struct {
+	db      types.IDatabase
+	commit  func(ctx context.Context, msgs ...kafka.Message) error
+	publish chan<- types.Log
+	config  WriterConfig
+
+	in chan pending
+}
+
+func NewBatchWriter(db types.IDatabase, commit func(ctx context.Context, msgs ...kafka.Message) error, publish chan<- types.Log, config WriterConfig) *BatchWriter {
+	if config.BatchSize < 1 {
+		config.BatchSize = 1
+	}
+
+	return &BatchWriter{
+		db:      db,
+		commit:  commit,
+		publish: publish,
+		config:  config,
+		in:      make(chan pending, config.BatchSize),
+	}
+}
+
+// Add queues a message, blocking while the writer is behind.
+func (b *BatchWriter) Add(ctx context.Context, msg kafka.Message, entry *types.Log) error {
+	select {
+	case b.in <- pending{msg: msg, entry: entry}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *BatchWriter) Run(ctx context.Context) {
+	ticker := time.NewTicker(b.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]pending, 0, b.config.BatchSize)
+
+	for {
+		select {
+		case p := <-b.in:
+			batch = append(batch, p)
+			if len(batch) < b.config.BatchSize {
+				continue
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+		case <-ctx.Done():
+			return
+		}
+
+		if err := b.flush(ctx, batch); err != nil {
+			return
+		}
+		batch = batch[:0]
+	}
+}
+
+func (b *BatchWriter) flush(ctx context.Context, batch []pending) error {
+	entries := make([]types.Log, 0, len(batch))
+	msgs := make([]kafka.Message, 0, len(batch))
+	for _, p := range batch {
+		if p.entry != nil {
+			entries = append(entries, *p.entry)
+		}
+		msgs = append(msgs, p.msg)
+	}
+
+	if len(entries) > 0 {
+		err := b.retry(ctx, "insert", func() error { return b.db.SaveMany(entries) })
+		if err != nil {
+			return err
+		}
+		log.Printf("Inserted batch of %d logs", len(entries))
+	}
+
+	err := b.retry(ctx, "commit", func() error { return b.commit(ctx, msgs...) })
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		select {
+		case b.publish <- entry:
+		default:
+			log.Println("logger channel full, dropping message")
+		}
+	}
+
+	return nil
+}
+
+// retry calls fn until it succeeds or ctx is cancelled.
+func (b *BatchWriter) retry(ctx context.Context, what string, fn func() error) error {
+	backoff := b.config.MinBackoff
+
+	for {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+
+		log.Printf("Batch %s failed, retrying in %s: %v", what, backoff, err)
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		backoff = min(backoff*2, b.config.MaxBackoff)
+	}
+}
@@ -0,0 +1,200 @@
+package internal
+
+import (
+	"context"
+	"eda-logs/internal/types"
+	"eda-shared/kafka"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// flakyDatabase fails the first fail inserts.
+type flakyDatabase struct {
+	*MemoryDatabase
+
+	mu      sync.Mutex
+	fail    int
+	inserts int
+}
+
+func (db *flakyDatabase) SaveMany(data []types.Log) error {
+	db.mu.Lock()
+	db.inserts++
+	if db.fail > 0 {
+		db.fail--
+		db.mu.Unlock()
+		return errors.New("connection reset")
+	}
+	db.mu.Unlock()
+
+	return db.MemoryDatabase.SaveMany(data)
+}
+
+func (db *flakyDatabase) stored(t *testing.T) int {
+	t.Helper()
+	logs, err := db.Since(primitive.NilObjectID, types.Filter{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(logs)
+}
+
+// startWriter runs a BatchWriter whose commits record the offsets and how
+// many logs were stored at the time.
+func startWriter(t *testing.T, db *flakyDatabase, config WriterConfig) (*BatchWriter, chan types.Log, func() []string) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var mu sync.Mutex
+	var commits []string
+	commit := func(ctx context.Context, msgs ...kafka.Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		var offsets []int64
+		for _, m := range msgs {
+			offsets = append(offsets, m.Offset)
+		}
+		commits = append(commits, fmt.Sprintf("%v stored=%d", offsets, db.stored(t)))
+		return nil
+	}
+
+	publish := make(chan types.Log, 10)
+	writer := NewBatchWriter(db, commit, publish, config)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		writer.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	return writer, publish, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), commits...)
+	}
+}
+
+func addLogs(t *testing.T, writer *BatchWriter, offsets ...int64) {
+	t.Helper()
+	for _, offset := range offsets {
+		entry := &types.Log{ID: primitive.NewObjectID(), Message: fmt.Sprint("log ", offset)}
+		// Negative offsets stand for undecodable messages
+		if offset < 0 {
+			entry = nil
+		}
+		if err := writer.Add(context.Background(), kafka.Message{Offset: offset}, entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func waitCommits(t *testing.T, commits func() []string, n int) []string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if c := commits(); len(c) >= n {
+			return c
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("commits = %v, want %d", commits(), n)
+	return nil
+}
+
+func TestBatchWriterFlushesFullBatches(t *testing.T) {
+	db := &flakyDatabase{MemoryDatabase: NewMemoryDatabase()}
+	config := DefaultWriterConfig()
+	config.BatchSize = 2
+	config.FlushInterval = time.Hour
+	writer, publish, commits := startWriter(t, db, config)
+
+	addLogs(t, writer, 0, 1, 2, 3)
+
+	got := waitCommits(t, commits, 2)
+	want := []string{"[0 1] stored=2", "[2 3] stored=4"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("commits = %q, want %q", got, want)
+	}
+	if db.inserts != 2 {
+		t.Errorf("%d inserts, want one per batch", db.inserts)
+	}
+	if len(publish) != 4 {
+		t.Errorf("%d logs published, want 4", len(publish))
+	}
+}
+
+func TestBatchWriterFlushesOnInterval(t *testing.T) {
+	db := &flakyDatabase{MemoryDatabase: NewMemoryDatabase()}
+	config := DefaultWriterConfig()
+	config.BatchSize = 100
+	config.FlushInterval = 10 * time.Millisecond
+	writer, _, commits := startWriter(t, db, config)
+
+	// An undecodable message is committed without insert
+	addLogs(t, writer, 0, -1)
+
+	got := waitCommits(t, commits, 1)
+	if got[0] != "[0 -1] stored=1" {
+		t.Errorf("commit = %q, want %q", got[0], "[0 -1] stored=1")
+	}
+}
+
+func TestBatchWriterRetriesBeforeCommit(t *testing.T) {
+	db := &flakyDatabase{MemoryDatabase: NewMemoryDatabase(), fail: 2}
+	config := DefaultWriterConfig()
+	config.BatchSize = 2
+	config.FlushInterval = time.Hour
+	config.MinBackoff = time.Millisecond
+	config.MaxBackoff = 2 * time.Millisecond
+	writer, publish, commits := startWriter(t, db, config)
+
+	addLogs(t, writer, 0, 1)
+
+	got := waitCommits(t, commits, 1)
+	if got[0] != "[0 1] stored=2" {
+		t.Errorf("commit = %q, want the offsets committed after the logs are stored", got[0])
+	}
+	if db.inserts != 3 {
+		t.Errorf("%d inserts, want 2 failed and 1 stored", db.inserts)
+	}
+	if len(publish) != 2 {
+		t.Errorf("%d logs published, want 2", len(publish))
+	}
+}
+
+func TestBatchWriterStopsRetryingOnCancel(t *testing.T) {
+	db := &flakyDatabase{MemoryDatabase: NewMemoryDatabase(), fail: 1 << 30}
+	config := DefaultWriterConfig()
+	config.BatchSize = 1
+	config.MinBackoff = time.Millisecond
+	writer := NewBatchWriter(db, func(ctx context.Context, msgs ...kafka.Message) error {
+		t.Error("commit of a batch that was never stored")
+		return nil
+	}, make(chan types.Log, 1), config)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		writer.Run(ctx)
+	}()
+
+	addLogs(t, writer, 0)
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("writer still retrying after cancel")
+	}
+}
@@ -0,0 +1,135 @@
+// Package logs is logs-service: it stores the logs consumed from Kafka and
+// streams them over websocket and SSE. cmd/logs runs it with the
+// environment configuration, tests run it in-process against in-memory
+// dependencies.
+package logs
+
+import (
+	"context"
+	"eda-logs/internal"
+	"eda-logs/internal/types"
+	"eda-shared/kafka"
+	"net/http"
+	"time"
+)
+
+const (
+	PORT = ":3000"
+)
+
+type Config struct {
+	Kafka kafka.Config
+	// Connects to MongoDB when nil. Retention and archival need MongoDB.
+	Database types.IDatabase
+	Writer   internal.WriterConfig
+	Hub      internal.HubConfig
+	// Retention rules, e.g. users:info=3d,*:*=30d
+	Retention string
+	// Archival is disabled without directory
+	ArchiveDir      string
+	ArchiveGrace    time.Duration
+	ArchiveInterval time.Duration
+	SigningKey      string
+	AllowedOrigins  []string
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Writer:          internal.DefaultWriterConfig(),
+		Hub:             internal.DefaultHubConfig(),
+		Retention:       "*:*=30d",
+		ArchiveGrace:    24 * time.Hour,
+		ArchiveInterval: time.Hour,
+		SigningKey:      "signing-key",
+		AllowedOrigins:  []string{"http://localhost:8080"},
+	}
+}
+
+// NewMemoryDatabase returns a database keeping the logs in memory
+func NewMemoryDatabase() types.IDatabase {
+	return internal.NewMemoryDatabase()
+}
+
+type Service struct {
+	config   Config
+	client   *internal.LogConsumer
+	writer   *internal.BatchWriter
+	hub      *internal.Hub
+	archiver *internal.Archiver
+	mux      *http.ServeMux
+}
+
+func New(config Config) (*Service, error) {
+	if config.Kafka.Registry == nil {
+		config.Kafka.Registry = kafka.NewRegistry("logs-service")
+	}
+
+	logger := make(chan types.Log, 256)
+
+	rules, err := internal.ParseRetention(config.Retention)
+	if err != nil {
+		return nil, err
+	}
+
+	db := config.Database
+	archiver := internal.NewArchiver(nil, rules, "")
+
+	if db == nil {
+		mongo, err := internal.NewDatabase()
+		if err != nil {
+			return nil, err
+		}
+		db = mongo
+
+		archiver = internal.NewArchiver(mongo, rules, config.ArchiveDir)
+
+		// Without archival the TTL indexes delete logs as soon as they expire
+		var grace time.Duration
+		if archiver.Enabled() {
+			grace = config.ArchiveGrace
+		}
+
+		if err := mongo.EnsureRetention(context.Background(), rules, grace); err != nil {
+			return nil, err
+		}
+	}
+
+	client, err := internal.NewLogConsumer(config.Kafka)
+	if err != nil {
+		return nil, err
+	}
+
+	writer := internal.NewBatchWriter(db, client.Commit, logger, config.Writer)
+	hub := internal.NewHub(logger, db, config.Hub)
+
+	auth := internal.NewAuthenticator(config.SigningKey, config.AllowedOrigins)
+
+	mux := http.NewServeMux()
+	mux.Handle("/", internal.NewWebsocketHandler(hub, auth))
+	mux.Handle("/events", internal.NewSSEHandler(hub, auth))
+	mux.Handle("/admin/archive", internal.NewArchiveHandler(archiver, auth))
+	mux.Handle("/topology", config.Kafka.Registry)
+	mux.Handle("/asyncapi", config.Kafka.Registry.AsyncAPIHandler())
+
+	return &Service{config, client, writer, hub, archiver, mux}, nil
+}
+
+func (s *Service) Handler() http.Handler {
+	return s.mux
+}
+
+// Run consumes the logs until ctx is cancelled.
+func (s *Service) Run(ctx context.Context) error {
+	go s.writer.Run(ctx)
+	go s.hub.Run()
+
+	if s.archiver.Enabled() {
+		go s.archiver.Run(ctx, s.config.ArchiveInterval)
+	}
+
+	return s.client.Read(ctx, s.writer)
+}
+
+func (s *Service) Close() {
+	s.client.Close()
+}
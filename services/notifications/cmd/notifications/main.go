@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	notifications "eda-notifications"
+	"eda-shared/env"
+	"eda-shared/kafka"
+	"log"
+	"net/http"
+	"os"
+)
+
+func main() {
+
+	config := notifications.DefaultConfig()
+	config.PreferencesFile = os.Getenv("PREFERENCES_FILE")
+	config.InboxFile = os.Getenv("INBOX_FILE")
+	config.WebhooksFile = os.Getenv("WEBHOOKS_FILE")
+	config.SeenEventsFile = os.Getenv("SEEN_EVENTS_FILE")
+	config.Email.Addr = os.Getenv("SMTP_ADDR")
+	config.Email.From = env.Get("SMTP_FROM", config.Email.From)
+	config.Email.Username = os.Getenv("SMTP_USERNAME")
+	config.Email.Password = os.Getenv("SMTP_PASSWORD")
+	config.AllowPrivateWebhooks = os.Getenv("WEBHOOK_ALLOW_PRIVATE") == "true"
+	config.DefaultLocale = env.Get("DEFAULT_LOCALE", config.DefaultLocale)
+	config.CollapseWindows = env.Get("COLLAPSE_WINDOWS", config.CollapseWindows)
+	config.SigningKey = []byte(env.Get("JWT_SIGNING_KEY", string(config.SigningKey)))
+
+	var err error
+	config.Kafka, err = kafka.ConfigFromEnv("notifications-service")
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	service, err := notifications.New(config)
+	if err != nil {
+		log.Fatalf("Error starting notification service: %v", err)
+	}
+	defer service.Close()
+
+	go func() {
+		if err := http.ListenAndServe(notifications.PORT, service.Handler()); err != nil {
+			log.Fatalf("Failed to start server: %v", err)
+		}
+	}()
+
+	log.Println("Starting notification service...")
+	err = service.Run(context.Background())
+
+	if err != nil {
+		log.Fatalf("Error reading messages: %v", err)
+	}
+}
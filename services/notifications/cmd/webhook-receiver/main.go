@@ -0,0 +1,55 @@
+// Local stand-in for a partner endpoint: logs the webhooks it receives and
+// checks their signature. Can fail on purpose to exercise retries and the
+// automatic disabling of subscriptions.
+//
+//	go run ./cmd/webhook-receiver -secret whsec_... -fail-every 3
+package main
+
+import (
+	"eda-notifications/internal"
+	"flag"
+	"io"
+	"log"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+func main() {
+	addr := flag.String("addr", ":4000", "listen address")
+	secret := flag.String("secret", "", "subscription secret, signatures aren't checked when empty")
+	failEvery := flag.Int("fail-every", 0, "answer 500 to every nth request (0 never fails)")
+	flag.Parse()
+
+	var count atomic.Int64
+
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		n := count.Add(1)
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "Failed to read body", http.StatusBadRequest)
+			return
+		}
+
+		if *secret != "" {
+			if err := internal.Verify(*secret, r.Header, body, 5*time.Minute); err != nil {
+				log.Printf("#%d rejected %s: %v", n, r.Header.Get(internal.HEADER_EVENT_ID), err)
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+		}
+
+		if *failEvery > 0 && n%int64(*failEvery) == 0 {
+			log.Printf("#%d failing %s on purpose", n, r.Header.Get(internal.HEADER_EVENT_ID))
+			http.Error(w, "Simulated failure", http.StatusInternalServerError)
+			return
+		}
+
+		log.Printf("#%d %s %s: %s", n, r.Header.Get(internal.HEADER_EVENT), r.Header.Get(internal.HEADER_EVENT_ID), body)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	log.Printf("Webhook receiver listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
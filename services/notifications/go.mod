@@ -2,9 +2,21 @@ module eda-notifications
 
 go 1.25.2
 
-require github.com/segmentio/kafka-go v0.4.49
+require github.com/golang-jwt/jwt/v5 v5.3.0 // indirect
 
 require (
+	github.com/segmentio/kafka-go v0.4.49 // indirect
+	github.com/xdg-go/pbkdf2 v1.0.0 // indirect
+	github.com/xdg-go/scram v1.1.2 // indirect
+	github.com/xdg-go/stringprep v1.0.4 // indirect
+	golang.org/x/text v0.23.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+require (
+	eda-shared v0.0.0
 	github.com/klauspost/compress v1.15.9 // indirect
 	github.com/pierrec/lz4/v4 v4.1.15 // indirect
 )
+
+replace eda-shared => ../shared
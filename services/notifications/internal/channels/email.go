@@ -0,0 +1,116 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"eda-notifications/internal/types"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+const (
+	EMAIL = "email"
+)
+
+type EmailConfig struct {
+	// host:port of the SMTP server, e.g. mailhog:1025
+	Addr     string
+	From     string
+	Username string
+	Password string
+}
+
+// Email sends notifications through an SMTP server.
+type Email struct {
+	config EmailConfig
+}
+
+func NewEmail(config EmailConfig) *Email {
+	return &Email{config: config}
+}
+
+func (e *Email) Name() string {
+	return EMAIL
+}
+
+func (e *Email) Reachable(p types.Preferences) bool {
+	return p.Email != ""
+}
+
+func (e *Email) Send(ctx context.Context, p types.Preferences, n types.Notification, msg types.Message) error {
+	var auth smtp.Auth
+	if e.config.Username != "" {
+		host, _, err := net.SplitHostPort(e.config.Addr)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", e.config.Username, e.config.Password, host)
+	}
+
+	data, err := e.message(p.Email, msg)
+	if err != nil {
+		return err
+	}
+
+	// net/smtp has no context support, run it aside to honour cancellation
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(e.config.Addr, auth, e.config.From, []string{p.Email}, data)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// message builds a multipart/alternative mail with the text and html bodies,
+// or a plain text mail when there is no html.
+func (e *Email) message(to string, msg types.Message) ([]byte, error) {
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, "From: %s\r\n", e.config.From)
+	fmt.Fprintf(&b, "To: %s\r\n", to)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	if msg.Locale != "" {
+		fmt.Fprintf(&b, "Content-Language: %s\r\n", msg.Locale)
+	}
+	b.WriteString("MIME-Version: 1.0\r\n")
+
+	if msg.HTML == "" {
+		b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+		b.WriteString(msg.Text)
+		b.WriteString("\r\n")
+		return b.Bytes(), nil
+	}
+
+	mw := multipart.NewWriter(&b)
+	fmt.Fprintf(&b, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", mw.Boundary())
+
+	parts := []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	}
+	for _, part := range parts {
+		w, err := mw.CreatePart(map[string][]string{"Content-Type": {part.contentType}})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write([]byte(part.body + "\r\n")); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
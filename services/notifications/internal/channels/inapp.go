@@ -0,0 +1,37 @@
+package channels
+
+import (
+	"context"
+	"eda-notifications/internal/types"
+)
+
+const (
+	IN_APP = "in-app"
+)
+
+// InApp stores notifications in the user's inbox shown by the shop frontend.
+type InApp struct {
+	inbox types.IInbox
+}
+
+func NewInApp(inbox types.IInbox) *InApp {
+	return &InApp{inbox: inbox}
+}
+
+func (i *InApp) Name() string {
+	return IN_APP
+}
+
+func (i *InApp) Reachable(p types.Preferences) bool {
+	return p.UserID != ""
+}
+
+func (i *InApp) Send(ctx context.Context, p types.Preferences, n types.Notification, msg types.Message) error {
+	_, err := i.inbox.Add(types.InboxItem{
+		UserID:  p.UserID,
+		Type:    n.Type,
+		Subject: msg.Subject,
+		Message: msg.Text,
+	})
+	return err
+}
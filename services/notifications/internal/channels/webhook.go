@@ -0,0 +1,125 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"eda-notifications/internal/types"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+const (
+	WEBHOOK = "webhook"
+)
+
+var ErrPrivateAddress = errors.New("webhook address is not public")
+
+// Webhook posts notifications as JSON to the URL chosen by the user. Since
+// any user chooses it, only public addresses are dialed, checked after DNS
+// resolution and on every redirect, unless allowPrivate (local setups).
+type Webhook struct {
+	client *http.Client
+}
+
+func NewWebhook(timeout time.Duration, allowPrivate bool) *Webhook {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = dialPublic
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	// A proxy would be dialed instead of the webhook address
+	transport.Proxy = nil
+
+	return &Webhook{client: &http.Client{Timeout: timeout, Transport: transport}}
+}
+
+// PublicAddr tells whether addr is neither loopback, private nor link-local
+// (which includes the cloud metadata endpoints).
+func PublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate()
+}
+
+// dialPublic is the dialer Control refusing the resolved non-public addresses.
+func dialPublic(network, address string, c syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !PublicAddr(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, addrPort.Addr())
+	}
+	return nil
+}
+
+// CheckWebhookURL validates a webhook URL chosen by a user: http(s) with a
+// host resolving to public addresses only, unless allowPrivate.
+func CheckWebhookURL(ctx context.Context, rawURL string, allowPrivate bool) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return errors.New("expected an http(s) URL")
+	}
+	if allowPrivate {
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", u.Hostname())
+	if err != nil {
+		return fmt.Errorf("can't resolve %s", u.Hostname())
+	}
+	for _, addr := range addrs {
+		if !PublicAddr(addr) {
+			return fmt.Errorf("%w: %s", ErrPrivateAddress, addr)
+		}
+	}
+	return nil
+}
+
+func (w *Webhook) Name() string {
+	return WEBHOOK
+}
+
+func (w *Webhook) Reachable(p types.Preferences) bool {
+	return p.WebhookURL != ""
+}
+
+type webhookPayload struct {
+	Type    string         `json:"type,omitempty"`
+	UserID  string         `json:"user_id"`
+	Params  map[string]any `json:"params,omitempty"`
+	Message types.Message  `json:"message"`
+}
+
+func (w *Webhook) Send(ctx context.Context, p types.Preferences, n types.Notification, msg types.Message) error {
+	body, err := json.Marshal(webhookPayload{Type: n.Type, UserID: n.UserID, Params: n.Params, Message: msg})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s answered %s", p.WebhookURL, res.Status)
+	}
+
+	return nil
+}
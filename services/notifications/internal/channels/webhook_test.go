@@ -0,0 +1,65 @@
+package channels
+
+import (
+	"context"
+	"eda-notifications/internal/types"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCheckWebhookURL(t *testing.T) {
+	for _, tc := range []struct {
+		url     string
+		private bool
+	}{
+		{"http://127.0.0.1:8080/hook", true},
+		{"http://[::1]/hook", true},
+		{"http://10.0.0.5/hook", true},
+		{"https://192.168.1.1/hook", true},
+		{"http://[fd00::1]/hook", true},
+		// Cloud metadata endpoint
+		{"http://169.254.169.254/latest/meta-data", true},
+		{"http://0.0.0.0/hook", true},
+		{"http://[::ffff:127.0.0.1]/hook", true},
+		{"https://93.184.216.34/hook", false},
+		{"https://[2606:2800:220:1::1]/hook", false},
+	} {
+		err := CheckWebhookURL(context.Background(), tc.url, false)
+		if got := errors.Is(err, ErrPrivateAddress); got != tc.private || (!tc.private && err != nil) {
+			t.Errorf("CheckWebhookURL(%s) = %v", tc.url, err)
+		}
+	}
+
+	for _, url := range []string{"ftp://93.184.216.34/hook", "http:///hook", "93.184.216.34"} {
+		if err := CheckWebhookURL(context.Background(), url, false); err == nil {
+			t.Errorf("CheckWebhookURL(%s) accepted", url)
+		}
+	}
+
+	if err := CheckWebhookURL(context.Background(), "http://127.0.0.1/hook", true); err != nil {
+		t.Errorf("private address refused despite allowPrivate: %v", err)
+	}
+}
+
+func TestWebhookRefusesPrivateAddresses(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+	defer server.Close()
+
+	p := types.Preferences{UserID: "alice", WebhookURL: server.URL}
+	n := types.Notification{Type: types.ORDER_CREATED, UserID: "alice"}
+
+	err := NewWebhook(time.Second, false).Send(context.Background(), p, n, types.Message{})
+	if !errors.Is(err, ErrPrivateAddress) || calls != 0 {
+		t.Errorf("err = %v after %d calls, want the loopback address refused", err, calls)
+	}
+
+	if err := NewWebhook(time.Second, true).Send(context.Background(), p, n, types.Message{}); err != nil || calls != 1 {
+		t.Errorf("err = %v after %d calls, want one delivery", err, calls)
+	}
+}
@@ -0,0 +1,38 @@
+package internal
+
+import (
+	"eda-notifications/internal/types"
+	"eda-shared/kafka"
+	"flag"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the contracts published in ressources/contracts")
+
+// Contracts of the consumers, see kafka.Contract
+const CONTRACTS = "../../../ressources/contracts"
+
+func TestConsumerContracts(t *testing.T) {
+	// Rendered from a template, or preformatted by producers predating them.
+	// The params depend on the type, see the templates.
+	notification, err := kafka.NewContract[types.Notification]("notifications-service", NOTIFICATION_TOPIC,
+		`{"event_id": "payment.processed:123", "type": "payment.processed", "user_id": "456", "params": {}}`,
+		`{"user_id": "456", "action": "Order 123 shipped"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, m := range notification.Messages() {
+		var n types.Notification
+		if err := m.Decode(&n); err != nil {
+			t.Fatal(err)
+		}
+		if n.UserID == "" || n.Type == "" && n.Action == "" {
+			t.Errorf("%s has no recipient or content", m.Value)
+		}
+	}
+
+	if err := kafka.PublishContracts(CONTRACTS, *update, notification); err != nil {
+		t.Error(err)
+	}
+}
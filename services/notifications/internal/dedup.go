@@ -0,0 +1,203 @@
+package internal
+
+import (
+	"eda-notifications/internal/types"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Deduplicator remembers handled event ids for ttl, keeping at most
+// capacity of them: the oldest are forgotten first. Ids are saved to store
+// as they are marked, and loaded back on start.
+type Deduplicator struct {
+	ttl      time.Duration
+	capacity int
+	store    types.ISeenEventStore
+	now      func() time.Time
+
+	mu    sync.Mutex
+	seen  map[string]time.Time // id -> expiry
+	order []string             // ids oldest first
+}
+
+func NewDeduplicator(ttl time.Duration, capacity int, store types.ISeenEventStore) (*Deduplicator, error) {
+	d := &Deduplicator{
+		ttl:      ttl,
+		capacity: max(capacity, 1),
+		store:    store,
+		now:      time.Now,
+		seen:     make(map[string]time.Time),
+	}
+
+	events, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+	// Expired ids are evicted by the next call
+	for _, e := range events {
+		if _, ok := d.seen[e.ID]; !ok {
+			d.seen[e.ID] = e.Expiry
+			d.order = append(d.order, e.ID)
+		}
+	}
+
+	return d, nil
+}
+
+// Seen tells whether id was marked within the ttl.
+func (d *Deduplicator) Seen(id string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.evict(d.now(), d.capacity)
+
+	_, ok := d.seen[id]
+	return ok
+}
+
+// Mark records id once its notification is handled and saves the ids. On a
+// failed save the id is still remembered until the process exits.
+func (d *Deduplicator) Mark(id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now()
+	d.evict(now, d.capacity)
+
+	if _, ok := d.seen[id]; ok {
+		return nil
+	}
+
+	// Room for id
+	d.evict(now, d.capacity-1)
+	d.seen[id] = now.Add(d.ttl)
+	d.order = append(d.order, id)
+
+	events := make([]types.SeenEvent, 0, len(d.order))
+	for _, id := range d.order {
+		events = append(events, types.SeenEvent{ID: id, Expiry: d.seen[id]})
+	}
+	return d.store.Save(events)
+}
+
+// evict forgets expired ids and the oldest past keep, it must be called with
+// d.mu held. Ids share the same ttl, so expiries follow insertion order.
+func (d *Deduplicator) evict(now time.Time, keep int) {
+	n := 0
+	for n < len(d.order) {
+		id := d.order[n]
+		if len(d.order)-n <= keep && now.Before(d.seen[id]) {
+			break
+		}
+		delete(d.seen, id)
+		n++
+	}
+
+	if n > 0 {
+		d.order = append(d.order[:0], d.order[n:]...)
+	}
+}
+
+type burst struct {
+	until      time.Time
+	suppressed int
+	// Event which opened the burst, retried if its dispatch failed
+	first string
+}
+
+// Collapser turns bursts of notifications sharing a collapse key into one:
+// the first is delivered, the others are suppressed until the window of
+// their type, opened by the first, closes.
+type Collapser struct {
+	windows map[string]time.Duration
+	now     func() time.Time
+
+	mu     sync.Mutex
+	bursts map[string]*burst
+	swept  time.Time
+}
+
+func NewCollapser(windows map[string]time.Duration) *Collapser {
+	return &Collapser{
+		windows: windows,
+		now:     time.Now,
+		bursts:  make(map[string]*burst),
+	}
+}
+
+// ParseCollapseWindows reads "user.logged_in=10m,stock.failed=1m".
+func ParseCollapseWindows(spec string) (map[string]time.Duration, error) {
+	windows := make(map[string]time.Duration)
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		notificationType, window, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid collapse window %q: expected type=duration", part)
+		}
+
+		d, err := time.ParseDuration(window)
+		if err != nil {
+			return nil, fmt.Errorf("invalid collapse window %q: %v", part, err)
+		}
+		windows[notificationType] = d
+	}
+
+	return windows, nil
+}
+
+// key is the collapse key of the notification, the producer's one or
+// type and user for types with a window.
+func (c *Collapser) key(n types.Notification) (string, time.Duration) {
+	window, ok := c.windows[n.Type]
+	if !ok {
+		return "", 0
+	}
+
+	if n.CollapseKey != "" {
+		return n.Type + ":" + n.CollapseKey, window
+	}
+	return n.Type + ":" + n.UserID, window
+}
+
+// Collapse tells whether the notification of event eventID falls within an
+// open burst and must be suppressed, with the number of notifications
+// suppressed so far.
+func (c *Collapser) Collapse(eventID string, n types.Notification) (bool, int) {
+	key, window := c.key(n)
+	if key == "" {
+		return false, 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+
+	// Drop closed bursts once in a while
+	if now.Sub(c.swept) > time.Minute {
+		for k, b := range c.bursts {
+			if !now.Before(b.until) {
+				delete(c.bursts, k)
+			}
+		}
+		c.swept = now
+	}
+
+	if b, ok := c.bursts[key]; ok && now.Before(b.until) {
+		if b.first == eventID {
+			return false, 0
+		}
+		b.suppressed++
+		return true, b.suppressed
+	}
+
+	c.bursts[key] = &burst{until: now.Add(window), first: eventID}
+	return false, 0
+}
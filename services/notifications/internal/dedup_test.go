@@ -0,0 +1,201 @@
+package internal
+
+import (
+	"eda-notifications/internal/store"
+	"eda-notifications/internal/types"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// clock is a manual clock for the now fields.
+type clock struct {
+	t time.Time
+}
+
+func newClock() *clock {
+	return &clock{t: time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)}
+}
+
+func (c *clock) now() time.Time          { return c.t }
+func (c *clock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestDeduplicator(t *testing.T, c *clock, ttl time.Duration, capacity int, path string) *Deduplicator {
+	t.Helper()
+
+	d, err := NewDeduplicator(ttl, capacity, store.NewSeenEvents(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.now = c.now
+	return d
+}
+
+// handled marks id unless it was seen, like the Kafka client once the
+// notification is delivered.
+func handled(t *testing.T, d *Deduplicator, id string) bool {
+	t.Helper()
+
+	if d.Seen(id) {
+		return true
+	}
+	if err := d.Mark(id); err != nil {
+		t.Fatal(err)
+	}
+	return false
+}
+
+func TestDeduplicatorTTL(t *testing.T) {
+	c := newClock()
+	d := newTestDeduplicator(t, c, time.Minute, 10, "")
+
+	if handled(t, d, "a") {
+		t.Error("a seen before being recorded")
+	}
+	c.advance(30 * time.Second)
+	if !handled(t, d, "a") {
+		t.Error("a not seen within the ttl")
+	}
+	if handled(t, d, "b") {
+		t.Error("b seen before being recorded")
+	}
+
+	// The ttl runs from the first time, a redelivery doesn't extend it
+	c.advance(30 * time.Second)
+	if handled(t, d, "a") {
+		t.Error("a still seen past its ttl")
+	}
+	if !handled(t, d, "b") {
+		t.Error("b not seen within the ttl")
+	}
+
+	// Expired ids are forgotten
+	c.advance(2 * time.Minute)
+	handled(t, d, "c")
+	if len(d.seen) != 1 || len(d.order) != 1 {
+		t.Errorf("%d ids remembered, want only c", len(d.seen))
+	}
+}
+
+func TestDeduplicatorCapacity(t *testing.T) {
+	c := newClock()
+	d := newTestDeduplicator(t, c, time.Hour, 3, "")
+
+	for i := range 5 {
+		handled(t, d, fmt.Sprint(i))
+		c.advance(time.Second)
+	}
+
+	if len(d.seen) > 3 || len(d.order) > 3 {
+		t.Errorf("%d ids remembered, want at most 3", len(d.seen))
+	}
+	// The oldest are forgotten first
+	for _, tc := range []struct {
+		id   string
+		want bool
+	}{{"4", true}, {"3", true}, {"2", true}, {"1", false}} {
+		if got := handled(t, d, tc.id); got != tc.want {
+			t.Errorf("Seen(%s) = %t, want %t", tc.id, got, tc.want)
+		}
+	}
+}
+
+func TestDeduplicatorMarkedOnly(t *testing.T) {
+	d := newTestDeduplicator(t, newClock(), time.Hour, 10, "")
+
+	// A notification whose dispatch failed is retried
+	for range 2 {
+		if d.Seen("a") {
+			t.Fatal("a seen before being marked")
+		}
+	}
+	if err := d.Mark("a"); err != nil {
+		t.Fatal(err)
+	}
+	if !d.Seen("a") {
+		t.Error("a not seen once marked")
+	}
+}
+
+func TestDeduplicatorPersists(t *testing.T) {
+	c := newClock()
+	path := filepath.Join(t.TempDir(), "seen.json")
+	d := newTestDeduplicator(t, c, time.Hour, 10, path)
+
+	handled(t, d, "a")
+	c.advance(30 * time.Minute)
+	handled(t, d, "b")
+
+	// Restarted past the ttl of a
+	c.advance(45 * time.Minute)
+	reloaded := newTestDeduplicator(t, c, time.Hour, 10, path)
+	if reloaded.Seen("a") || !reloaded.Seen("b") {
+		t.Errorf("seen after reload = %v, want only b", reloaded.order)
+	}
+}
+
+func TestCollapserWindows(t *testing.T) {
+	c := newClock()
+	collapser := NewCollapser(map[string]time.Duration{types.USER_LOGGED_IN: 10 * time.Minute})
+	collapser.now = c.now
+
+	events := 0
+	login := func(user, key string) types.Notification {
+		events++
+		return types.Notification{EventID: fmt.Sprint(events), Type: types.USER_LOGGED_IN, UserID: user, CollapseKey: key}
+	}
+	check := func(n types.Notification, wantCollapsed bool, wantSuppressed int) {
+		t.Helper()
+		if collapsed, suppressed := collapser.Collapse(n.EventID, n); collapsed != wantCollapsed || suppressed != wantSuppressed {
+			t.Errorf("Collapse(%s %s) = %t, %d, want %t, %d", n.UserID, n.CollapseKey, collapsed, suppressed, wantCollapsed, wantSuppressed)
+		}
+	}
+
+	first := login("alice", "")
+	check(first, false, 0)
+	c.advance(time.Minute)
+	// Retried after a failed dispatch
+	check(first, false, 0)
+	check(login("alice", ""), true, 1)
+	check(login("alice", ""), true, 2)
+	// Keyed by user by default
+	check(login("bob", ""), false, 0)
+	// Or by the producer's key
+	check(login("alice", "device-1"), false, 0)
+	check(login("bob", "device-1"), true, 1)
+
+	// Types without window are never collapsed
+	for range 2 {
+		check(types.Notification{Type: types.ORDER_CREATED, UserID: "alice"}, false, 0)
+	}
+
+	// The window opened by the first notification, later ones don't extend it
+	c.advance(9 * time.Minute)
+	check(login("alice", ""), false, 0)
+	check(login("alice", ""), true, 1)
+
+	// Closed bursts are swept
+	c.advance(time.Hour)
+	check(login("carol", ""), false, 0)
+	if len(collapser.bursts) != 1 {
+		t.Errorf("%d bursts kept, want carol's", len(collapser.bursts))
+	}
+}
+
+func TestParseCollapseWindows(t *testing.T) {
+	windows, err := ParseCollapseWindows(" user.logged_in=10m, stock.failed=1m,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]time.Duration{types.USER_LOGGED_IN: 10 * time.Minute, types.STOCK_FAILED: time.Minute}
+	if fmt.Sprint(windows) != fmt.Sprint(want) {
+		t.Errorf("windows = %v, want %v", windows, want)
+	}
+
+	for _, spec := range []string{"user.logged_in", "user.logged_in=ten"} {
+		if _, err := ParseCollapseWindows(spec); err == nil {
+			t.Errorf("ParseCollapseWindows(%q) succeeded", spec)
+		}
+	}
+}
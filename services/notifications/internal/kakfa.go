@@ -2,88 +2,120 @@ package internal
 
 import (
 	"context"
-	"encoding/json"
+	"eda-notifications/internal/types"
+	"eda-shared/kafka"
 	"fmt"
 	"log"
-
-	"github.com/segmentio/kafka-go"
+	"strings"
 )
 
 const (
 	LOG_TOPIC          = "logs.central"
 	NOTIFICATION_TOPIC = "notifications.central"
-	BROKER_ADDRESS     = "kafka:29092"
 	GROUP_ID           = "notifications-group"
 )
 
-type Log struct {
-	Message     string `json:"message"`
-	ServiceName string `json:"service_name"`
-}
-
-type Notification struct {
-	Action string `json:"action"`
-}
-
 type KafkaClient struct {
-	reader *kafka.Reader
-	writer *kafka.Writer
+	consumer *kafka.Consumer
+	logs     *kafka.Producer[types.Log]
+	router   *Router
+	renderer *Renderer
+	webhooks *Webhooks
+
+	dedup    *Deduplicator
+	collapse *Collapser
 }
 
-func NewKafkaClient() *KafkaClient {
-	reader := kafka.NewReader(kafka.ReaderConfig{
-		Brokers: []string{BROKER_ADDRESS},
-		Topic:   NOTIFICATION_TOPIC,
-		GroupID: GROUP_ID,
-	})
+func NewKafkaClient(config kafka.Config, router *Router, renderer *Renderer, webhooks *Webhooks, dedup *Deduplicator, collapse *Collapser) (*KafkaClient, error) {
+	consumer, err := kafka.NewConsumer(config, kafka.DefaultConsumerConfig(GROUP_ID, NOTIFICATION_TOPIC))
+	if err != nil {
+		return nil, err
+	}
+	kafka.Consumes[types.Notification](config.Registry, GROUP_ID, NOTIFICATION_TOPIC)
 
-	writer := &kafka.Writer{
-		Addr:  kafka.TCP(BROKER_ADDRESS),
-		Topic: LOG_TOPIC,
+	logs, err := kafka.NewProducer[types.Log](config, kafka.DefaultProducerConfig(LOG_TOPIC))
+	if err != nil {
+		return nil, err
 	}
 
 	return &KafkaClient{
-		reader,
-		writer,
-	}
+		consumer,
+		logs,
+		router,
+		renderer,
+		webhooks,
+		dedup,
+		collapse,
+	}, nil
 }
 
-func (k KafkaClient) Read() error {
+func (k KafkaClient) Read(ctx context.Context) error {
+	return kafka.Run(ctx, k.consumer, k.handle)
+}
+
+func (k KafkaClient) handle(ctx context.Context, m kafka.Message, message types.Notification) error {
+
+	// Traces are written in the default locale
+	text := message.Type
+	if msg, err := k.renderer.Render(message, ""); err != nil {
+		log.Printf("Error rendering notification: %v", err)
+	} else {
+		text = msg.Text
+	}
 
-	for {
-		m, err := k.reader.ReadMessage(context.Background())
-		if err != nil {
-			return err
-		}
+	output := fmt.Sprintf("[Notification] Received Notification: %s", text)
+	log.Println(output)
 
-		var message Notification
-		if err := json.Unmarshal(m.Value, &message); err != nil {
-			log.Printf("Error unmarshaling JSON: %v\n", err)
-			continue
-		}
+	// Producers predating event ids are deduplicated on the message
+	// position, which is the same when it's consumed again
+	eventID := message.EventID
+	if eventID == "" {
+		eventID = fmt.Sprintf("%s/%d/%d", m.Topic, m.Partition, m.Offset)
+	}
 
-		output := fmt.Sprintf("[Notification] Received Notification: %s", message.Action)
-		log.Println(output)
+	if k.dedup.Seen(eventID) {
+		log.Printf("Duplicate notification %s suppressed", eventID)
+		return nil
+	}
+
+	if collapsed, n := k.collapse.Collapse(eventID, message); collapsed {
+		log.Printf("Notification %s collapsed (%d suppressed in burst)", eventID, n)
+		k.mark(eventID)
+		return nil
+	}
 
-		notification, err := json.Marshal(Log{Message: output, ServiceName: "notifications"})
-		if err != nil {
-			return err
-		}
+	// Not marked seen, the consumer retries the notification
+	delivered, err := k.router.Dispatch(ctx, message)
+	if err != nil {
+		return fmt.Errorf("dispatching notification %s: %w", eventID, err)
+	}
+	k.mark(eventID)
 
-		msg := kafka.Message{
-			Key:   []byte("notification"),
-			Value: notification,
-		}
+	if err := k.webhooks.Publish(ctx, message); err != nil {
+		log.Printf("Error publishing notification to webhooks: %v", err)
+	}
 
-		err = k.writer.WriteMessages(context.TODO(), msg)
+	if len(delivered) > 0 {
+		output += fmt.Sprintf(" (sent by %s)", strings.Join(delivered, ", "))
+	}
 
-		if err != nil {
-			return err
-		}
+	// The notification is already delivered, a lost trace isn't worth a retry
+	// that would deliver it again
+	if err := k.logs.Send(ctx, "notification", types.Log{Message: output, ServiceName: "notifications", UserID: message.UserID}); err != nil {
+		log.Printf("Error writing log: %v", err)
 	}
+	return nil
+}
 
+// mark records a handled event. The notification is already delivered, a
+// failed save only lets a redelivery after a restart through.
+func (k KafkaClient) mark(eventID string) {
+	if err := k.dedup.Mark(eventID); err != nil {
+		log.Printf("Error saving seen event %s: %v", eventID, err)
+	}
 }
 
 func (k KafkaClient) Close() {
-	k.Close()
+	_ = k.consumer.Close()
+	_ = k.logs.Close()
 }
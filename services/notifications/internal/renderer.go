@@ -0,0 +1,167 @@
+package internal
+
+import (
+	"bytes"
+	"eda-notifications/internal/types"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+)
+
+const (
+	// Locale used when neither the user's locale nor its language has a template
+	DEFAULT_LOCALE = "en"
+)
+
+// Templates are stored per locale and notification type:
+// templates/<locale>/<type>.tmpl, each defining "subject", "text" and "html".
+//
+//go:embed templates
+var templatesFS embed.FS
+
+type template struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+type Renderer struct {
+	defaultLocale string
+	// locale -> type -> template
+	templates map[string]map[string]template
+}
+
+func NewRenderer(defaultLocale string) (*Renderer, error) {
+	r := &Renderer{
+		defaultLocale: defaultLocale,
+		templates:     make(map[string]map[string]template),
+	}
+
+	err := fs.WalkDir(templatesFS, "templates", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || path.Ext(p) != ".tmpl" {
+			return err
+		}
+
+		locale := path.Base(path.Dir(p))
+		notificationType := strings.TrimSuffix(path.Base(p), ".tmpl")
+
+		src, err := templatesFS.ReadFile(p)
+		if err != nil {
+			return err
+		}
+
+		text, err := texttemplate.New(p).Option("missingkey=error").Parse(string(src))
+		if err != nil {
+			return err
+		}
+		html, err := htmltemplate.New(p).Option("missingkey=error").Parse(string(src))
+		if err != nil {
+			return err
+		}
+
+		for _, name := range []string{"subject", "text", "html"} {
+			if text.Lookup(name) == nil {
+				return fmt.Errorf("%s: missing %q template", p, name)
+			}
+		}
+
+		if r.templates[locale] == nil {
+			r.templates[locale] = make(map[string]template)
+		}
+		r.templates[locale][notificationType] = template{text: text, html: html}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// candidates lists the locales to try in order: "fr-CA", "fr", default, en.
+func (r *Renderer) candidates(locale string) []string {
+	var locales []string
+	seen := make(map[string]bool)
+
+	add := func(l string) {
+		l = strings.ToLower(strings.ReplaceAll(l, "_", "-"))
+		if l != "" && !seen[l] {
+			seen[l] = true
+			locales = append(locales, l)
+		}
+	}
+
+	locale = strings.ReplaceAll(locale, "_", "-")
+	add(locale)
+	if lang, _, ok := strings.Cut(locale, "-"); ok {
+		add(lang)
+	}
+	add(r.defaultLocale)
+	add(DEFAULT_LOCALE)
+
+	return locales
+}
+
+// Render the notification in the first locale having a template for its
+// type. Notifications without template keep their raw action text.
+func (r *Renderer) Render(n types.Notification, locale string) (types.Message, error) {
+	for _, l := range r.candidates(locale) {
+		t, ok := r.templates[l][n.Type]
+		if !ok {
+			continue
+		}
+
+		msg := types.Message{Locale: l}
+		var err error
+
+		if msg.Subject, err = executeText(t.text, "subject", n.Params); err != nil {
+			return types.Message{}, err
+		}
+		if msg.Text, err = executeText(t.text, "text", n.Params); err != nil {
+			return types.Message{}, err
+		}
+
+		var b bytes.Buffer
+		if err := t.html.ExecuteTemplate(&b, "html", n.Params); err != nil {
+			return types.Message{}, err
+		}
+		msg.HTML = strings.TrimSpace(b.String())
+
+		return msg, nil
+	}
+
+	if n.Action == "" {
+		return types.Message{}, fmt.Errorf("no template for notification type %q", n.Type)
+	}
+
+	// Untyped notification from a producer not migrated to templates
+	return types.Message{Subject: "[Skate Shop] Notification", Text: n.Action}, nil
+}
+
+func executeText(t *texttemplate.Template, name string, params map[string]any) (string, error) {
+	var b bytes.Buffer
+	if err := t.ExecuteTemplate(&b, name, params); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// Templates returns the locales available for each notification type.
+func (r *Renderer) Templates() map[string][]string {
+	out := make(map[string][]string)
+	for locale, byType := range r.templates {
+		for notificationType := range byType {
+			out[notificationType] = append(out[notificationType], locale)
+		}
+	}
+	for _, locales := range out {
+		sort.Strings(locales)
+	}
+	return out
+}
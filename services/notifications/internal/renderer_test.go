@@ -0,0 +1,104 @@
+package internal
+
+import (
+	"eda-notifications/internal/types"
+	"strings"
+	"testing"
+)
+
+func TestRenderLocaleFallback(t *testing.T) {
+	n := types.Notification{Type: types.ORDER_CREATED, Params: map[string]any{"orderId": "123"}}
+
+	for _, tc := range []struct {
+		defaultLocale, locale, want string
+	}{
+		{DEFAULT_LOCALE, "fr", "fr"},
+		// Region then language
+		{DEFAULT_LOCALE, "fr-CA", "fr"},
+		{DEFAULT_LOCALE, "FR_ca", "fr"},
+		// Default locale then en
+		{DEFAULT_LOCALE, "de", "en"},
+		{DEFAULT_LOCALE, "", "en"},
+		{"fr", "de-AT", "fr"},
+		{"it", "de", "en"},
+	} {
+		r, err := NewRenderer(tc.defaultLocale)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		msg, err := r.Render(n, tc.locale)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msg.Locale != tc.want {
+			t.Errorf("%q with default %q rendered in %q, want %q", tc.locale, tc.defaultLocale, msg.Locale, tc.want)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	r, err := NewRenderer(DEFAULT_LOCALE)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := types.Notification{Type: types.ORDER_CREATED, Params: map[string]any{"orderId": "<b>123</b>"}}
+	msg, err := r.Render(n, "fr")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := types.Message{
+		Locale:  "fr",
+		Subject: "Commande <b>123</b> confirmée",
+		Text:    "Nouvelle commande créée : <b>123</b>",
+		// Params are escaped in html only
+		HTML: "<p>Votre commande <strong>&lt;b&gt;123&lt;/b&gt;</strong> est confirmée.</p>",
+	}
+	if msg != want {
+		t.Errorf("message = %+v, want %+v", msg, want)
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	r, err := NewRenderer(DEFAULT_LOCALE)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Missing param
+	_, err = r.Render(types.Notification{Type: types.ORDER_CREATED, Params: map[string]any{}}, "en")
+	if err == nil || !strings.Contains(err.Error(), "orderId") {
+		t.Errorf("err = %v, want the missing orderId", err)
+	}
+
+	// No template and no preformatted text
+	if _, err := r.Render(types.Notification{Type: "order.shipped"}, "en"); err == nil {
+		t.Error("rendered a notification without template nor action")
+	}
+
+	// Preformatted text of producers predating templates
+	msg, err := r.Render(types.Notification{Type: "order.shipped", Action: "Order 123 shipped"}, "fr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Text != "Order 123 shipped" || msg.Locale != "" {
+		t.Errorf("message = %+v, want the raw action", msg)
+	}
+}
+
+func TestTemplatesCoverEveryType(t *testing.T) {
+	r, err := NewRenderer(DEFAULT_LOCALE)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	templates := r.Templates()
+	for notificationType := range ROUTES {
+		locales := templates[notificationType]
+		if strings.Join(locales, ",") != "en,fr" {
+			t.Errorf("%s has templates in %v, want en and fr", notificationType, locales)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package internal
+
+import (
+	"context"
+	"eda-notifications/internal/channels"
+	"eda-notifications/internal/types"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	SEND_TIMEOUT = 10 * time.Second
+)
+
+// Channels used for each notification type unless the user turned them off
+var ROUTES = map[string][]string{
+	types.USER_REGISTERED:   {channels.EMAIL, channels.IN_APP},
+	types.USER_LOGGED_IN:    {channels.IN_APP},
+	types.PAYMENT_PROCESSED: {channels.EMAIL, channels.IN_APP, channels.WEBHOOK},
+	types.PAYMENT_REFUNDED:  {channels.EMAIL, channels.IN_APP, channels.WEBHOOK},
+	types.STOCK_RESERVED:    {channels.IN_APP},
+	types.STOCK_FAILED:      {channels.EMAIL, channels.IN_APP},
+	types.ORDER_CREATED:     {channels.EMAIL, channels.IN_APP, channels.WEBHOOK},
+	types.ORDER_CANCELLED:   {channels.EMAIL, channels.IN_APP, channels.WEBHOOK},
+}
+
+// Channels used for types missing from ROUTES
+var DEFAULT_ROUTE = []string{channels.IN_APP}
+
+// Router picks the channels of a notification from its type and the
+// preferences of its user, then sends it on each of them.
+type Router struct {
+	channels    map[string]types.IChannel
+	preferences types.IPreferenceStore
+	renderer    *Renderer
+	now         func() time.Time
+}
+
+func NewRouter(preferences types.IPreferenceStore, renderer *Renderer, chans ...types.IChannel) *Router {
+	r := &Router{
+		channels:    make(map[string]types.IChannel),
+		preferences: preferences,
+		renderer:    renderer,
+		now:         time.Now,
+	}
+	for _, c := range chans {
+		r.channels[c.Name()] = c
+	}
+	return r
+}
+
+func (r *Router) route(n types.Notification, p types.Preferences) []types.IChannel {
+	names, ok := ROUTES[n.Type]
+	if !ok {
+		names = DEFAULT_ROUTE
+	}
+
+	wanted := make(map[string]bool)
+	for _, name := range names {
+		wanted[name] = true
+	}
+	for name, enabled := range p.Channels {
+		wanted[name] = enabled
+	}
+
+	// Only the inbox during quiet hours, it doesn't wake anyone up
+	if p.QuietHours != nil && p.QuietHours.Active(r.now()) {
+		wanted = map[string]bool{channels.IN_APP: wanted[channels.IN_APP]}
+	}
+
+	var chans []types.IChannel
+	for name, c := range r.channels {
+		if wanted[name] && c.Reachable(p) {
+			chans = append(chans, c)
+		}
+	}
+
+	return chans
+}
+
+// Dispatch sends the notification on every channel it's routed to and
+// returns the names of the channels that delivered it.
+func (r *Router) Dispatch(ctx context.Context, n types.Notification) ([]string, error) {
+	// Notifications about no one in particular are only logged
+	if n.UserID == "" {
+		return nil, nil
+	}
+
+	p, err := r.preferences.Get(n.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.Wants(n.Type) {
+		return nil, nil
+	}
+
+	chans := r.route(n, p)
+	if len(chans) == 0 {
+		return nil, nil
+	}
+
+	msg, err := r.renderer.Render(n, p.Locale)
+	if err != nil {
+		return nil, err
+	}
+
+	var delivered []string
+	var errs []error
+
+	for _, c := range chans {
+		sendCtx, cancel := context.WithTimeout(ctx, SEND_TIMEOUT)
+		err := c.Send(sendCtx, p, n, msg)
+		cancel()
+
+		if err != nil {
+			log.Printf("Error sending %s notification to %s: %v", c.Name(), n.UserID, err)
+			errs = append(errs, fmt.Errorf("%s: %w", c.Name(), err))
+			continue
+		}
+		delivered = append(delivered, c.Name())
+	}
+
+	return delivered, errors.Join(errs...)
+}
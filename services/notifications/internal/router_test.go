@@ -0,0 +1,223 @@
+package internal
+
+import (
+	"context"
+	"eda-notifications/internal/channels"
+	"eda-notifications/internal/store"
+	"eda-notifications/internal/types"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeChannel records what it sends, reachable like the channel it stands for.
+type fakeChannel struct {
+	name      string
+	reachable func(p types.Preferences) bool
+	err       error
+
+	mu   sync.Mutex
+	sent []types.Message
+}
+
+func (c *fakeChannel) Name() string                       { return c.name }
+func (c *fakeChannel) Reachable(p types.Preferences) bool { return c.reachable(p) }
+
+func (c *fakeChannel) Send(ctx context.Context, p types.Preferences, n types.Notification, msg types.Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sent = append(c.sent, msg)
+	return c.err
+}
+
+func fakeChannels() []*fakeChannel {
+	return []*fakeChannel{
+		{name: channels.EMAIL, reachable: func(p types.Preferences) bool { return p.Email != "" }},
+		{name: channels.IN_APP, reachable: func(p types.Preferences) bool { return p.UserID != "" }},
+		{name: channels.WEBHOOK, reachable: func(p types.Preferences) bool { return p.WebhookURL != "" }},
+	}
+}
+
+func newTestRouter(t *testing.T, p types.Preferences, chans ...*fakeChannel) *Router {
+	t.Helper()
+
+	preferences, err := store.NewPreferences("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.UserID != "" {
+		if err := preferences.Save(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	renderer, err := NewRenderer(DEFAULT_LOCALE)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ichans []types.IChannel
+	for _, c := range chans {
+		ichans = append(ichans, c)
+	}
+	return NewRouter(preferences, renderer, ichans...)
+}
+
+func TestRouterFanOut(t *testing.T) {
+	reachable := types.Preferences{UserID: "alice", Email: "alice@shop.local", WebhookURL: "http://partner.local"}
+	params := map[string]any{"orderId": "123", "total": 42.5, "username": "alice"}
+
+	for _, tc := range []struct {
+		name string
+		p    types.Preferences
+		n    types.Notification
+		want []string
+	}{
+		{"routes", reachable, types.Notification{Type: types.PAYMENT_PROCESSED},
+			[]string{channels.EMAIL, channels.IN_APP, channels.WEBHOOK}},
+		{"inbox only type", reachable, types.Notification{Type: types.USER_LOGGED_IN},
+			[]string{channels.IN_APP}},
+		{"default route", reachable, types.Notification{Type: "order.shipped", Action: "Order 123 shipped"},
+			[]string{channels.IN_APP}},
+		{"unreachable", types.Preferences{UserID: "alice"}, types.Notification{Type: types.PAYMENT_PROCESSED},
+			[]string{channels.IN_APP}},
+		{"channel turned off", types.Preferences{UserID: "alice", Email: "alice@shop.local",
+			Channels: map[string]bool{channels.EMAIL: false}}, types.Notification{Type: types.ORDER_CREATED},
+			[]string{channels.IN_APP}},
+		{"channel turned on", types.Preferences{UserID: "alice", Email: "alice@shop.local",
+			Channels: map[string]bool{channels.EMAIL: true}}, types.Notification{Type: types.STOCK_RESERVED},
+			[]string{channels.EMAIL, channels.IN_APP}},
+		{"type turned off", types.Preferences{UserID: "alice", Email: "alice@shop.local",
+			EventTypes: map[string]bool{types.ORDER_CREATED: false}}, types.Notification{Type: types.ORDER_CREATED},
+			nil},
+		{"no user", reachable, types.Notification{Type: types.ORDER_CREATED}, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			chans := fakeChannels()
+			router := newTestRouter(t, tc.p, chans...)
+
+			n := tc.n
+			if tc.name != "no user" {
+				n.UserID = "alice"
+			}
+			n.Params = params
+
+			delivered, err := router.Dispatch(context.Background(), n)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			slices.Sort(delivered)
+			if fmt.Sprint(delivered) != fmt.Sprint(tc.want) {
+				t.Errorf("delivered on %v, want %v", delivered, tc.want)
+			}
+			for _, c := range chans {
+				if sent := len(c.sent); sent != 0 && !slices.Contains(tc.want, c.name) || sent > 1 {
+					t.Errorf("%s sent %d messages", c.name, sent)
+				}
+			}
+		})
+	}
+}
+
+func TestRouterQuietHours(t *testing.T) {
+	chans := fakeChannels()
+	p := types.Preferences{
+		UserID:     "alice",
+		Email:      "alice@shop.local",
+		QuietHours: &types.QuietHours{Start: "22:00", End: "07:00", Timezone: "Europe/Paris"},
+	}
+	router := newTestRouter(t, p, chans...)
+	n := types.Notification{Type: types.ORDER_CREATED, UserID: "alice", Params: map[string]any{"orderId": "123"}}
+
+	for _, tc := range []struct {
+		at   time.Time
+		want []string
+	}{
+		// 23:30 in Paris
+		{time.Date(2026, 1, 10, 22, 30, 0, 0, time.UTC), []string{channels.IN_APP}},
+		{time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC), []string{channels.EMAIL, channels.IN_APP}},
+	} {
+		router.now = func() time.Time { return tc.at }
+
+		delivered, err := router.Dispatch(context.Background(), n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		slices.Sort(delivered)
+		if fmt.Sprint(delivered) != fmt.Sprint(tc.want) {
+			t.Errorf("at %s delivered on %v, want %v", tc.at, delivered, tc.want)
+		}
+	}
+}
+
+func TestRouterChannelError(t *testing.T) {
+	chans := fakeChannels()
+	chans[0].err = errors.New("smtp down")
+	router := newTestRouter(t, types.Preferences{UserID: "alice", Email: "alice@shop.local"}, chans...)
+
+	n := types.Notification{Type: types.ORDER_CREATED, UserID: "alice", Params: map[string]any{"orderId": "123"}}
+	delivered, err := router.Dispatch(context.Background(), n)
+	if !errors.Is(err, chans[0].err) {
+		t.Errorf("err = %v, want the email error", err)
+	}
+	if fmt.Sprint(delivered) != fmt.Sprint([]string{channels.IN_APP}) {
+		t.Errorf("delivered on %v, want the inbox despite the email error", delivered)
+	}
+}
+
+func TestRouterRealChannels(t *testing.T) {
+	received := make(chan map[string]any, 1)
+	partner := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		received <- body
+	}))
+	defer partner.Close()
+
+	inbox, err := store.NewInbox("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	preferences, err := store.NewPreferences("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := preferences.Save(types.Preferences{UserID: "alice", WebhookURL: partner.URL, Locale: "fr"}); err != nil {
+		t.Fatal(err)
+	}
+	renderer, err := NewRenderer(DEFAULT_LOCALE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := NewRouter(preferences, renderer, channels.NewInApp(inbox), channels.NewWebhook(time.Second, true))
+
+	n := types.Notification{Type: types.ORDER_CREATED, UserID: "alice", Params: map[string]any{"orderId": "123"}}
+	if _, err := router.Dispatch(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := inbox.List("alice", false, "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Subject != "Commande 123 confirmée" || items[0].Type != types.ORDER_CREATED {
+		t.Errorf("inbox = %+v", items)
+	}
+
+	body := <-received
+	if body["user_id"] != "alice" || body["type"] != types.ORDER_CREATED {
+		t.Errorf("webhook body = %v", body)
+	}
+	if msg, _ := body["message"].(map[string]any); msg["locale"] != "fr" {
+		t.Errorf("webhook message = %v, want the fr rendering", body["message"])
+	}
+}
@@ -0,0 +1,163 @@
+package store
+
+import (
+	"eda-notifications/internal/types"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// Older items are dropped past this number per user
+	INBOX_SIZE = 100
+)
+
+// Inbox keeps in-app notifications in memory, persisted to a JSON file when
+// path isn't empty.
+type Inbox struct {
+	path string
+
+	mu     sync.Mutex
+	items  map[string][]types.InboxItem // oldest first
+	lastID int64
+}
+
+func NewInbox(path string) (*Inbox, error) {
+	s := &Inbox{path: path, items: make(map[string][]types.InboxItem)}
+
+	if err := load(path, &s.items); err != nil {
+		return nil, err
+	}
+
+	for userID, items := range s.items {
+		for i := range items {
+			items[i].UserID = userID
+			if id, err := strconv.ParseInt(items[i].ID, 10, 64); err == nil {
+				s.lastID = max(s.lastID, id)
+			}
+		}
+	}
+
+	return s, nil
+}
+
+// nextID returns a fixed width id increasing with time, so ids sort
+// lexicographically in creation order.
+func (s *Inbox) nextID(t time.Time) string {
+	id := max(t.UnixNano(), s.lastID+1)
+	s.lastID = id
+	return fmt.Sprintf("%019d", id)
+}
+
+func (s *Inbox) Add(item types.InboxItem) (types.InboxItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if item.CreatedAt.IsZero() {
+		item.CreatedAt = time.Now().UTC()
+	}
+	item.ID = s.nextID(item.CreatedAt)
+
+	previous := s.items[item.UserID]
+
+	items := append(previous[:len(previous):len(previous)], item)
+	if len(items) > INBOX_SIZE {
+		items = items[len(items)-INBOX_SIZE:]
+	}
+	s.items[item.UserID] = items
+
+	if err := save(s.path, s.items); err != nil {
+		s.items[item.UserID] = previous
+		return types.InboxItem{}, err
+	}
+
+	return item, nil
+}
+
+func (s *Inbox) List(userID string, unreadOnly bool, before string, limit int) ([]types.InboxItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items := s.items[userID]
+
+	// Index of the first item not older than the cursor
+	end := len(items)
+	if before != "" {
+		end = sort.Search(len(items), func(i int) bool { return items[i].ID >= before })
+	}
+
+	var out []types.InboxItem
+	for i := end - 1; i >= 0 && len(out) < limit; i-- {
+		if unreadOnly && items[i].Read {
+			continue
+		}
+		out = append(out, items[i])
+	}
+
+	return out, nil
+}
+
+func (s *Inbox) MarkRead(userID, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items := s.items[userID]
+	i := sort.Search(len(items), func(i int) bool { return items[i].ID >= id })
+	if i == len(items) || items[i].ID != id {
+		return types.ErrNotFound
+	}
+	if items[i].Read {
+		return nil
+	}
+
+	items[i].Read = true
+	if err := save(s.path, s.items); err != nil {
+		items[i].Read = false
+		return err
+	}
+
+	return nil
+}
+
+func (s *Inbox) MarkAllRead(userID string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var marked []int
+	items := s.items[userID]
+	for i := range items {
+		if !items[i].Read {
+			items[i].Read = true
+			marked = append(marked, i)
+		}
+	}
+
+	if len(marked) == 0 {
+		return 0, nil
+	}
+
+	if err := save(s.path, s.items); err != nil {
+		for _, i := range marked {
+			items[i].Read = false
+		}
+		return 0, err
+	}
+
+	return len(marked), nil
+}
+
+func (s *Inbox) Unread(userID string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for _, item := range s.items[userID] {
+		if !item.Read {
+			n++
+		}
+	}
+
+	return n, nil
+}
@@ -0,0 +1,158 @@
+package store
+
+import (
+	"eda-notifications/internal/types"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func subjects(items []types.InboxItem) []string {
+	var s []string
+	for _, item := range items {
+		s = append(s, item.Subject)
+	}
+	return s
+}
+
+// fill adds n items to alice's inbox, all created at the same instant.
+func fill(t *testing.T, inbox *Inbox, n int) []types.InboxItem {
+	t.Helper()
+
+	at := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	var items []types.InboxItem
+	for i := range n {
+		item, err := inbox.Add(types.InboxItem{UserID: "alice", Subject: fmt.Sprint(i), CreatedAt: at})
+		if err != nil {
+			t.Fatal(err)
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+func TestInboxPagination(t *testing.T) {
+	inbox, err := NewInbox("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := fill(t, inbox, 5)
+
+	// Ids increase even within the same nanosecond
+	for i := 1; i < len(items); i++ {
+		if items[i].ID <= items[i-1].ID {
+			t.Fatalf("id %s after %s", items[i].ID, items[i-1].ID)
+		}
+	}
+
+	var pages [][]string
+	cursor := ""
+	for {
+		page, err := inbox.List("alice", false, cursor, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		pages = append(pages, subjects(page))
+		cursor = page[len(page)-1].ID
+	}
+	if fmt.Sprint(pages) != "[[4 3] [2 1] [0]]" {
+		t.Errorf("pages = %v, want newest first", pages)
+	}
+
+	if other, _ := inbox.List("bob", false, "", 10); len(other) != 0 {
+		t.Errorf("bob sees %v", subjects(other))
+	}
+}
+
+func TestInboxReadState(t *testing.T) {
+	inbox, err := NewInbox("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := fill(t, inbox, 4)
+
+	if err := inbox.MarkRead("alice", items[2].ID); err != nil {
+		t.Fatal(err)
+	}
+	// Already read
+	if err := inbox.MarkRead("alice", items[2].ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := inbox.MarkRead("bob", items[1].ID); !errors.Is(err, types.ErrNotFound) {
+		t.Errorf("bob marked alice's item read: %v", err)
+	}
+	if err := inbox.MarkRead("alice", "0"); !errors.Is(err, types.ErrNotFound) {
+		t.Errorf("unknown item: %v", err)
+	}
+
+	if n, _ := inbox.Unread("alice"); n != 3 {
+		t.Errorf("%d unread, want 3", n)
+	}
+	unread, err := inbox.List("alice", true, "", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(subjects(unread)) != "[3 1]" {
+		t.Errorf("unread = %v, want [3 1]", subjects(unread))
+	}
+
+	if n, err := inbox.MarkAllRead("alice"); err != nil || n != 3 {
+		t.Errorf("marked %d, %v, want 3", n, err)
+	}
+	if n, _ := inbox.MarkAllRead("alice"); n != 0 {
+		t.Errorf("marked %d again", n)
+	}
+	if n, _ := inbox.Unread("alice"); n != 0 {
+		t.Errorf("%d unread after marking all read", n)
+	}
+}
+
+func TestInboxSize(t *testing.T) {
+	inbox, err := NewInbox("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := fill(t, inbox, INBOX_SIZE+2)
+
+	all, err := inbox.List("alice", false, "", 2*INBOX_SIZE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != INBOX_SIZE || all[len(all)-1].ID != items[2].ID {
+		t.Errorf("%d items down to %s, want the %d newest", len(all), all[len(all)-1].Subject, INBOX_SIZE)
+	}
+}
+
+func TestInboxPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "inbox.json")
+	inbox, err := NewInbox(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := fill(t, inbox, 2)
+	if err := inbox.MarkRead("alice", items[0].ID); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := NewInbox(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := reloaded.Unread("alice"); n != 1 {
+		t.Errorf("%d unread after reload, want 1", n)
+	}
+
+	// New ids keep increasing after a reload
+	item, err := reloaded.Add(types.InboxItem{UserID: "alice", Subject: "2", CreatedAt: items[0].CreatedAt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.ID <= items[1].ID {
+		t.Errorf("id %s after %s", item.ID, items[1].ID)
+	}
+}
@@ -0,0 +1,60 @@
+package store
+
+import (
+	"eda-notifications/internal/types"
+	"sync"
+)
+
+// Preferences keeps user preferences in memory, persisted to a JSON file
+// keyed by user id when path isn't empty.
+type Preferences struct {
+	path string
+
+	mu    sync.RWMutex
+	users map[string]types.Preferences
+}
+
+func NewPreferences(path string) (*Preferences, error) {
+	s := &Preferences{path: path, users: make(map[string]types.Preferences)}
+
+	if err := load(path, &s.users); err != nil {
+		return nil, err
+	}
+
+	for id, p := range s.users {
+		p.UserID = id
+		s.users[id] = p
+	}
+
+	return s, nil
+}
+
+func (s *Preferences) Get(userID string) (types.Preferences, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	p, ok := s.users[userID]
+	if !ok {
+		return types.Preferences{UserID: userID}, nil
+	}
+	return p, nil
+}
+
+func (s *Preferences) Save(p types.Preferences) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, existed := s.users[p.UserID]
+	s.users[p.UserID] = p
+
+	if err := save(s.path, s.users); err != nil {
+		if existed {
+			s.users[p.UserID] = previous
+		} else {
+			delete(s.users, p.UserID)
+		}
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,25 @@
+package store
+
+import "eda-notifications/internal/types"
+
+// SeenEvents persists the deduplicated event ids to a JSON file when path
+// isn't empty, so redeliveries after a restart are still suppressed.
+type SeenEvents struct {
+	path string
+}
+
+func NewSeenEvents(path string) *SeenEvents {
+	return &SeenEvents{path: path}
+}
+
+func (s *SeenEvents) Load() ([]types.SeenEvent, error) {
+	var events []types.SeenEvent
+	if err := load(s.path, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (s *SeenEvents) Save(events []types.SeenEvent) error {
+	return save(s.path, events)
+}
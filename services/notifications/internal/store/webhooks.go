@@ -0,0 +1,150 @@
+package store
+
+import (
+	"eda-notifications/internal/types"
+	"sort"
+	"sync"
+)
+
+const (
+	// Older deliveries are dropped past this number per subscription
+	DELIVERY_LOG_SIZE = 500
+)
+
+// Subscriptions keeps webhook subscriptions in memory, persisted to a JSON
+// file when path isn't empty.
+type Subscriptions struct {
+	path string
+
+	mu   sync.Mutex
+	subs map[string]types.WebhookSubscription
+}
+
+func NewSubscriptions(path string) (*Subscriptions, error) {
+	s := &Subscriptions{path: path, subs: make(map[string]types.WebhookSubscription)}
+
+	if err := load(path, &s.subs); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *Subscriptions) Create(sub types.WebhookSubscription) (types.WebhookSubscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.subs[sub.ID] = sub
+	if err := save(s.path, s.subs); err != nil {
+		delete(s.subs, sub.ID)
+		return types.WebhookSubscription{}, err
+	}
+
+	return sub, nil
+}
+
+func (s *Subscriptions) Get(id string) (types.WebhookSubscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub, ok := s.subs[id]
+	if !ok {
+		return types.WebhookSubscription{}, types.ErrNotFound
+	}
+	return sub, nil
+}
+
+// List returns the subscriptions, oldest first.
+func (s *Subscriptions) List() ([]types.WebhookSubscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subs := make([]types.WebhookSubscription, 0, len(s.subs))
+	for _, sub := range s.subs {
+		subs = append(subs, sub)
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].CreatedAt.Before(subs[j].CreatedAt) })
+
+	return subs, nil
+}
+
+func (s *Subscriptions) Update(id string, fn func(sub *types.WebhookSubscription)) (types.WebhookSubscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, ok := s.subs[id]
+	if !ok {
+		return types.WebhookSubscription{}, types.ErrNotFound
+	}
+
+	sub := previous
+	sub.EventTypes = append([]string(nil), previous.EventTypes...)
+	fn(&sub)
+	sub.ID = id
+
+	s.subs[id] = sub
+	if err := save(s.path, s.subs); err != nil {
+		s.subs[id] = previous
+		return types.WebhookSubscription{}, err
+	}
+
+	return sub, nil
+}
+
+func (s *Subscriptions) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, ok := s.subs[id]
+	if !ok {
+		return types.ErrNotFound
+	}
+
+	delete(s.subs, id)
+	if err := save(s.path, s.subs); err != nil {
+		s.subs[id] = previous
+		return err
+	}
+
+	return nil
+}
+
+// Deliveries is the in-memory log of webhook delivery attempts.
+type Deliveries struct {
+	mu         sync.Mutex
+	deliveries map[string][]types.Delivery // oldest first
+}
+
+func NewDeliveries() *Deliveries {
+	return &Deliveries{deliveries: make(map[string][]types.Delivery)}
+}
+
+func (s *Deliveries) Record(d types.Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deliveries := append(s.deliveries[d.SubscriptionID], d)
+	if len(deliveries) > DELIVERY_LOG_SIZE {
+		deliveries = deliveries[len(deliveries)-DELIVERY_LOG_SIZE:]
+	}
+	s.deliveries[d.SubscriptionID] = deliveries
+
+	return nil
+}
+
+func (s *Deliveries) List(subscriptionID string, failedOnly bool, limit int) ([]types.Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deliveries := s.deliveries[subscriptionID]
+
+	var out []types.Delivery
+	for i := len(deliveries) - 1; i >= 0 && len(out) < limit; i-- {
+		if failedOnly && deliveries[i].Success {
+			continue
+		}
+		out = append(out, deliveries[i])
+	}
+
+	return out, nil
+}
@@ -0,0 +1,230 @@
+package types
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrNotFound = errors.New("not found")
+
+// Notification types, set by the producing services
+const (
+	USER_REGISTERED   = "user.registered"
+	USER_LOGGED_IN    = "user.logged_in"
+	PAYMENT_PROCESSED = "payment.processed"
+	PAYMENT_REFUNDED  = "payment.refunded"
+	STOCK_RESERVED    = "stock.reserved"
+	STOCK_FAILED      = "stock.failed"
+	ORDER_CREATED     = "order.created"
+	ORDER_CANCELLED   = "order.cancelled"
+)
+
+type Notification struct {
+	// Unique per event, a redelivered event keeps its id
+	EventID string         `json:"event_id,omitempty"`
+	Type    string         `json:"type,omitempty"`
+	UserID  string         `json:"user_id,omitempty"`
+	Params  map[string]any `json:"params,omitempty"`
+	// Notifications of the same type and collapse key within the type's
+	// window are delivered once. Defaults to the user id.
+	CollapseKey string `json:"collapse_key,omitempty"`
+	// Preformatted text sent by producers predating templates
+	Action string `json:"action,omitempty"`
+}
+
+// Message is a notification rendered for a user
+type Message struct {
+	Locale  string `json:"locale"`
+	Subject string `json:"subject"`
+	Text    string `json:"text"`
+	HTML    string `json:"html,omitempty"`
+}
+
+type Log struct {
+	Message     string `json:"message" example:"notification payment.processed sent to 456"`
+	ServiceName string `json:"service_name" example:"notifications-service"`
+	UserID      string `json:"user_id,omitempty" example:"456"`
+}
+
+// Preferences of a user regarding notifications
+type Preferences struct {
+	UserID     string `json:"user_id"`
+	Email      string `json:"email,omitempty"`
+	WebhookURL string `json:"webhook_url,omitempty"`
+	// fr, en, fr-CA... falls back to the language then the default locale
+	Locale string `json:"locale,omitempty"`
+	// Channels explicitly turned on or off, the others follow the routes
+	Channels map[string]bool `json:"channels,omitempty"`
+	// Notification types turned off, the user isn't notified of them at all
+	EventTypes map[string]bool `json:"event_types,omitempty"`
+	QuietHours *QuietHours     `json:"quiet_hours,omitempty"`
+}
+
+// Wants tells whether the user wants notifications of this type.
+func (p Preferences) Wants(notificationType string) bool {
+	enabled, ok := p.EventTypes[notificationType]
+	return !ok || enabled
+}
+
+// QuietHours is a daily time range, possibly over midnight ("22:00" to "07:00"),
+// during which only the in-app inbox is used.
+type QuietHours struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+	// IANA time zone of Start and End, UTC when empty
+	Timezone string `json:"timezone,omitempty"`
+}
+
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func (q QuietHours) Validate() error {
+	if _, err := parseClock(q.Start); err != nil {
+		return err
+	}
+	if _, err := parseClock(q.End); err != nil {
+		return err
+	}
+	if _, err := time.LoadLocation(q.Timezone); err != nil {
+		return fmt.Errorf("invalid timezone %q", q.Timezone)
+	}
+	return nil
+}
+
+// Active tells whether t falls within the quiet hours.
+func (q QuietHours) Active(t time.Time) bool {
+	start, err := parseClock(q.Start)
+	if err != nil {
+		return false
+	}
+	end, err := parseClock(q.End)
+	if err != nil {
+		return false
+	}
+	loc, err := time.LoadLocation(q.Timezone)
+	if err != nil {
+		return false
+	}
+
+	t = t.In(loc)
+	now := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+
+	if start <= end {
+		return now >= start && now < end
+	}
+	return now >= start || now < end
+}
+
+type InboxItem struct {
+	// Increasing with time, used as pagination cursor
+	ID        string    `json:"id"`
+	UserID    string    `json:"-"`
+	Type      string    `json:"type,omitempty"`
+	Subject   string    `json:"subject"`
+	Message   string    `json:"message"`
+	CreatedAt time.Time `json:"created_at"`
+	Read      bool      `json:"read"`
+}
+
+type IChannel interface {
+	Name() string
+	// Reachable tells whether the channel can deliver to the user,
+	// e.g. the user has an email address
+	Reachable(p Preferences) bool
+	Send(ctx context.Context, p Preferences, n Notification, msg Message) error
+}
+
+type IPreferenceStore interface {
+	// Get returns the preferences of the user, zero value with UserID set if
+	// the user never configured any
+	Get(userID string) (Preferences, error)
+	Save(p Preferences) error
+}
+
+// SeenEvent is an event id whose notification was handled, remembered
+// until Expiry to suppress its redeliveries
+type SeenEvent struct {
+	ID     string    `json:"id"`
+	Expiry time.Time `json:"expiry"`
+}
+
+type ISeenEventStore interface {
+	Load() ([]SeenEvent, error)
+	// Save replaces the stored events, oldest first
+	Save(events []SeenEvent) error
+}
+
+type IInbox interface {
+	Add(item InboxItem) (InboxItem, error)
+	// List returns up to limit items of the user older than the before
+	// cursor (all if empty), newest first
+	List(userID string, unreadOnly bool, before string, limit int) ([]InboxItem, error)
+	// MarkRead returns ErrNotFound if the user has no such item
+	MarkRead(userID, id string) error
+	MarkAllRead(userID string) (int, error)
+	Unread(userID string) (int, error)
+}
+
+// WebhookSubscription registers a partner URL for some notification types
+type WebhookSubscription struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+	// Notification types delivered, "*" for all
+	EventTypes []string `json:"event_types"`
+	// Key of the HMAC-SHA256 signature, only shown on creation
+	Secret    string    `json:"secret,omitempty"`
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+	// Deliveries that failed every attempt in a row, disables the
+	// subscription past a threshold
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	DisabledAt          *time.Time `json:"disabled_at,omitempty"`
+}
+
+func (s WebhookSubscription) Matches(notificationType string) bool {
+	for _, t := range s.EventTypes {
+		if t == "*" || t == notificationType {
+			return true
+		}
+	}
+	return false
+}
+
+// Delivery is one attempt at delivering an event to a webhook
+type Delivery struct {
+	ID             string    `json:"id"`
+	SubscriptionID string    `json:"subscription_id"`
+	EventID        string    `json:"event_id"`
+	EventType      string    `json:"event_type"`
+	Attempt        int       `json:"attempt"`
+	StatusCode     int       `json:"status_code,omitempty"`
+	Error          string    `json:"error,omitempty"`
+	Success        bool      `json:"success"`
+	DurationMs     int64     `json:"duration_ms"`
+	AttemptedAt    time.Time `json:"attempted_at"`
+	// Set when another attempt is scheduled
+	NextRetryAt *time.Time `json:"next_retry_at,omitempty"`
+}
+
+type ISubscriptionStore interface {
+	Create(s WebhookSubscription) (WebhookSubscription, error)
+	// Get returns ErrNotFound for unknown ids, as Update and Delete
+	Get(id string) (WebhookSubscription, error)
+	List() ([]WebhookSubscription, error)
+	// Update applies fn to the stored subscription atomically
+	Update(id string, fn func(s *WebhookSubscription)) (WebhookSubscription, error)
+	Delete(id string) error
+}
+
+type IDeliveryLog interface {
+	Record(d Delivery) error
+	// List returns up to limit deliveries of the subscription, newest first
+	List(subscriptionID string, failedOnly bool, limit int) ([]Delivery, error)
+}
@@ -0,0 +1,44 @@
+package web
+
+import (
+	"eda-shared/auth"
+	"net/http"
+)
+
+// authenticate returns the claims of the users-service token found in the
+// Authorization header or the access_token query parameter.
+func (s Server) authenticate(r *http.Request) (auth.Claims, error) {
+	raw := auth.BearerToken(r)
+	if raw == "" {
+		raw = r.URL.Query().Get("access_token")
+	}
+	return auth.Verify(raw, s.SigningKey)
+}
+
+// authenticated wraps handlers needing the user id of the caller.
+func (s Server) authenticated(handler func(w http.ResponseWriter, r *http.Request, userID string)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := s.authenticate(r)
+		if err != nil {
+			http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+		handler(w, r, claims.Subject)
+	}
+}
+
+// admin wraps handlers restricted to the admin role.
+func (s Server) admin(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := s.authenticate(r)
+		if err != nil {
+			http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if !claims.Admin() {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		handler(w, r)
+	}
+}
@@ -0,0 +1,102 @@
+package web
+
+import (
+	"eda-notifications/internal/types"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+const (
+	DEFAULT_PAGE_SIZE = 20
+	MAX_PAGE_SIZE     = 100
+)
+
+type inboxPage struct {
+	Items       []types.InboxItem `json:"items"`
+	UnreadCount int               `json:"unread_count"`
+	// Pass as cursor to get the next page, empty on the last one
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// Notifications lists the caller's inbox, newest first:
+// GET /notifications?limit=20&cursor=<next_cursor>&unread=true
+func (s Server) Notifications(w http.ResponseWriter, r *http.Request, userID string) {
+	query := r.URL.Query()
+
+	limit := DEFAULT_PAGE_SIZE
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, MAX_PAGE_SIZE)
+	}
+
+	unreadOnly := query.Get("unread") == "true"
+
+	// One more item tells whether there is a next page
+	items, err := s.Inbox.List(userID, unreadOnly, query.Get("cursor"), limit+1)
+	if err != nil {
+		log.Printf("Error listing inbox of %s: %v", userID, err)
+		http.Error(w, "Failed to list notifications", http.StatusInternalServerError)
+		return
+	}
+
+	unread, err := s.Inbox.Unread(userID)
+	if err != nil {
+		log.Printf("Error counting unread notifications of %s: %v", userID, err)
+		http.Error(w, "Failed to list notifications", http.StatusInternalServerError)
+		return
+	}
+
+	page := inboxPage{Items: items, UnreadCount: unread}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		page.NextCursor = items[limit-1].ID
+	}
+	if page.Items == nil {
+		page.Items = []types.InboxItem{}
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (s Server) UnreadCount(w http.ResponseWriter, r *http.Request, userID string) {
+	unread, err := s.Inbox.Unread(userID)
+	if err != nil {
+		log.Printf("Error counting unread notifications of %s: %v", userID, err)
+		http.Error(w, "Failed to count notifications", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]int{"unread_count": unread})
+}
+
+func (s Server) MarkRead(w http.ResponseWriter, r *http.Request, userID string) {
+	err := s.Inbox.MarkRead(userID, r.PathValue("id"))
+	if errors.Is(err, types.ErrNotFound) {
+		http.Error(w, "Notification not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error marking notification read for %s: %v", userID, err)
+		http.Error(w, "Failed to mark notification read", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s Server) MarkAllRead(w http.ResponseWriter, r *http.Request, userID string) {
+	n, err := s.Inbox.MarkAllRead(userID)
+	if err != nil {
+		log.Printf("Error marking notifications read for %s: %v", userID, err)
+		http.Error(w, "Failed to mark notifications read", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]int{"marked": n})
+}
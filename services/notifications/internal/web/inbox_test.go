@@ -0,0 +1,129 @@
+package web
+
+import (
+	"eda-notifications/internal/store"
+	"eda-notifications/internal/types"
+	"eda-shared/auth"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const testKey = "test-key"
+
+func newTestServer(t *testing.T) (Server, *store.Inbox) {
+	t.Helper()
+
+	inbox, err := store.NewInbox("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Server{Inbox: inbox, SigningKey: []byte(testKey)}, inbox
+}
+
+// request serves method path as user, anonymously when user is empty.
+func request(t *testing.T, s Server, user, method, path string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	r := httptest.NewRequest(method, path, nil)
+	if user != "" {
+		token, err := auth.Sign([]byte(testKey), user, "user", time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	return w
+}
+
+func TestInboxPages(t *testing.T) {
+	s, inbox := newTestServer(t)
+	for i := range 5 {
+		if _, err := inbox.Add(types.InboxItem{UserID: "alice", Subject: fmt.Sprint(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var subjects []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages == 3 {
+			t.Fatal("more than 3 pages of 2 items")
+		}
+
+		w := request(t, s, "alice", http.MethodGet, "/notifications?limit=2&cursor="+cursor)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status %d: %s", w.Code, w.Body)
+		}
+
+		var page inboxPage
+		if err := json.NewDecoder(w.Body).Decode(&page); err != nil {
+			t.Fatal(err)
+		}
+		if page.UnreadCount != 5 {
+			t.Errorf("unread_count = %d, want 5", page.UnreadCount)
+		}
+		for _, item := range page.Items {
+			subjects = append(subjects, item.Subject)
+		}
+
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+
+	if fmt.Sprint(subjects) != "[4 3 2 1 0]" {
+		t.Errorf("items = %v, want newest first", subjects)
+	}
+}
+
+func TestInboxRead(t *testing.T) {
+	s, inbox := newTestServer(t)
+	item, err := inbox.Add(types.InboxItem{UserID: "alice", Subject: "order confirmed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := inbox.Add(types.InboxItem{UserID: "alice", Subject: "payment processed"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		user, method, path string
+		want               int
+	}{
+		{"", http.MethodGet, "/notifications", http.StatusUnauthorized},
+		{"alice", http.MethodGet, "/notifications?limit=0", http.StatusBadRequest},
+		{"bob", http.MethodPost, "/notifications/" + item.ID + "/read", http.StatusNotFound},
+		{"alice", http.MethodPost, "/notifications/" + item.ID + "/read", http.StatusNoContent},
+	} {
+		if w := request(t, s, tc.user, tc.method, tc.path); w.Code != tc.want {
+			t.Errorf("%s %s as %q: status %d, want %d", tc.method, tc.path, tc.user, w.Code, tc.want)
+		}
+	}
+
+	w := request(t, s, "alice", http.MethodGet, "/notifications?unread=true")
+	var page inboxPage
+	if err := json.NewDecoder(w.Body).Decode(&page); err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || page.Items[0].Subject != "payment processed" || page.UnreadCount != 1 {
+		t.Errorf("unread page = %+v", page)
+	}
+
+	w = request(t, s, "alice", http.MethodPost, "/notifications/read")
+	if w.Body.String() != `{"marked":1}`+"\n" {
+		t.Errorf("mark all read = %s", w.Body)
+	}
+
+	w = request(t, s, "alice", http.MethodGet, "/notifications/unread-count")
+	if w.Body.String() != `{"unread_count":0}`+"\n" {
+		t.Errorf("unread count = %s", w.Body)
+	}
+}
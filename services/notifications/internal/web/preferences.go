@@ -0,0 +1,76 @@
+package web
+
+import (
+	"context"
+	"eda-notifications/internal/channels"
+	"eda-notifications/internal/types"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+)
+
+func (s Server) validatePreferences(ctx context.Context, p types.Preferences) error {
+	if p.Email != "" {
+		if _, err := mail.ParseAddress(p.Email); err != nil {
+			return fmt.Errorf("invalid email: %v", err)
+		}
+	}
+
+	if p.WebhookURL != "" {
+		if err := channels.CheckWebhookURL(ctx, p.WebhookURL, s.AllowPrivateWebhooks); err != nil {
+			return fmt.Errorf("invalid webhook_url: %v", err)
+		}
+	}
+
+	for name := range p.Channels {
+		switch name {
+		case channels.EMAIL, channels.WEBHOOK, channels.IN_APP:
+		default:
+			return fmt.Errorf("unknown channel %q", name)
+		}
+	}
+
+	if p.QuietHours != nil {
+		if err := p.QuietHours.Validate(); err != nil {
+			return fmt.Errorf("invalid quiet_hours: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func (s Server) GetPreferences(w http.ResponseWriter, r *http.Request, userID string) {
+	p, err := s.Preferences.Get(userID)
+	if err != nil {
+		log.Printf("Error loading preferences of %s: %v", userID, err)
+		http.Error(w, "Failed to load preferences", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, p)
+}
+
+// PutPreferences replaces the caller's preferences.
+func (s Server) PutPreferences(w http.ResponseWriter, r *http.Request, userID string) {
+	var p types.Preferences
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	p.UserID = userID
+
+	if err := s.validatePreferences(r.Context(), p); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.Preferences.Save(p); err != nil {
+		log.Printf("Error saving preferences of %s: %v", userID, err)
+		http.Error(w, "Failed to save preferences", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, p)
+}
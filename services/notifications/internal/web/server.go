@@ -0,0 +1,117 @@
+package web
+
+import (
+	"eda-notifications/internal"
+	"eda-notifications/internal/types"
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+type Server struct {
+	Renderer    *internal.Renderer
+	Inbox       types.IInbox
+	Preferences types.IPreferenceStore
+	// webhook_url may point to private addresses, for local setups only
+	AllowPrivateWebhooks bool
+
+	Subscriptions types.ISubscriptionStore
+	Deliveries    types.IDeliveryLog
+
+	// Key of the users-service tokens
+	SigningKey []byte
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// Templates lists the locales available for each notification type.
+func (s Server) Templates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.Renderer.Templates())
+}
+
+type previewRequest struct {
+	Type   string         `json:"type"`
+	Locale string         `json:"locale"`
+	Params map[string]any `json:"params"`
+}
+
+// Preview renders a notification without sending it, either from a JSON body
+// (POST) or from the query string (GET /templates/preview?type=order.created&locale=fr&orderId=42).
+func (s Server) Preview(w http.ResponseWriter, r *http.Request) {
+	var req previewRequest
+
+	switch r.Method {
+	case http.MethodGet:
+		query := r.URL.Query()
+		req.Type = query.Get("type")
+		req.Locale = query.Get("locale")
+		req.Params = make(map[string]any)
+		for key := range query {
+			if key == "type" || key == "locale" {
+				continue
+			}
+			// Numbers are passed as such so printf verbs work in templates
+			if f, err := strconv.ParseFloat(query.Get(key), 64); err == nil {
+				req.Params[key] = f
+			} else {
+				req.Params[key] = query.Get(key)
+			}
+		}
+	case http.MethodPost:
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if req.Type == "" {
+		http.Error(w, "Notification type is required", http.StatusBadRequest)
+		return
+	}
+
+	msg, err := s.Renderer.Render(types.Notification{Type: req.Type, Params: req.Params}, req.Locale)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, msg)
+}
+
+func (s Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/templates", s.Templates)
+	mux.HandleFunc("/templates/preview", s.Preview)
+
+	mux.HandleFunc("GET /notifications", s.authenticated(s.Notifications))
+	mux.HandleFunc("GET /notifications/unread-count", s.authenticated(s.UnreadCount))
+	mux.HandleFunc("POST /notifications/read", s.authenticated(s.MarkAllRead))
+	mux.HandleFunc("POST /notifications/{id}/read", s.authenticated(s.MarkRead))
+
+	mux.HandleFunc("GET /preferences", s.authenticated(s.GetPreferences))
+	mux.HandleFunc("PUT /preferences", s.authenticated(s.PutPreferences))
+
+	mux.HandleFunc("POST /webhooks", s.admin(s.CreateWebhook))
+	mux.HandleFunc("GET /webhooks", s.admin(s.ListWebhooks))
+	mux.HandleFunc("GET /webhooks/{id}", s.admin(s.GetWebhook))
+	mux.HandleFunc("DELETE /webhooks/{id}", s.admin(s.DeleteWebhook))
+	mux.HandleFunc("POST /webhooks/{id}/enable", s.admin(s.EnableWebhook))
+	mux.HandleFunc("GET /webhooks/{id}/deliveries", s.admin(s.WebhookDeliveries))
+
+	mux.ServeHTTP(w, r)
+
+}
@@ -0,0 +1,149 @@
+package web
+
+import (
+	"eda-notifications/internal"
+	"eda-notifications/internal/types"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+type subscriptionRequest struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+}
+
+// redact hides the secret, only returned when the subscription is created.
+func redact(sub types.WebhookSubscription) types.WebhookSubscription {
+	sub.Secret = ""
+	return sub
+}
+
+func (s Server) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var req subscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		http.Error(w, "url must be an http(s) URL", http.StatusBadRequest)
+		return
+	}
+	if len(req.EventTypes) == 0 {
+		http.Error(w, "event_types is required, use [\"*\"] for all types", http.StatusBadRequest)
+		return
+	}
+
+	sub, err := s.Subscriptions.Create(types.WebhookSubscription{
+		ID:         internal.NewID(),
+		URL:        req.URL,
+		EventTypes: req.EventTypes,
+		Secret:     internal.NewSecret(),
+		Enabled:    true,
+		CreatedAt:  time.Now().UTC(),
+	})
+	if err != nil {
+		log.Printf("Error creating webhook subscription: %v", err)
+		http.Error(w, "Failed to create subscription", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, sub)
+}
+
+func (s Server) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	subs, err := s.Subscriptions.List()
+	if err != nil {
+		log.Printf("Error listing webhook subscriptions: %v", err)
+		http.Error(w, "Failed to list subscriptions", http.StatusInternalServerError)
+		return
+	}
+
+	for i := range subs {
+		subs[i] = redact(subs[i])
+	}
+
+	writeJSON(w, http.StatusOK, subs)
+}
+
+func (s Server) writeSubscription(w http.ResponseWriter, sub types.WebhookSubscription, err error) {
+	if errors.Is(err, types.ErrNotFound) {
+		http.Error(w, "Subscription not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error accessing webhook subscription: %v", err)
+		http.Error(w, "Failed to access subscription", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, redact(sub))
+}
+
+func (s Server) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	sub, err := s.Subscriptions.Get(r.PathValue("id"))
+	s.writeSubscription(w, sub, err)
+}
+
+// EnableWebhook re-enables a subscription disabled after repeated failures.
+func (s Server) EnableWebhook(w http.ResponseWriter, r *http.Request) {
+	sub, err := s.Subscriptions.Update(r.PathValue("id"), func(sub *types.WebhookSubscription) {
+		sub.Enabled = true
+		sub.DisabledAt = nil
+		sub.ConsecutiveFailures = 0
+	})
+	s.writeSubscription(w, sub, err)
+}
+
+func (s Server) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	err := s.Subscriptions.Delete(r.PathValue("id"))
+	if errors.Is(err, types.ErrNotFound) {
+		http.Error(w, "Subscription not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error deleting webhook subscription: %v", err)
+		http.Error(w, "Failed to delete subscription", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// WebhookDeliveries lists the delivery attempts of a subscription, newest
+// first: GET /webhooks/{id}/deliveries?limit=50&failed=true
+func (s Server) WebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if _, err := s.Subscriptions.Get(id); err != nil {
+		s.writeSubscription(w, types.WebhookSubscription{}, err)
+		return
+	}
+
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, 500)
+	}
+
+	deliveries, err := s.Deliveries.List(id, r.URL.Query().Get("failed") == "true", limit)
+	if err != nil {
+		log.Printf("Error listing webhook deliveries: %v", err)
+		http.Error(w, "Failed to list deliveries", http.StatusInternalServerError)
+		return
+	}
+	if deliveries == nil {
+		deliveries = []types.Delivery{}
+	}
+
+	writeJSON(w, http.StatusOK, deliveries)
+}
@@ -0,0 +1,287 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"eda-notifications/internal/types"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Headers of webhook requests. The signature is the hex HMAC-SHA256 of
+// "<timestamp>.<body>" keyed by the subscription secret, prefixed by "sha256=".
+const (
+	HEADER_EVENT_ID  = "X-Webhook-Id"
+	HEADER_EVENT     = "X-Webhook-Event"
+	HEADER_TIMESTAMP = "X-Webhook-Timestamp"
+	HEADER_SIGNATURE = "X-Webhook-Signature"
+)
+
+// NewID returns a random hex identifier.
+func NewID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func NewSecret() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return "whsec_" + hex.EncodeToString(b)
+}
+
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature headers of a webhook request, rejecting
+// timestamps further than tolerance from now to prevent replays.
+func Verify(secret string, header http.Header, body []byte, tolerance time.Duration) error {
+	timestamp, err := strconv.ParseInt(header.Get(HEADER_TIMESTAMP), 10, 64)
+	if err != nil {
+		return errors.New("invalid timestamp header")
+	}
+
+	if d := time.Since(time.Unix(timestamp, 0)); d > tolerance || d < -tolerance {
+		return errors.New("timestamp outside tolerance")
+	}
+
+	expected := Sign(secret, timestamp, body)
+	if !hmac.Equal([]byte(expected), []byte(header.Get(HEADER_SIGNATURE))) {
+		return errors.New("signature mismatch")
+	}
+
+	return nil
+}
+
+type WebhookConfig struct {
+	Workers     int
+	Timeout     time.Duration
+	MaxAttempts int
+	// Delay before the second attempt, doubled each time up to MaxBackoff
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// Consecutive failed deliveries after which a subscription is disabled
+	DisableAfter int
+}
+
+func DefaultWebhookConfig() WebhookConfig {
+	return WebhookConfig{
+		Workers:      4,
+		Timeout:      10 * time.Second,
+		MaxAttempts:  6,
+		MinBackoff:   time.Second,
+		MaxBackoff:   5 * time.Minute,
+		DisableAfter: 5,
+	}
+}
+
+// WebhookEvent is the body posted to partners
+type WebhookEvent struct {
+	ID        string         `json:"id"`
+	Type      string         `json:"type"`
+	CreatedAt time.Time      `json:"created_at"`
+	UserID    string         `json:"user_id,omitempty"`
+	Params    map[string]any `json:"params,omitempty"`
+}
+
+type webhookJob struct {
+	subscriptionID string
+	event          WebhookEvent
+	body           []byte
+	attempt        int
+}
+
+// Webhooks delivers notifications to the partner subscriptions, retrying
+// failed deliveries with exponential backoff.
+type Webhooks struct {
+	subscriptions types.ISubscriptionStore
+	deliveries    types.IDeliveryLog
+	client        *http.Client
+	config        WebhookConfig
+
+	jobs chan webhookJob
+}
+
+func NewWebhooks(subscriptions types.ISubscriptionStore, deliveries types.IDeliveryLog, config WebhookConfig) *Webhooks {
+	return &Webhooks{
+		subscriptions: subscriptions,
+		deliveries:    deliveries,
+		client:        &http.Client{Timeout: config.Timeout},
+		config:        config,
+		jobs:          make(chan webhookJob, 1000),
+	}
+}
+
+func (wh *Webhooks) Run(ctx context.Context) {
+	for i := 0; i < max(wh.config.Workers, 1); i++ {
+		go func() {
+			for {
+				select {
+				case job := <-wh.jobs:
+					wh.deliver(ctx, job)
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+}
+
+// Publish queues the notification for every enabled subscription to its type.
+func (wh *Webhooks) Publish(ctx context.Context, n types.Notification) error {
+	subs, err := wh.subscriptions.List()
+	if err != nil {
+		return err
+	}
+
+	id := n.EventID
+	if id == "" {
+		id = NewID()
+	}
+
+	event := WebhookEvent{
+		ID:        id,
+		Type:      n.Type,
+		CreatedAt: time.Now().UTC(),
+		UserID:    n.UserID,
+		Params:    n.Params,
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	for _, sub := range subs {
+		if !sub.Enabled || !sub.Matches(n.Type) {
+			continue
+		}
+
+		select {
+		case wh.jobs <- webhookJob{subscriptionID: sub.ID, event: event, body: body, attempt: 1}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+func (wh *Webhooks) backoff(attempt int) time.Duration {
+	d := wh.config.MinBackoff
+	for i := 1; i < attempt && d < wh.config.MaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, wh.config.MaxBackoff)
+}
+
+func (wh *Webhooks) deliver(ctx context.Context, job webhookJob) {
+	sub, err := wh.subscriptions.Get(job.subscriptionID)
+	if err != nil || !sub.Enabled {
+		// Deleted or disabled since the event was queued
+		return
+	}
+
+	start := time.Now()
+	delivery := types.Delivery{
+		ID:             NewID(),
+		SubscriptionID: sub.ID,
+		EventID:        job.event.ID,
+		EventType:      job.event.Type,
+		Attempt:        job.attempt,
+		AttemptedAt:    start.UTC(),
+	}
+
+	status, err := wh.post(ctx, sub, job, start.Unix())
+	delivery.DurationMs = time.Since(start).Milliseconds()
+	delivery.StatusCode = status
+	delivery.Success = err == nil
+	if err != nil {
+		delivery.Error = err.Error()
+	}
+
+	retry := !delivery.Success && job.attempt < wh.config.MaxAttempts
+	if retry {
+		next := time.Now().Add(wh.backoff(job.attempt)).UTC()
+		delivery.NextRetryAt = &next
+	}
+
+	if err := wh.deliveries.Record(delivery); err != nil {
+		log.Printf("Error recording webhook delivery: %v", err)
+	}
+
+	switch {
+	case delivery.Success:
+		if sub.ConsecutiveFailures > 0 {
+			wh.update(sub.ID, func(s *types.WebhookSubscription) { s.ConsecutiveFailures = 0 })
+		}
+
+	case retry:
+		job.attempt++
+		time.AfterFunc(time.Until(*delivery.NextRetryAt), func() {
+			select {
+			case wh.jobs <- job:
+			case <-ctx.Done():
+			}
+		})
+
+	default:
+		log.Printf("Webhook %s failed %d times for event %s: %v", sub.URL, job.attempt, job.event.ID, err)
+		wh.update(sub.ID, func(s *types.WebhookSubscription) {
+			s.ConsecutiveFailures++
+			if s.Enabled && s.ConsecutiveFailures >= wh.config.DisableAfter {
+				now := time.Now().UTC()
+				s.Enabled = false
+				s.DisabledAt = &now
+				log.Printf("Webhook %s disabled after %d failed deliveries", s.URL, s.ConsecutiveFailures)
+			}
+		})
+	}
+}
+
+func (wh *Webhooks) update(id string, fn func(s *types.WebhookSubscription)) {
+	if _, err := wh.subscriptions.Update(id, fn); err != nil && !errors.Is(err, types.ErrNotFound) {
+		log.Printf("Error updating webhook subscription %s: %v", id, err)
+	}
+}
+
+func (wh *Webhooks) post(ctx context.Context, sub types.WebhookSubscription, job webhookJob, timestamp int64) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(job.body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "skate-shop-webhooks/1.0")
+	req.Header.Set(HEADER_EVENT_ID, job.event.ID)
+	req.Header.Set(HEADER_EVENT, job.event.Type)
+	req.Header.Set(HEADER_TIMESTAMP, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HEADER_SIGNATURE, Sign(sub.Secret, timestamp, job.body))
+
+	res, err := wh.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return res.StatusCode, fmt.Errorf("answered %s", res.Status)
+	}
+
+	return res.StatusCode, nil
+}
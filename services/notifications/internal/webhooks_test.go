@@ -0,0 +1,284 @@
+package internal
+
+import (
+	"context"
+	"eda-notifications/internal/store"
+	"eda-notifications/internal/types"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	body := []byte(`{"id":"1","type":"order.created"}`)
+	now := time.Now().Unix()
+
+	header := func(secret string, timestamp int64) http.Header {
+		return http.Header{
+			HEADER_TIMESTAMP: {strconv.FormatInt(timestamp, 10)},
+			HEADER_SIGNATURE: {Sign(secret, timestamp, body)},
+		}
+	}
+
+	if err := Verify("whsec_a", header("whsec_a", now), body, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, tc := range map[string]struct {
+		header http.Header
+		body   []byte
+	}{
+		"other secret":   {header("whsec_b", now), body},
+		"tampered body":  {header("whsec_a", now), []byte(`{"id":"2","type":"order.created"}`)},
+		"replayed":       {header("whsec_a", now-120), body},
+		"future":         {header("whsec_a", now+120), body},
+		"no timestamp":   {http.Header{HEADER_SIGNATURE: {Sign("whsec_a", now, body)}}, body},
+		"no signature":   {http.Header{HEADER_TIMESTAMP: {strconv.FormatInt(now, 10)}}, body},
+		"timestamp lies": {http.Header{HEADER_TIMESTAMP: {strconv.FormatInt(now+1, 10)}, HEADER_SIGNATURE: {Sign("whsec_a", now, body)}}, body},
+	} {
+		if err := Verify("whsec_a", tc.header, tc.body, time.Minute); err == nil {
+			t.Errorf("%s: verified", name)
+		}
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	config := DefaultWebhookConfig()
+	config.MinBackoff = time.Second
+	config.MaxBackoff = 10 * time.Second
+	wh := NewWebhooks(nil, nil, config)
+
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second}
+	for i, d := range want {
+		if got := wh.backoff(i + 1); got != d {
+			t.Errorf("backoff after attempt %d = %s, want %s", i+1, got, d)
+		}
+	}
+}
+
+// partner is a webhook endpoint answering the given statuses in turn, then
+// the last one, and checking the signatures.
+type partner struct {
+	t        *testing.T
+	secret   string
+	statuses []int
+
+	mu       sync.Mutex
+	received []WebhookEvent
+}
+
+func (p *partner) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		p.t.Error(err)
+	}
+	if err := Verify(p.secret, r.Header, body, time.Minute); err != nil {
+		p.t.Errorf("webhook signature: %v", err)
+	}
+
+	var event WebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		p.t.Error(err)
+	}
+	if r.Header.Get(HEADER_EVENT_ID) != event.ID || r.Header.Get(HEADER_EVENT) != event.Type {
+		p.t.Errorf("headers %v of event %+v", r.Header, event)
+	}
+
+	p.mu.Lock()
+	status := p.statuses[min(len(p.received), len(p.statuses)-1)]
+	p.received = append(p.received, event)
+	p.mu.Unlock()
+
+	w.WriteHeader(status)
+}
+
+func (p *partner) count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.received)
+}
+
+// startWebhooks runs Webhooks with one subscription of the partner to
+// order.created.
+func startWebhooks(t *testing.T, config WebhookConfig, p *partner, failures int) (*Webhooks, *store.Subscriptions, *store.Deliveries, types.WebhookSubscription) {
+	t.Helper()
+
+	server := httptest.NewServer(p)
+	t.Cleanup(server.Close)
+
+	subscriptions, err := store.NewSubscriptions("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub, err := subscriptions.Create(types.WebhookSubscription{
+		ID:                  NewID(),
+		URL:                 server.URL,
+		EventTypes:          []string{types.ORDER_CREATED},
+		Secret:              p.secret,
+		Enabled:             true,
+		CreatedAt:           time.Now().UTC(),
+		ConsecutiveFailures: failures,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deliveries := store.NewDeliveries()
+	wh := NewWebhooks(subscriptions, deliveries, config)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	wh.Run(ctx)
+
+	return wh, subscriptions, deliveries, sub
+}
+
+func testWebhookConfig() WebhookConfig {
+	config := DefaultWebhookConfig()
+	config.Timeout = time.Second
+	config.MinBackoff = time.Millisecond
+	config.MaxBackoff = 4 * time.Millisecond
+	return config
+}
+
+// waitDeliveries waits for n deliveries of the subscription, newest first.
+func waitDeliveries(t *testing.T, deliveries *store.Deliveries, subscriptionID string, n int) []types.Delivery {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		list, err := deliveries.List(subscriptionID, false, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) >= n {
+			return list
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d deliveries, want %d", len(list), n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// waitSubscription waits for the subscription to satisfy done.
+func waitSubscription(t *testing.T, subscriptions *store.Subscriptions, id string, done func(s types.WebhookSubscription) bool) types.WebhookSubscription {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		sub, err := subscriptions.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if done(sub) {
+			return sub
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("subscription = %+v", sub)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestWebhooksRetryUntilMaxAttempts(t *testing.T) {
+	config := testWebhookConfig()
+	config.MaxAttempts = 3
+	p := &partner{t: t, secret: NewSecret(), statuses: []int{http.StatusInternalServerError}}
+	wh, subscriptions, deliveries, sub := startWebhooks(t, config, p, 0)
+
+	n := types.Notification{EventID: "order.created:1", Type: types.ORDER_CREATED, UserID: "alice"}
+	if err := wh.Publish(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+
+	list := waitDeliveries(t, deliveries, sub.ID, 3)
+	// No attempt past MaxAttempts
+	time.Sleep(20 * time.Millisecond)
+	if p.count() != 3 {
+		t.Errorf("%d attempts, want 3", p.count())
+	}
+
+	for i, d := range list {
+		attempt := 3 - i
+		if d.Attempt != attempt || d.Success || d.StatusCode != http.StatusInternalServerError || d.EventID != n.EventID {
+			t.Errorf("delivery %d = %+v", i, d)
+		}
+		if (d.NextRetryAt != nil) != (attempt < 3) {
+			t.Errorf("attempt %d next retry at %v", attempt, d.NextRetryAt)
+		}
+	}
+
+	failed, err := deliveries.List(sub.ID, true, 1)
+	if err != nil || len(failed) != 1 || failed[0].Attempt != 3 {
+		t.Errorf("last failed delivery = %+v, %v", failed, err)
+	}
+
+	got := waitSubscription(t, subscriptions, sub.ID, func(s types.WebhookSubscription) bool { return s.ConsecutiveFailures > 0 })
+	if got.ConsecutiveFailures != 1 || !got.Enabled {
+		t.Errorf("subscription = %+v, want 1 failure and still enabled", got)
+	}
+}
+
+func TestWebhooksSuccessResetsFailures(t *testing.T) {
+	config := testWebhookConfig()
+	p := &partner{t: t, secret: NewSecret(), statuses: []int{http.StatusServiceUnavailable, http.StatusNoContent}}
+	wh, subscriptions, deliveries, sub := startWebhooks(t, config, p, 2)
+
+	// Not subscribed to
+	if err := wh.Publish(context.Background(), types.Notification{Type: types.PAYMENT_PROCESSED}); err != nil {
+		t.Fatal(err)
+	}
+	if err := wh.Publish(context.Background(), types.Notification{Type: types.ORDER_CREATED, UserID: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+
+	list := waitDeliveries(t, deliveries, sub.ID, 2)
+	if !list[0].Success || list[0].Attempt != 2 || list[0].StatusCode != http.StatusNoContent {
+		t.Errorf("last delivery = %+v, want the second attempt delivered", list[0])
+	}
+	// A generated event id, kept across attempts
+	if list[0].EventID == "" || list[0].EventID != list[1].EventID {
+		t.Errorf("event ids %q and %q", list[1].EventID, list[0].EventID)
+	}
+
+	waitSubscription(t, subscriptions, sub.ID, func(s types.WebhookSubscription) bool { return s.ConsecutiveFailures == 0 })
+
+	if p.count() != 2 {
+		t.Errorf("partner received %d requests, want 2", p.count())
+	}
+}
+
+func TestWebhooksDisableAfter(t *testing.T) {
+	config := testWebhookConfig()
+	config.MaxAttempts = 1
+	config.DisableAfter = 2
+	p := &partner{t: t, secret: NewSecret(), statuses: []int{http.StatusGone}}
+	wh, subscriptions, deliveries, sub := startWebhooks(t, config, p, 0)
+
+	for i := 1; i <= 2; i++ {
+		if err := wh.Publish(context.Background(), types.Notification{Type: types.ORDER_CREATED}); err != nil {
+			t.Fatal(err)
+		}
+		waitDeliveries(t, deliveries, sub.ID, i)
+	}
+
+	got := waitSubscription(t, subscriptions, sub.ID, func(s types.WebhookSubscription) bool { return !s.Enabled })
+	if got.DisabledAt == nil || got.ConsecutiveFailures != 2 {
+		t.Errorf("disabled subscription = %+v", got)
+	}
+
+	// Disabled subscriptions get nothing
+	if err := wh.Publish(context.Background(), types.Notification{Type: types.ORDER_CREATED}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if p.count() != 2 {
+		t.Errorf("partner received %d requests, want 2", p.count())
+	}
+}
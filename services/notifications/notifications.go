@@ -0,0 +1,148 @@
+// Package notifications is notifications-service: it delivers the
+// notifications consumed from Kafka over email, webhooks and the in-app
+// inbox. cmd/notifications runs it with the environment configuration,
+// tests run it in-process with the state kept in memory.
+package notifications
+
+import (
+	"context"
+	"eda-notifications/internal"
+	"eda-notifications/internal/channels"
+	"eda-notifications/internal/store"
+	"eda-notifications/internal/types"
+	"eda-notifications/internal/web"
+	"eda-shared/kafka"
+	"fmt"
+	"net/http"
+	"time"
+	// Quiet hours time zones, the runtime image has no zoneinfo
+	_ "time/tzdata"
+)
+
+const (
+	PORT = ":3004"
+)
+
+type Config struct {
+	Kafka kafka.Config
+	// Without file the state only lives in memory
+	PreferencesFile string
+	InboxFile       string
+	WebhooksFile    string
+	SeenEventsFile  string
+	// The email channel is disabled without SMTP address
+	Email channels.EmailConfig
+	// Users may choose webhook URLs on private addresses, for local setups
+	AllowPrivateWebhooks bool
+	DefaultLocale        string
+	// Per notification type, e.g. user.logged_in=10m
+	CollapseWindows string
+	// Key of the users-service tokens
+	SigningKey []byte
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Email:           channels.EmailConfig{From: "notifications@skate-shop.local"},
+		DefaultLocale:   internal.DEFAULT_LOCALE,
+		CollapseWindows: "user.logged_in=10m",
+		SigningKey:      []byte("signing-key"),
+	}
+}
+
+type Service struct {
+	client   *internal.KafkaClient
+	webhooks *internal.Webhooks
+	mux      *http.ServeMux
+}
+
+func New(config Config) (*Service, error) {
+	if config.Kafka.Registry == nil {
+		config.Kafka.Registry = kafka.NewRegistry("notifications-service")
+	}
+
+	preferences, err := store.NewPreferences(config.PreferencesFile)
+	if err != nil {
+		return nil, fmt.Errorf("loading preferences: %w", err)
+	}
+
+	inbox, err := store.NewInbox(config.InboxFile)
+	if err != nil {
+		return nil, fmt.Errorf("loading inbox: %w", err)
+	}
+
+	chans := []types.IChannel{
+		channels.NewInApp(inbox),
+		channels.NewWebhook(5*time.Second, config.AllowPrivateWebhooks),
+	}
+
+	if config.Email.Addr != "" {
+		chans = append(chans, channels.NewEmail(config.Email))
+	}
+
+	renderer, err := internal.NewRenderer(config.DefaultLocale)
+	if err != nil {
+		return nil, fmt.Errorf("loading templates: %w", err)
+	}
+
+	subscriptions, err := store.NewSubscriptions(config.WebhooksFile)
+	if err != nil {
+		return nil, fmt.Errorf("loading webhook subscriptions: %w", err)
+	}
+	deliveries := store.NewDeliveries()
+
+	webhooks := internal.NewWebhooks(subscriptions, deliveries, internal.DefaultWebhookConfig())
+
+	dedup, err := internal.NewDeduplicator(24*time.Hour, 100_000, store.NewSeenEvents(config.SeenEventsFile))
+	if err != nil {
+		return nil, fmt.Errorf("loading seen events: %w", err)
+	}
+
+	windows, err := internal.ParseCollapseWindows(config.CollapseWindows)
+	if err != nil {
+		return nil, fmt.Errorf("invalid collapse windows: %w", err)
+	}
+
+	client, err := internal.NewKafkaClient(
+		config.Kafka,
+		internal.NewRouter(preferences, renderer, chans...),
+		renderer,
+		webhooks,
+		dedup,
+		internal.NewCollapser(windows),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("creating Kafka client: %w", err)
+	}
+
+	server := &web.Server{
+		Renderer:             renderer,
+		Inbox:                inbox,
+		Preferences:          preferences,
+		AllowPrivateWebhooks: config.AllowPrivateWebhooks,
+		Subscriptions:        subscriptions,
+		Deliveries:           deliveries,
+		SigningKey:           config.SigningKey,
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/", server)
+	mux.Handle("/topology", config.Kafka.Registry)
+	mux.Handle("/asyncapi", config.Kafka.Registry.AsyncAPIHandler())
+
+	return &Service{client, webhooks, mux}, nil
+}
+
+func (s *Service) Handler() http.Handler {
+	return s.mux
+}
+
+// Run delivers the notifications until ctx is cancelled.
+func (s *Service) Run(ctx context.Context) error {
+	s.webhooks.Run(ctx)
+	return s.client.Read(ctx)
+}
+
+func (s *Service) Close() {
+	s.client.Close()
+}
@@ -0,0 +1,390 @@
+package orders
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	DEFAULT_PAGE_SIZE = 20
+	MAX_PAGE_SIZE     = 100
+	DEFAULT_SORT      = "-createdAt"
+
+	CANCEL_REASON_USER = "cancelled by customer"
+)
+
+// Champs acceptés par ?sort= (préfixe "-" pour un tri décroissant)
+var sortFields = map[string]bool{
+	"createdAt": true,
+	"updatedAt": true,
+	"total":     true,
+}
+
+// Critères de GET /orders
+type OrderQuery struct {
+	UserID   string
+	Statuses []Status
+	From     time.Time
+	To       time.Time
+	Sort     string
+	Desc     bool
+	Limit    int
+	After    *Cursor
+}
+
+// Position de la dernière commande d'une page
+type Cursor struct {
+	Value   any
+	OrderID string
+}
+
+type cursorPayload struct {
+	Value   json.RawMessage `json:"v"`
+	OrderID string          `json:"id"`
+}
+
+// Réponse de GET /orders
+type OrderPage struct {
+	Orders     []Order `json:"orders"`
+	NextCursor string  `json:"nextCursor,omitempty"`
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+func encodeCursor(sort string, o Order) string {
+	var value any
+	switch sort {
+	case "createdAt":
+		value = o.CreatedAt
+	case "updatedAt":
+		value = o.UpdatedAt
+	case "total":
+		value = o.Total
+	}
+
+	v, _ := json.Marshal(value)
+	b, _ := json.Marshal(cursorPayload{Value: v, OrderID: o.OrderID})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(sort, s string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var p cursorPayload
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, err
+	}
+
+	c := &Cursor{OrderID: p.OrderID}
+	if sort == "total" {
+		var total float64
+		err = json.Unmarshal(p.Value, &total)
+		c.Value = total
+	} else {
+		var at time.Time
+		err = json.Unmarshal(p.Value, &at)
+		c.Value = at
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// parseDate accepte une date RFC 3339 ou AAAA-MM-JJ (minuit UTC)
+func parseDate(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, s)
+}
+
+func parseOrderQuery(c *gin.Context) (OrderQuery, error) {
+	q := OrderQuery{UserID: c.Query("userId"), Limit: DEFAULT_PAGE_SIZE}
+
+	if v := c.Query("status"); v != "" {
+		for _, s := range strings.Split(v, ",") {
+			status, err := ParseStatus(strings.TrimSpace(s))
+			if err != nil {
+				return q, err
+			}
+			q.Statuses = append(q.Statuses, status)
+		}
+	}
+
+	var err error
+	if v := c.Query("from"); v != "" {
+		if q.From, err = parseDate(v); err != nil {
+			return q, fmt.Errorf("invalid from: %q", v)
+		}
+	}
+	if v := c.Query("to"); v != "" {
+		if q.To, err = parseDate(v); err != nil {
+			return q, fmt.Errorf("invalid to: %q", v)
+		}
+	}
+
+	sort := c.DefaultQuery("sort", DEFAULT_SORT)
+	q.Sort, q.Desc = strings.TrimPrefix(sort, "-"), strings.HasPrefix(sort, "-")
+	if !sortFields[q.Sort] {
+		return q, fmt.Errorf("invalid sort: %q", sort)
+	}
+
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			return q, fmt.Errorf("invalid limit: %q", v)
+		}
+		q.Limit = min(limit, MAX_PAGE_SIZE)
+	}
+
+	if v := c.Query("cursor"); v != "" {
+		if q.After, err = decodeCursor(q.Sort, v); err != nil {
+			return q, errors.New("invalid cursor")
+		}
+	}
+
+	return q, nil
+}
+
+// GET /orders?userId=&status=paid,reserved&from=&to=&sort=-createdAt&limit=20&cursor=
+// userId n'est libre que pour un admin, les autres ne voient que leurs commandes
+func (s *Service) listOrders(c *gin.Context) {
+	q, err := parseOrderQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if claims := claimsFrom(c); !claims.Admin() {
+		q.UserID = claims.Subject
+	}
+
+	list, next, err := s.orders.List(c.Request.Context(), q)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get orders"})
+		return
+	}
+
+	c.JSON(http.StatusOK, OrderPage{Orders: list, NextCursor: next})
+}
+
+// readableOrder charge la commande :id si le porteur du token peut la lire
+// (propriétaire ou admin). Sinon la réponse est déjà écrite.
+func (s *Service) readableOrder(c *gin.Context) (*Order, bool) {
+	o, err := s.orders.Get(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, ErrNotFound) {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Order not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get order"})
+		return nil, false
+	}
+
+	if claims := claimsFrom(c); !claims.Admin() && o.UserID != claims.Subject {
+		c.JSON(http.StatusForbidden, ErrorResponse{Error: "Forbidden"})
+		return nil, false
+	}
+	return o, true
+}
+
+// GET /orders/:id
+func (s *Service) getOrder(c *gin.Context) {
+	if o, ok := s.readableOrder(c); ok {
+		c.JSON(http.StatusOK, o)
+	}
+}
+
+// POST /orders/:id/cancel, réservé au propriétaire de la commande. Annuler
+// une commande déjà annulée renvoie la commande telle quelle, une commande
+// dont payment.done n'est pas encore arrivé répond 409.
+func (s *Service) cancelOrder(c *gin.Context) {
+	claims := claimsFrom(c)
+	orderID := c.Param("id")
+
+	if _, err := s.orders.Get(c.Request.Context(), orderID); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Order not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get order"})
+		}
+		return
+	}
+
+	o, changes, err := s.orders.Append(c.Request.Context(), orderID, func(o *Order) ([]Event, error) {
+		if o.UserID == "" {
+			return nil, ErrNotPlaced
+		}
+		if o.UserID != claims.Subject {
+			return nil, ErrForbidden
+		}
+		if err := o.CheckTransition(STATUS_CANCELLED); err != nil {
+			return nil, err
+		}
+		if o.Status == STATUS_CANCELLED {
+			return nil, nil
+		}
+		return []Event{{Type: EVENT_CANCELLED, Reason: CANCEL_REASON_USER}}, nil
+	})
+
+	switch {
+	case errors.Is(err, ErrForbidden):
+		c.JSON(http.StatusForbidden, ErrorResponse{Error: "Forbidden"})
+		return
+	case errors.Is(err, ErrInvalidTransition), errors.Is(err, ErrNotPlaced):
+		c.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to cancel order"})
+		return
+	}
+
+	s.publishStatusChanges(o, changes)
+	c.JSON(http.StatusOK, o)
+}
+
+// Réponse de GET /orders/:id/events
+type EventsResponse struct {
+	OrderID string  `json:"orderId"`
+	Events  []Event `json:"events"`
+}
+
+// GET /orders/:id/events: historique des événements de la commande
+func (s *Service) getOrderEvents(c *gin.Context) {
+	o, ok := s.readableOrder(c)
+	if !ok {
+		return
+	}
+
+	events, err := s.orders.Events(c.Request.Context(), o.OrderID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get order events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, EventsResponse{OrderID: o.OrderID, Events: events})
+}
+
+// Réponse des routes /admin/offsets
+type OffsetsResponse struct {
+	GroupID   string                   `json:"groupId"`
+	Timestamp *time.Time               `json:"timestamp,omitempty"`
+	Offsets   map[string]map[int]int64 `json:"offsets"`
+}
+
+type ResetOffsetsRequest struct {
+	// Topics à retraiter, tous les topics consommés si vide
+	Topics    []string  `json:"topics"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// GET /admin/offsets
+func (s *Service) getOffsets(c *gin.Context) {
+	offsets, err := s.consumer.Offsets(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusBadGateway, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, OffsetsResponse{GroupID: s.consumer.config.GroupID, Offsets: offsets})
+}
+
+// POST /admin/offsets/reset {"timestamp": "2024-01-01T00:00:00Z", "topics": ["payment.done"]}
+func (s *Service) resetOffsets(c *gin.Context) {
+	var req ResetOffsetsRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Timestamp.IsZero() {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "timestamp (RFC 3339) is required"})
+		return
+	}
+	if len(req.Topics) == 0 {
+		req.Topics = s.consumer.Topics()
+	}
+
+	offsets, err := s.consumer.ResetOffsets(c.Request.Context(), req.Topics, req.Timestamp)
+	if err != nil {
+		c.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, OffsetsResponse{GroupID: s.consumer.config.GroupID, Timestamp: &req.Timestamp, Offsets: offsets})
+}
+
+// GET /admin/users/:userId/summary: projection orders-by-user
+func (s *Service) getUserSummary(c *gin.Context) {
+	u, err := s.orders.UserSummary(c.Request.Context(), c.Param("userId"))
+	if errors.Is(err, ErrNotFound) {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "User has no order"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get user summary"})
+		return
+	}
+
+	c.JSON(http.StatusOK, u)
+}
+
+// Réponse de GET /admin/sales
+type SalesResponse struct {
+	Sales []DailySales `json:"sales"`
+}
+
+// GET /admin/sales?from=2024-01-01&to=2024-02-01: projection daily-sales,
+// jours de [from, to)
+func (s *Service) getDailySales(c *gin.Context) {
+	var from, to time.Time
+	for name, t := range map[string]*time.Time{"from": &from, "to": &to} {
+		v := c.Query(name)
+		if v == "" {
+			continue
+		}
+		parsed, err := parseDate(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid " + name + ": " + v})
+			return
+		}
+		*t = parsed
+	}
+
+	sales, err := s.orders.DailySales(c.Request.Context(), from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get daily sales"})
+		return
+	}
+
+	c.JSON(http.StatusOK, SalesResponse{Sales: sales})
+}
+
+// POST /admin/projections/rebuild: reconstruit les projections à partir des
+// flux. Cette instance quitte le consumer group le temps de la
+// reconstruction, les autres instances doivent être arrêtées.
+func (s *Service) rebuildProjections(c *gin.Context) {
+	defer s.consumer.restart()
+	if err := s.consumer.pause(c.Request.Context()); err != nil {
+		c.JSON(http.StatusServiceUnavailable, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	start := time.Now()
+	if err := s.orders.Rebuild(c.Request.Context()); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	log.Printf("Projections rebuilt in %s", time.Since(start))
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,98 @@
+package orders
+
+import (
+	"eda-shared/auth"
+	"eda-shared/kafka"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func request(t *testing.T, s *Service, method, path, subject, role string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, nil)
+	if subject != "" {
+		token, err := auth.Sign(DefaultConfig().SigningKey, subject, role, time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	s.Handler().ServeHTTP(w, req)
+	return w
+}
+
+func TestOrderReadsRequireOwner(t *testing.T) {
+	s, _ := startContractService(t)
+
+	for _, evt := range []OrderPlaced{{OrderID: "o-1", UserID: "alice", Total: 10}, {OrderID: "o-2", UserID: "bob", Total: 20}} {
+		value, err := kafka.JSON.Encode(evt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.handlePaymentDone(value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, path := range []string{"/orders", "/orders/o-1", "/orders/o-1/events"} {
+		if w := request(t, s, http.MethodGet, path, "", ""); w.Code != http.StatusUnauthorized {
+			t.Errorf("GET %s without token: status %d", path, w.Code)
+		}
+	}
+
+	for _, tc := range []struct {
+		path, subject, role string
+		want                int
+	}{
+		{"/orders/o-1", "alice", "user", http.StatusOK},
+		{"/orders/o-1/events", "alice", "user", http.StatusOK},
+		{"/orders/o-2", "alice", "user", http.StatusForbidden},
+		{"/orders/o-2/events", "alice", "user", http.StatusForbidden},
+		{"/orders/o-2", "admin", "admin", http.StatusOK},
+		{"/orders/o-2/events", "admin", "admin", http.StatusOK},
+		{"/orders/o-3/events", "admin", "admin", http.StatusNotFound},
+	} {
+		if w := request(t, s, http.MethodGet, tc.path, tc.subject, tc.role); w.Code != tc.want {
+			t.Errorf("GET %s as %s: status %d, want %d", tc.path, tc.subject, w.Code, tc.want)
+		}
+	}
+
+	list := func(subject, role string) []Order {
+		w := request(t, s, http.MethodGet, "/orders?userId=bob", subject, role)
+		var page OrderPage
+		if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+			t.Fatal(err)
+		}
+		return page.Orders
+	}
+	// userId n'est libre que pour un admin
+	if orders := list("alice", "user"); len(orders) != 1 || orders[0].OrderID != "o-1" {
+		t.Errorf("alice listed %v, want only o-1", orders)
+	}
+	if orders := list("admin", "admin"); len(orders) != 1 || orders[0].OrderID != "o-2" {
+		t.Errorf("admin listed %v, want bob's o-2", orders)
+	}
+}
+
+func TestCancelOrderNotPlaced(t *testing.T) {
+	s, _ := startContractService(t)
+
+	// stock.released avant payment.done: commande pending sans propriétaire
+	value, err := kafka.JSON.Encode(StockReleased{OrderID: "o-1", Released: []Item{{SKU: "deck", Qty: 1}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.handleStockReleased(value); err != nil {
+		t.Fatal(err)
+	}
+
+	if w := request(t, s, http.MethodPost, "/orders/o-1/cancel", "alice", "user"); w.Code != http.StatusConflict {
+		t.Errorf("cancel before payment.done: status %d, want 409", w.Code)
+	}
+}
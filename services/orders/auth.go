@@ -0,0 +1,40 @@
+package orders
+
+import (
+	"eda-shared/auth"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	CLAIMS_KEY = "claims"
+)
+
+// requireAuth vérifie le JWT de l'en-tête Authorization et place les claims
+// dans le contexte gin
+func requireAuth(signingKey []byte) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, err := auth.Verify(auth.BearerToken(c.Request), signingKey)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, ErrorResponse{Error: "Unauthorized: " + err.Error()})
+			return
+		}
+
+		c.Set(CLAIMS_KEY, claims)
+		c.Next()
+	}
+}
+
+// requireAdmin suit requireAuth et réserve la route au rôle admin
+func requireAdmin(c *gin.Context) {
+	if !claimsFrom(c).Admin() {
+		c.AbortWithStatusJSON(http.StatusForbidden, ErrorResponse{Error: "Forbidden"})
+		return
+	}
+	c.Next()
+}
+
+func claimsFrom(c *gin.Context) auth.Claims {
+	return c.MustGet(CLAIMS_KEY).(auth.Claims)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"eda-shared/env"
+	"eda-shared/kafka"
+	"log"
+	"net/http"
+	"orders"
+	"os"
+	"strconv"
+	"time"
+)
+
+func duration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Fatalf("Invalid %s: %v", key, err)
+	}
+	return d
+}
+
+func poolConfig() orders.PoolConfig {
+	config := orders.DefaultPoolConfig()
+
+	if v := os.Getenv("ORDERS_WORKERS"); v != "" {
+		workers, err := strconv.Atoi(v)
+		if err != nil || workers < 1 {
+			log.Fatalln("Invalid ORDERS_WORKERS: ", v)
+		}
+		config.Workers = workers
+	}
+	config.SimulatedLatency = duration("ORDERS_SIMULATED_LATENCY", 0)
+
+	return config
+}
+
+func main() {
+	config := orders.DefaultConfig()
+
+	// Kafka: KAFKA_BROKER (fallback kafka:29092) et options de connexion
+	var err error
+	config.Kafka, err = kafka.ConfigFromEnv("orders-service")
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	config.Topics = orders.TopicsFromEnv()
+	config.GroupID = env.Get("ORDERS_GROUP_ID", config.GroupID)
+	config.Pool = poolConfig()
+	config.SigningKey = []byte(env.Get("JWT_SIGNING_KEY", string(config.SigningKey)))
+
+	config.StartOffset, err = kafka.ParseStartOffset(env.Get("ORDERS_START_OFFSET", "first"))
+	if err != nil {
+		log.Fatalln("Invalid ORDERS_START_OFFSET: ", err)
+	}
+
+	service, err := orders.New(config)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer service.Close()
+
+	go func() {
+		if err := service.Run(context.Background()); err != nil {
+			log.Fatalln("Can't start consumer: ", err)
+		}
+	}()
+
+	log.Fatal(http.ListenAndServe(orders.PORT, service.Handler()))
+}
@@ -0,0 +1,102 @@
+// Commande replay: reconstruit les commandes à partir des topics consommés
+// par orders-service, par exemple après la perte de la base:
+//
+//	replay -database orders-db-rebuild -from 2024-01-01 -topics payment.done,order.central
+//
+// Rien n'est publié et le consumer group du service ne bouge pas. Avec
+// -dry-run, les commandes sont reconstruites en mémoire: les messages que
+// les handlers refusent sont signalés sans rien écrire.
+package main
+
+import (
+	"context"
+	"eda-shared/env"
+	"eda-shared/kafka"
+	"flag"
+	"log"
+	"orders"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// date accepte une date RFC 3339 ou AAAA-MM-JJ (minuit UTC)
+func date(t *time.Time) func(string) error {
+	return func(s string) error {
+		parsed, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			parsed, err = time.Parse(time.DateOnly, s)
+		}
+		*t = parsed
+		return err
+	}
+}
+
+// connect ouvre la base à reconstruire
+func connect(ctx context.Context, uri, database string) (*orders.OrderStore, error) {
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := client.Ping(ctx, nil); err != nil {
+		return nil, err
+	}
+	return orders.NewOrderStore(client.Database(database)), nil
+}
+
+func main() {
+	config := orders.ReplayConfig{
+		Topics: orders.TopicsFromEnv(),
+		Replay: kafka.DefaultReplayConfig(),
+	}
+	rc := &config.Replay
+
+	topics := flag.String("topics", "", "topics à rejouer, séparés par des virgules (tous les topics consommés par défaut)")
+	flag.Int64Var(&rc.Offset, "offset", rc.Offset, "premier offset de chaque partition, prioritaire sur -from")
+	flag.Func("from", "rejoue les messages écrits à partir de cette date (RFC 3339 ou AAAA-MM-JJ)", date(&rc.From))
+	flag.Func("to", "rejoue les messages écrits avant cette date", date(&rc.To))
+	flag.StringVar(&rc.Key, "key", "", "seulement les messages de cette commande (orderId)")
+	uri := flag.String("uri", env.Get("MONGO_URI", orders.MONGO_URI), "MongoDB à reconstruire")
+	database := flag.String("database", "orders-db-rebuild", "base à reconstruire, orders-service lit orders-db")
+	flag.Int64Var(&rc.ProgressEvery, "progress", rc.ProgressEvery, "affiche la progression tous les n messages")
+	dryRun := flag.Bool("dry-run", false, "reconstruit les commandes en mémoire sans rien écrire")
+	flag.Parse()
+
+	if *topics != "" {
+		rc.Topics = strings.Split(*topics, ",")
+	}
+	rc.Progress = func(s kafka.ReplayStats) {
+		log.Printf("Replay: %s", s)
+	}
+
+	var err error
+	config.Kafka, err = kafka.ConfigFromEnv("orders-replay")
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if *dryRun {
+		config.Store = orders.NewMemoryStore()
+	} else if config.Store, err = connect(ctx, *uri, *database); err != nil {
+		log.Fatalln("Can't connect to MongoDB: ", err)
+	}
+
+	stats, err := orders.Replay(ctx, config)
+	if err != nil {
+		log.Fatalf("Replay stopped after %s: %v", stats, err)
+	}
+
+	if *dryRun {
+		log.Printf("Dry run: %d messages handled in memory, nothing written", stats.Handled)
+		return
+	}
+	log.Printf("%d messages handled into %s", stats.Handled, *database)
+}
@@ -0,0 +1,244 @@
+package orders
+
+import (
+	"context"
+	"eda-shared/kafka"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Configuration du consumer group d'orders-service
+type ConsumerConfig struct {
+	Kafka kafka.Config
+	// ORDERS_GROUP_ID: toutes les instances partagent les partitions
+	GroupID string
+	// ORDERS_START_OFFSET: position de départ quand le groupe n'a encore
+	// rien commité (kafka.FIRST_OFFSET ou kafka.LAST_OFFSET)
+	StartOffset int64
+}
+
+type partitionKey struct {
+	topic     string
+	partition int
+}
+
+type tracked struct {
+	message kafka.Message
+	done    bool
+}
+
+// offsetTracker retient les messages en cours par partition. Un offset n'est
+// commité que lorsque tous les messages qui le précèdent dans sa partition
+// ont été traités, même si le pool les termine dans le désordre.
+type offsetTracker struct {
+	mu       sync.Mutex
+	inflight map[partitionKey][]*tracked
+}
+
+func newOffsetTracker() *offsetTracker {
+	return &offsetTracker{inflight: make(map[partitionKey][]*tracked)}
+}
+
+func (t *offsetTracker) add(m kafka.Message) *tracked {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tr := &tracked{message: m}
+	key := partitionKey{m.Topic, m.Partition}
+	t.inflight[key] = append(t.inflight[key], tr)
+	return tr
+}
+
+// complete marque le message traité et retourne le dernier message de la
+// partition pouvant être commité
+func (t *offsetTracker) complete(tr *tracked) (kafka.Message, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tr.done = true
+
+	key := partitionKey{tr.message.Topic, tr.message.Partition}
+	queue := t.inflight[key]
+
+	n := 0
+	for n < len(queue) && queue[n].done {
+		n++
+	}
+	if n == 0 {
+		return kafka.Message{}, false
+	}
+
+	last := queue[n-1].message
+	t.inflight[key] = queue[n:]
+	return last, true
+}
+
+// Consumer lit les topics d'orders-service dans un consumer group et confie
+// les messages au pool. Les offsets sont commités après traitement.
+type Consumer struct {
+	config   ConsumerConfig
+	handlers map[string]func([]byte) error
+	pool     *WorkerPool
+
+	mu     sync.Mutex
+	reader *kafka.Consumer
+	// Fermé par Stop, interrompt l'attente après une erreur de lecture
+	stop chan struct{}
+	done chan struct{}
+}
+
+func NewConsumer(config ConsumerConfig, handlers map[string]func([]byte) error, pool *WorkerPool) *Consumer {
+	return &Consumer{
+		config:   config,
+		handlers: handlers,
+		pool:     pool,
+	}
+}
+
+func (c *Consumer) Topics() []string {
+	topics := make([]string, 0, len(c.handlers))
+	for topic := range c.handlers {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// Start rejoint le groupe et commence la lecture
+func (c *Consumer) Start() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.reader != nil {
+		return nil
+	}
+
+	cc := kafka.DefaultConsumerConfig(c.config.GroupID, c.Topics()...)
+	cc.StartOffset = c.config.StartOffset
+	cc.CommitInterval = time.Second
+
+	reader, err := kafka.NewConsumer(c.config.Kafka, cc)
+	if err != nil {
+		return fmt.Errorf("creating consumer: %w", err)
+	}
+	c.reader = reader
+	c.stop = make(chan struct{})
+	c.done = make(chan struct{})
+
+	go c.run(c.reader, newOffsetTracker(), c.stop, c.done)
+	log.Printf("Consumer group %s reading %v", c.config.GroupID, c.Topics())
+	return nil
+}
+
+// Stop quitte le groupe. Les messages déjà confiés au pool sont traités mais
+// leurs offsets ne sont plus commités.
+func (c *Consumer) Stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.reader == nil {
+		return
+	}
+
+	close(c.stop)
+	if err := c.reader.Close(); err != nil {
+		log.Printf("Error closing reader: %v\n", err)
+	}
+	<-c.done
+	c.reader = nil
+}
+
+// pause quitte le groupe et attend la fin des messages déjà confiés au
+// pool: relus après restart, ils seraient traités deux fois en parallèle
+func (c *Consumer) pause(ctx context.Context) error {
+	c.Stop()
+	if err := c.pool.Wait(ctx); err != nil {
+		return fmt.Errorf("waiting for the messages in progress: %w", err)
+	}
+	return nil
+}
+
+// restart reprend la lecture après pause, une fois le pool vidé même si
+// pause a abandonné l'attente
+func (c *Consumer) restart() {
+	_ = c.pool.Wait(context.Background())
+	if err := c.Start(); err != nil {
+		log.Printf("Error restarting consumer: %v\n", err)
+	}
+}
+
+func (c *Consumer) run(reader *kafka.Consumer, tracker *offsetTracker, stop, done chan struct{}) {
+	defer close(done)
+
+	// Attente après une erreur de lecture (broker injoignable), doublée à
+	// chaque erreur consécutive
+	backoff := c.pool.config.MinBackoff
+
+	for {
+		message, err := reader.Fetch(context.Background())
+		if errors.Is(err, kafka.ErrClosed) {
+			return
+		}
+		if err != nil && message.Topic == "" {
+			log.Printf("Error reading message, retrying in %s: %v\n", backoff, err)
+			select {
+			case <-time.After(backoff):
+			case <-stop:
+				return
+			}
+			backoff = min(backoff*2, c.pool.config.MaxBackoff)
+			continue
+		}
+		backoff = c.pool.config.MinBackoff
+
+		log.Printf("Received message on %s: %s", message.Topic, string(message.Value))
+
+		tr := tracker.add(message)
+		c.pool.Submit(message, c.handlers[message.Topic], func() {
+			last, ok := tracker.complete(tr)
+			if !ok {
+				return
+			}
+			// ErrClosed: le reader a été fermé (Stop), le message sera relu
+			err := reader.Commit(context.Background(), last)
+			if err != nil && !errors.Is(err, kafka.ErrClosed) {
+				log.Printf("Error committing %s/%d@%d: %v\n", last.Topic, last.Partition, last.Offset, err)
+			}
+		})
+	}
+}
+
+// Offsets retourne les offsets commités du groupe, par topic et partition
+func (c *Consumer) Offsets(ctx context.Context) (kafka.Offsets, error) {
+	return kafka.CommittedOffsets(ctx, c.config.Kafka, c.config.GroupID, c.Topics())
+}
+
+// ResetOffsets replace le groupe sur le premier message postérieur à at
+// (ou en fin de partition s'il n'y en a pas) pour retraiter les topics.
+// Le groupe doit être vide: cette instance le quitte le temps du reset, les
+// autres instances doivent être arrêtées. La lecture ne reprend qu'une fois
+// les messages en cours traités.
+func (c *Consumer) ResetOffsets(ctx context.Context, topics []string, at time.Time) (kafka.Offsets, error) {
+	for _, topic := range topics {
+		if _, ok := c.handlers[topic]; !ok {
+			return nil, fmt.Errorf("topic %s is not consumed by orders-service", topic)
+		}
+	}
+
+	defer c.restart()
+	if err := c.pause(ctx); err != nil {
+		return nil, err
+	}
+
+	result, err := kafka.ResetOffsets(ctx, c.config.Kafka, c.config.GroupID, topics, at)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Consumer group %s reset to %s: %v", c.config.GroupID, at.Format(time.RFC3339), result)
+	return result, nil
+}
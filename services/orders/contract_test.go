@@ -0,0 +1,123 @@
+package orders
+
+import (
+	"context"
+	"eda-shared/kafka"
+	"flag"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "réécrit les contrats publiés dans ressources/contracts")
+
+// Contrats des consumers, voir kafka.Contract
+const CONTRACTS = "../../ressources/contracts"
+
+// Service sur un broker et un store en mémoire, sans consumer: les tests
+// appellent les handlers
+func startContractService(t *testing.T) (*Service, *kafka.MemoryBroker) {
+	t.Helper()
+
+	broker := kafka.NewMemoryBroker(1)
+	config := DefaultConfig()
+	config.Kafka = kafka.Config{Broker: broker}
+	config.Store = NewMemoryStore()
+
+	s, err := New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Close)
+	return s, broker
+}
+
+// Ce qu'orders-service lit des événements qu'il consomme, dans l'ordre de
+// la vie des commandes 123 (expédiée) et 124 (annulée puis remboursée)
+func consumerContracts(t *testing.T) []kafka.Contract {
+	t.Helper()
+
+	var contracts []kafka.Contract
+	add := func(c kafka.Contract, err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		contracts = append(contracts, c)
+	}
+	add(kafka.NewContract[OrderPlaced]("orders-service", TOPIC_PAYMENT_DONE,
+		`{"orderId": "123", "userId": "456", "items": [{"sku": "pro-street", "qty": 2}], "total": 100}`,
+		`{"orderId": "124", "userId": "456", "items": [{"sku": "pro-street", "qty": 20}], "total": 1000}`))
+	add(kafka.NewContract[StockReserved]("orders-service", TOPIC_STOCK_RESERVED,
+		`{"orderId": "123", "reserved": [{"sku": "pro-street", "qty": 2}]}`))
+	add(kafka.NewContract[ShipmentEvent]("orders-service", TOPIC_SHIPMENT,
+		`{"orderId": "123", "status": "shipped"}`))
+	add(kafka.NewContract[StockFailed]("orders-service", TOPIC_STOCK_FAILED,
+		`{"orderId": "124", "reason": "insufficient stock"}`))
+	add(kafka.NewContract[StockReleased]("orders-service", TOPIC_STOCK_RELEASED,
+		`{"orderId": "124", "released": [{"sku": "pro-street", "qty": 20}]}`))
+	add(kafka.NewContract[PaymentRefunded]("orders-service", TOPIC_PAYMENT_REFUNDED,
+		`{"orderId": "124", "refundId": "9f1c2a7e4b3d"}`))
+	return contracts
+}
+
+func TestConsumerContracts(t *testing.T) {
+	s, _ := startContractService(t)
+	contracts := consumerContracts(t)
+
+	handle := s.handlers(DefaultTopics())
+	for _, c := range contracts {
+		for _, m := range c.Messages() {
+			if err := handle[c.Topic](m.Value); err != nil {
+				t.Errorf("%s: %v", c.Topic, err)
+			}
+		}
+	}
+
+	for id, want := range map[string]Status{"123": STATUS_SHIPPED, "124": STATUS_REFUNDED} {
+		if o, err := s.orders.Get(context.Background(), id); err != nil || o.Status != want {
+			t.Errorf("order %s = %+v, %v, want %s", id, o, err, want)
+		}
+	}
+
+	if err := kafka.PublishContracts(CONTRACTS, *update, contracts...); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestProducerContracts(t *testing.T) {
+	s, broker := startContractService(t)
+
+	// Commande réservée, puis commande payée annulée faute de stock
+	items := []Item{{SKU: "pro-street", Qty: 2}}
+	for _, step := range []struct {
+		handle func([]byte) error
+		evt    any
+	}{
+		{s.handlePaymentDone, OrderPlaced{OrderID: "o-1", UserID: "u-1", Items: items, Total: 100}},
+		{s.handleStockReserved, StockReserved{OrderID: "o-1", Reserved: items}},
+		{s.handlePaymentDone, OrderPlaced{OrderID: "o-2", UserID: "u-1", Items: items, Total: 100}},
+		{s.handleStockFailed, StockFailed{OrderID: "o-2", Reason: "insufficient stock"}},
+	} {
+		value, err := kafka.JSON.Encode(step.evt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := step.handle(value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	topics := []string{TOPIC_ORDER_CANCELLED, TOPIC_NOTIFICATIONS, TOPIC_STATUS_CHANGED}
+	contracts, err := kafka.LoadContracts(CONTRACTS, topics...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var messages []kafka.Message
+	for _, topic := range topics {
+		messages = append(messages, broker.Messages(topic)...)
+	}
+	if len(broker.Messages(TOPIC_ORDER_CANCELLED)) != 1 {
+		t.Fatalf("%d order.cancelled, want 1", len(broker.Messages(TOPIC_ORDER_CANCELLED)))
+	}
+	if err := kafka.VerifyContracts(contracts, messages...); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,179 @@
+package orders
+
+import (
+	"slices"
+	"time"
+)
+
+// Type d'un événement du flux d'une commande
+type EventType string
+
+const (
+	EVENT_PLACED         EventType = "placed"
+	EVENT_PAID           EventType = "paid"
+	EVENT_RESERVED       EventType = "reserved"
+	EVENT_CANCELLED      EventType = "cancelled"
+	EVENT_SHIPPED        EventType = "shipped"
+	EVENT_DELIVERED      EventType = "delivered"
+	EVENT_REFUNDED       EventType = "refunded"
+	EVENT_STOCK_RELEASED EventType = "stock_released"
+	// Commande enregistrée avant l'event sourcing: son état devient le
+	// premier événement de son flux
+	EVENT_IMPORTED EventType = "imported"
+)
+
+// Statut visé par les événements de transition
+var eventStatus = map[EventType]Status{
+	EVENT_PAID:      STATUS_PAID,
+	EVENT_RESERVED:  STATUS_RESERVED,
+	EVENT_CANCELLED: STATUS_CANCELLED,
+	EVENT_SHIPPED:   STATUS_SHIPPED,
+	EVENT_DELIVERED: STATUS_DELIVERED,
+	EVENT_REFUNDED:  STATUS_REFUNDED,
+}
+
+// Événement du domaine, ajouté au flux de sa commande sans jamais être
+// modifié. Seuls les champs de son type sont renseignés.
+type Event struct {
+	OrderID string `json:"orderId" bson:"orderId"`
+	// Position dans le flux, à partir de 1
+	Seq  int64     `json:"seq" bson:"seq"`
+	Type EventType `json:"type" bson:"type"`
+	At   time.Time `json:"at" bson:"at"`
+
+	// paid
+	UserID string  `json:"userId,omitempty" bson:"userId,omitempty"`
+	Items  []Item  `json:"items,omitempty" bson:"items,omitempty"`
+	Total  float64 `json:"total,omitempty" bson:"total,omitempty"`
+	// reserved
+	Reserved []Item `json:"reserved,omitempty" bson:"reserved,omitempty"`
+	// stock_released
+	Released []Item `json:"released,omitempty" bson:"released,omitempty"`
+	// cancelled, refunded
+	Reason string `json:"reason,omitempty" bson:"reason,omitempty"`
+	// imported
+	Snapshot *Order `json:"snapshot,omitempty" bson:"snapshot,omitempty"`
+}
+
+// Status retourne le statut visé par l'événement, ok=false s'il ne change
+// que des données
+func (e Event) Status() (status Status, ok bool) {
+	status, ok = eventStatus[e.Type]
+	return
+}
+
+// Apply fait évoluer la commande selon l'événement. Une transition invalide
+// laisse le statut inchangé mais les données de l'événement sont conservées:
+// un payment.done reçu après stock.reserve complète la commande.
+func (o *Order) Apply(e Event) {
+	switch e.Type {
+	case EVENT_IMPORTED:
+		*o = e.Snapshot.clone()
+	case EVENT_PLACED:
+		*o = *NewOrder(e.OrderID, e.At)
+	case EVENT_PAID:
+		at := e.At
+		o.UserID = e.UserID
+		o.Items = e.Items
+		o.Total = e.Total
+		o.PaidAt = &at
+	case EVENT_RESERVED:
+		o.Reserved = e.Reserved
+	case EVENT_STOCK_RELEASED:
+		o.Released = e.Released
+	}
+
+	if to, ok := e.Status(); ok {
+		_, _, _ = o.Transition(to, e.Reason, e.At)
+	}
+	o.Version = e.Seq
+}
+
+// Fold reconstruit la commande à partir de son flux, nil si le flux est vide
+func Fold(events []Event) *Order {
+	if len(events) == 0 {
+		return nil
+	}
+
+	o := &Order{}
+	for _, e := range events {
+		o.Apply(e)
+	}
+	return o
+}
+
+// Changes indique si l'événement modifie la commande. Un événement relu
+// (même statut, mêmes données) n'est pas ajouté une seconde fois au flux.
+func (e Event) Changes(o *Order) bool {
+	next := o.clone()
+	next.Apply(e)
+
+	return next.Status != o.Status ||
+		len(next.History) != len(o.History) ||
+		next.UserID != o.UserID ||
+		next.Total != o.Total ||
+		(next.PaidAt == nil) != (o.PaidAt == nil) ||
+		!slices.Equal(next.Items, o.Items) ||
+		!slices.Equal(next.Reserved, o.Reserved) ||
+		!slices.Equal(next.Released, o.Released)
+}
+
+// decideEvents replie le flux, y ajoute first (création de la commande) puis
+// les événements retournés par decide. Retourne l'état obtenu, les
+// changements de statut ajoutés à l'historique et les événements à
+// enregistrer, numérotés à la suite du flux. Si decide ne retourne rien,
+// rien n'est à enregistrer, pas même first.
+func decideEvents(orderID string, stream, first []Event, decide func(o *Order) ([]Event, error)) (*Order, []StatusChange, []Event, error) {
+	var state Order
+	if o := Fold(stream); o != nil {
+		state = *o
+	}
+
+	before := len(state.History)
+	seq := state.Version
+	now := time.Now().UTC()
+
+	var events []Event
+	add := func(e Event) {
+		seq++
+		e.OrderID = orderID
+		e.Seq = seq
+		if e.At.IsZero() {
+			e.At = now
+		}
+		state.Apply(e)
+		events = append(events, e)
+	}
+
+	for _, e := range first {
+		add(e)
+	}
+	if len(first) > 0 {
+		// Nouvelle commande: tout l'historique est nouveau
+		before = 0
+	}
+
+	// decide reçoit une copie: seuls les événements retournés comptent
+	view := state.clone()
+	decided, err := decide(&view)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if len(decided) == 0 {
+		return &state, nil, nil, nil
+	}
+
+	for _, e := range decided {
+		add(e)
+	}
+	return &state, state.History[before:], events, nil
+}
+
+// firstEvent ouvre le flux d'une commande: son état enregistré avant l'event
+// sourcing s'il existe, sa création sinon
+func firstEvent(legacy *Order) Event {
+	if legacy != nil {
+		return Event{Type: EVENT_IMPORTED, Snapshot: legacy}
+	}
+	return Event{Type: EVENT_PLACED}
+}
@@ -11,4 +11,6 @@ db.createUser({
 });
 
 db.createCollection("orders");
-db.orders.createIndex({ userId: 1 });
\ No newline at end of file
+db.orders.createIndex({ orderId: 1 }, { unique: true });
+db.orders.createIndex({ userId: 1, createdAt: -1 });
+db.orders.createIndex({ status: 1, createdAt: -1 });
\ No newline at end of file
@@ -0,0 +1,231 @@
+package orders
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryStore garde les flux d'événements et les projections en mémoire,
+// pour les tests. Les commandes retournées sont des copies: les modifier ne
+// change pas le store.
+type MemoryStore struct {
+	mu     sync.Mutex
+	events map[string][]Event
+	// Projections
+	orders map[string]Order
+	users  map[string]UserSummary
+	sales  map[string]DailySales
+}
+
+func NewMemoryStore() *MemoryStore {
+	s := &MemoryStore{events: make(map[string][]Event)}
+	s.reset()
+	return s
+}
+
+// Called with the lock held.
+func (s *MemoryStore) reset() {
+	s.orders = make(map[string]Order)
+	s.users = make(map[string]UserSummary)
+	s.sales = make(map[string]DailySales)
+}
+
+func (s *MemoryStore) EnsureIndexes(ctx context.Context) error {
+	return nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, orderID string) (*Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o, ok := s.orders[orderID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	o = o.clone()
+	return &o, nil
+}
+
+// List applique les mêmes filtres, tri et curseur qu'OrderStore
+func (s *MemoryStore) List(ctx context.Context, q OrderQuery) ([]Order, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	orders := []Order{}
+	for _, o := range s.orders {
+		if q.UserID != "" && o.UserID != q.UserID {
+			continue
+		}
+		if len(q.Statuses) > 0 && !slices.Contains(q.Statuses, o.Status) {
+			continue
+		}
+		if !q.From.IsZero() && o.CreatedAt.Before(q.From) {
+			continue
+		}
+		if !q.To.IsZero() && !o.CreatedAt.Before(q.To) {
+			continue
+		}
+		if q.After != nil && !q.after(o) {
+			continue
+		}
+		orders = append(orders, o.clone())
+	}
+
+	slices.SortFunc(orders, q.compare)
+
+	next := ""
+	if len(orders) > q.Limit {
+		orders = orders[:q.Limit]
+		next = encodeCursor(q.Sort, orders[q.Limit-1])
+	}
+
+	return orders, next, nil
+}
+
+// Append ajoute au flux les événements décidés par decide, sous le verrou
+// du store: pas d'écriture concurrente possible
+func (s *MemoryStore) Append(ctx context.Context, orderID string, decide func(o *Order) ([]Event, error)) (*Order, []StatusChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stream := s.events[orderID]
+
+	var first []Event
+	if len(stream) == 0 {
+		first = []Event{firstEvent(nil)}
+	}
+
+	o, changes, events, err := decideEvents(orderID, stream, first, decide)
+	if err != nil || len(events) == 0 {
+		return o, nil, err
+	}
+
+	s.events[orderID] = append(stream, events...)
+	s.project(Fold(stream), events)
+
+	return o, changes, nil
+}
+
+// project met à jour les projections. Called with the lock held.
+func (s *MemoryStore) project(o *Order, events []Event) {
+	var state Order
+	if o != nil {
+		state = *o
+	}
+
+	replay(&state, events, func(before, after *Order, e Event) {
+		user, sales := project(before, after, e)
+		if user != nil {
+			u := s.users[user.UserID]
+			u.UserID = user.UserID
+			u.add(*user)
+			s.users[user.UserID] = u
+		}
+		for _, d := range sales {
+			x := s.sales[d.Day]
+			x.Day = d.Day
+			x.add(d)
+			s.sales[d.Day] = x
+		}
+	})
+
+	s.orders[state.OrderID] = state.clone()
+}
+
+func (s *MemoryStore) Events(ctx context.Context, orderID string) ([]Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.events[orderID]), nil
+}
+
+func (s *MemoryStore) UserSummary(ctx context.Context, userID string) (*UserSummary, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[userID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	u.OrderIDs = slices.Clone(u.OrderIDs)
+	u.Statuses = maps.Clone(u.Statuses)
+	u.normalize()
+	return &u, nil
+}
+
+func (s *MemoryStore) DailySales(ctx context.Context, from, to time.Time) ([]DailySales, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sales := []DailySales{}
+	for _, d := range s.sales {
+		if (!from.IsZero() && d.Day < day(from)) || (!to.IsZero() && d.Day >= day(to)) {
+			continue
+		}
+		d.Items = maps.Clone(d.Items)
+		sales = append(sales, d)
+	}
+
+	slices.SortFunc(sales, func(a, b DailySales) int { return strings.Compare(a.Day, b.Day) })
+	return sales, nil
+}
+
+// Rebuild reconstruit les projections en rejouant tous les flux
+func (s *MemoryStore) Rebuild(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reset()
+	for _, stream := range s.events {
+		s.project(nil, stream)
+	}
+	return nil
+}
+
+// compare ordonne deux commandes selon q.Sort puis orderId
+func (q OrderQuery) compare(a, b Order) int {
+	var c int
+	switch q.Sort {
+	case "createdAt":
+		c = a.CreatedAt.Compare(b.CreatedAt)
+	case "updatedAt":
+		c = a.UpdatedAt.Compare(b.UpdatedAt)
+	case "total":
+		c = cmp.Compare(a.Total, b.Total)
+	}
+	if c == 0 {
+		c = strings.Compare(a.OrderID, b.OrderID)
+	}
+	if q.Desc {
+		c = -c
+	}
+	return c
+}
+
+// after indique si la commande suit le curseur dans l'ordre de tri
+func (q OrderQuery) after(o Order) bool {
+	var c int
+	switch v := q.After.Value.(type) {
+	case time.Time:
+		at := o.CreatedAt
+		if q.Sort == "updatedAt" {
+			at = o.UpdatedAt
+		}
+		c = at.Compare(v)
+	case float64:
+		c = cmp.Compare(o.Total, v)
+	}
+	if c == 0 {
+		c = strings.Compare(o.OrderID, q.After.OrderID)
+	}
+	if q.Desc {
+		c = -c
+	}
+	return c > 0
+}
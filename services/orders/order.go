@@ -0,0 +1,142 @@
+package orders
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Statut d'une commande
+type Status string
+
+const (
+	STATUS_PENDING   Status = "pending"
+	STATUS_PAID      Status = "paid"
+	STATUS_RESERVED  Status = "reserved"
+	STATUS_SHIPPED   Status = "shipped"
+	STATUS_DELIVERED Status = "delivered"
+	STATUS_CANCELLED Status = "cancelled"
+	STATUS_REFUNDED  Status = "refunded"
+)
+
+// Transitions autorisées. pending → reserved est accepté car stock.reserved
+// peut être consommé avant payment.done (topics différents, sans ordre global).
+var transitions = map[Status][]Status{
+	STATUS_PENDING:   {STATUS_PAID, STATUS_RESERVED, STATUS_CANCELLED},
+	STATUS_PAID:      {STATUS_RESERVED, STATUS_CANCELLED, STATUS_REFUNDED},
+	STATUS_RESERVED:  {STATUS_SHIPPED, STATUS_CANCELLED},
+	STATUS_SHIPPED:   {STATUS_DELIVERED},
+	STATUS_DELIVERED: {STATUS_REFUNDED},
+	STATUS_CANCELLED: {STATUS_REFUNDED},
+	STATUS_REFUNDED:  {},
+}
+
+var (
+	ErrInvalidTransition = errors.New("invalid status transition")
+	ErrUnknownStatus     = errors.New("unknown status")
+	ErrForbidden         = errors.New("not the owner of the order")
+	// Commande connue par un événement arrivé avant payment.done, son
+	// propriétaire n'est pas encore connu
+	ErrNotPlaced = errors.New("order not placed yet")
+	// Message illisible ou incomplet, le relire n'y changera rien
+	ErrUnrecognized = errors.New("unrecognized")
+)
+
+func ParseStatus(s string) (Status, error) {
+	status := Status(s)
+	if _, ok := transitions[status]; !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownStatus, s)
+	}
+	return status, nil
+}
+
+func (s Status) CanTransition(to Status) bool {
+	for _, next := range transitions[s] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+type Item struct {
+	SKU string `json:"sku" bson:"sku"`
+	Qty int    `json:"qty" bson:"qty"`
+}
+
+// Entrée de l'historique des statuts
+type StatusChange struct {
+	From   Status    `json:"from,omitempty" bson:"from,omitempty"`
+	To     Status    `json:"to" bson:"to"`
+	Reason string    `json:"reason,omitempty" bson:"reason,omitempty"`
+	At     time.Time `json:"at" bson:"at"`
+}
+
+// Agrégat commande, état obtenu en repliant son flux d'événements (Fold)
+type Order struct {
+	OrderID  string  `json:"orderId" bson:"orderId"`
+	UserID   string  `json:"userId,omitempty" bson:"userId,omitempty"`
+	Items    []Item  `json:"items,omitempty" bson:"items,omitempty"`
+	Total    float64 `json:"total" bson:"total"`
+	Reserved []Item  `json:"reserved,omitempty" bson:"reserved,omitempty"`
+	// Stock rendu par inventories-service après annulation
+	Released []Item `json:"released,omitempty" bson:"released,omitempty"`
+	// Date de réception de payment.done, même si la commande était déjà annulée
+	PaidAt    *time.Time     `json:"paidAt,omitempty" bson:"paidAt,omitempty"`
+	Status    Status         `json:"status" bson:"status"`
+	History   []StatusChange `json:"history" bson:"history"`
+	CreatedAt time.Time      `json:"createdAt" bson:"createdAt"`
+	UpdatedAt time.Time      `json:"updatedAt" bson:"updatedAt"`
+	// Position du dernier événement appliqué (Event.Seq)
+	Version int64 `json:"-" bson:"version"`
+}
+
+// NewOrder crée une commande en attente, premier statut de l'historique
+func NewOrder(orderID string, at time.Time) *Order {
+	o := &Order{OrderID: orderID, CreatedAt: at}
+	o.History = []StatusChange{{To: STATUS_PENDING, At: at}}
+	o.Status = STATUS_PENDING
+	o.UpdatedAt = at
+	return o
+}
+
+// Transition applique un changement de statut et l'ajoute à l'historique.
+// Rejouer un événement déjà appliqué (même statut) ne change rien et
+// retourne ok=false sans erreur.
+func (o *Order) Transition(to Status, reason string, at time.Time) (change StatusChange, ok bool, err error) {
+	if o.Status == to {
+		return StatusChange{}, false, nil
+	}
+	if err := o.CheckTransition(to); err != nil {
+		return StatusChange{}, false, err
+	}
+
+	change = StatusChange{From: o.Status, To: to, Reason: reason, At: at}
+	o.History = append(o.History, change)
+	o.Status = to
+	o.UpdatedAt = at
+	return change, true, nil
+}
+
+// CheckTransition retourne ErrInvalidTransition si la commande ne peut pas
+// passer au statut to. Rester au même statut est toujours possible.
+func (o *Order) CheckTransition(to Status) error {
+	if o.Status != to && !o.Status.CanTransition(to) {
+		return fmt.Errorf("%w: %s → %s (order %s)", ErrInvalidTransition, o.Status, to, o.OrderID)
+	}
+	return nil
+}
+
+// clone copie la commande sans partager ses slices
+func (o Order) clone() Order {
+	o.Items = slices.Clone(o.Items)
+	o.Reserved = slices.Clone(o.Reserved)
+	o.Released = slices.Clone(o.Released)
+	o.History = slices.Clone(o.History)
+	if o.PaidAt != nil {
+		paidAt := *o.PaidAt
+		o.PaidAt = &paidAt
+	}
+	return o
+}
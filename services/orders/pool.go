@@ -0,0 +1,186 @@
+package orders
+
+import (
+	"context"
+	"eda-shared/kafka"
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"log"
+	"sync"
+	"time"
+)
+
+// Configuration du pool de traitement des événements
+type PoolConfig struct {
+	// Nombre de workers (ORDERS_WORKERS)
+	Workers int
+	// Messages en attente par worker avant de bloquer la lecture Kafka
+	QueueSize int
+	// Latence ajoutée à chaque message, pour les démos uniquement
+	// (ORDERS_SIMULATED_LATENCY, désactivée par défaut)
+	SimulatedLatency time.Duration
+	// Attente avant de retraiter un message en échec transitoire, doublée
+	// à chaque échec jusqu'à MaxBackoff
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+func DefaultPoolConfig() PoolConfig {
+	return PoolConfig{Workers: 8, QueueSize: 64, MinBackoff: 200 * time.Millisecond, MaxBackoff: 30 * time.Second}
+}
+
+type job struct {
+	message kafka.Message
+	handle  func([]byte) error
+	done    func()
+}
+
+// WorkerPool traite les événements en parallèle tout en conservant l'ordre
+// par commande: tous les messages d'un même orderId, quel que soit leur
+// topic, passent par le même worker, dans l'ordre de lecture.
+type WorkerPool struct {
+	config PoolConfig
+	queues []chan job
+	wg     sync.WaitGroup
+	// Fermé par Close, interrompt les reprises en cours
+	quit chan struct{}
+
+	// Messages soumis pas encore traités ni abandonnés, idle est fermé
+	// quand il n'en reste plus
+	mu      sync.Mutex
+	pending int
+	idle    chan struct{}
+}
+
+func NewWorkerPool(config PoolConfig) *WorkerPool {
+	config.MaxBackoff = max(config.MaxBackoff, config.MinBackoff)
+	p := &WorkerPool{config: config, queues: make([]chan job, config.Workers), quit: make(chan struct{})}
+
+	for i := range p.queues {
+		p.queues[i] = make(chan job, config.QueueSize)
+		p.wg.Add(1)
+		go p.worker(p.queues[i])
+	}
+
+	return p
+}
+
+// orderKey retourne l'orderId du message, ou sa clé Kafka à défaut
+func orderKey(m kafka.Message) string {
+	var evt struct {
+		OrderID string `json:"orderId"`
+	}
+	if err := json.Unmarshal(m.Value, &evt); err == nil && evt.OrderID != "" {
+		return evt.OrderID
+	}
+	return m.Key
+}
+
+// Submit place le message dans la file du worker de sa clé. Bloque si la
+// file est pleine, ce qui ralentit la lecture Kafka. done est appelé une
+// fois le message traité ou écarté, jamais pour un message abandonné en
+// cours de reprise par Close.
+func (p *WorkerPool) Submit(m kafka.Message, handle func([]byte) error, done func()) {
+	p.mu.Lock()
+	if p.pending == 0 {
+		p.idle = make(chan struct{})
+	}
+	p.pending++
+	p.mu.Unlock()
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(orderKey(m)))
+	p.queues[h.Sum32()%uint32(len(p.queues))] <- job{message: m, handle: handle, done: done}
+}
+
+func (p *WorkerPool) worker(queue chan job) {
+	defer p.wg.Done()
+
+	for j := range queue {
+		if p.config.SimulatedLatency > 0 {
+			time.Sleep(p.config.SimulatedLatency)
+		}
+
+		if p.handle(j) && j.done != nil {
+			j.done()
+		}
+		p.finish()
+	}
+}
+
+func (p *WorkerPool) finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.pending--
+	if p.pending == 0 {
+		close(p.idle)
+	}
+}
+
+// Wait attend que les messages déjà soumis soient traités (ou abandonnés
+// par Close), ou l'annulation de ctx
+func (p *WorkerPool) Wait(ctx context.Context) error {
+	p.mu.Lock()
+	if p.pending == 0 {
+		p.mu.Unlock()
+		return nil
+	}
+	idle := p.idle
+	p.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// permanent indique si retraiter le message ne peut pas réussir: message
+// illisible ou transition invalide
+func permanent(err error) bool {
+	return errors.Is(err, ErrUnrecognized) || errors.Is(err, ErrInvalidTransition)
+}
+
+// handle traite le message en le reprenant tant que l'erreur est
+// transitoire (base ou Kafka indisponible): le worker, et donc les
+// commandes qui lui reviennent, attend sans que l'offset soit commité. Un
+// message en erreur définitive est écarté pour ne pas bloquer sa
+// partition. Retourne false si Close interrompt les reprises.
+func (p *WorkerPool) handle(j job) bool {
+	backoff := p.config.MinBackoff
+
+	for attempt := 1; ; attempt++ {
+		err := j.handle(j.message.Value)
+		if err == nil {
+			return true
+		}
+
+		m := j.message
+		if permanent(err) {
+			log.Printf("Skipping message %s/%d@%d: %v\n", m.Topic, m.Partition, m.Offset, err)
+			return true
+		}
+
+		log.Printf("Error handling message %s/%d@%d (attempt %d), retrying in %s: %v\n", m.Topic, m.Partition, m.Offset, attempt, backoff, err)
+
+		select {
+		case <-time.After(backoff):
+		case <-p.quit:
+			return false
+		}
+		backoff = min(backoff*2, p.config.MaxBackoff)
+	}
+}
+
+// Close attend la fin des messages déjà soumis. Ceux en échec transitoire
+// sont abandonnés sans être commités, ils seront relus.
+func (p *WorkerPool) Close() {
+	close(p.quit)
+	for _, queue := range p.queues {
+		close(queue)
+	}
+	p.wg.Wait()
+}
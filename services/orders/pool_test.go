@@ -0,0 +1,214 @@
+package orders
+
+import (
+	"context"
+	"eda-shared/kafka"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitFor polls cond until it holds, failing the test after 5s.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestOffsetTrackerCommitsContiguous(t *testing.T) {
+	tracker := newOffsetTracker()
+
+	var a []*tracked
+	for offset := range 3 {
+		a = append(a, tracker.add(kafka.Message{Topic: "a", Partition: 0, Offset: int64(offset)}))
+	}
+	other := tracker.add(kafka.Message{Topic: "a", Partition: 1, Offset: 7})
+
+	// 1 is done before 0: nothing to commit yet
+	if m, ok := tracker.complete(a[1]); ok {
+		t.Errorf("committed %d before 0 was done", m.Offset)
+	}
+	// Other partitions don't wait
+	if m, ok := tracker.complete(other); !ok || m.Offset != 7 {
+		t.Errorf("partition 1 commit = %d, %t, want 7", m.Offset, ok)
+	}
+	// 0 unblocks 1
+	if m, ok := tracker.complete(a[0]); !ok || m.Offset != 1 {
+		t.Errorf("commit = %d, %t, want 1", m.Offset, ok)
+	}
+	if m, ok := tracker.complete(a[2]); !ok || m.Offset != 2 {
+		t.Errorf("commit = %d, %t, want 2", m.Offset, ok)
+	}
+}
+
+func TestWorkerPoolKeyOrdering(t *testing.T) {
+	pool := NewWorkerPool(PoolConfig{Workers: 4, QueueSize: 2})
+
+	var mu sync.Mutex
+	handled := make(map[string][]int)
+	var done sync.WaitGroup
+
+	for i := range 200 {
+		orderID := fmt.Sprint("order-", i%7)
+		m := kafka.Message{Topic: fmt.Sprint("topic-", i%3), Offset: int64(i), Value: fmt.Appendf(nil, `{"orderId": %q}`, orderID)}
+
+		done.Add(1)
+		pool.Submit(m, func([]byte) error {
+			time.Sleep(time.Duration(rand.IntN(200)) * time.Microsecond)
+			mu.Lock()
+			handled[orderID] = append(handled[orderID], i)
+			mu.Unlock()
+			return nil
+		}, done.Done)
+	}
+	done.Wait()
+	pool.Close()
+
+	for orderID, seq := range handled {
+		for j := 1; j < len(seq); j++ {
+			if seq[j] < seq[j-1] {
+				t.Errorf("%s handled out of order: %v", orderID, seq)
+				break
+			}
+		}
+	}
+	if len(handled) != 7 {
+		t.Errorf("%d orders handled, want 7", len(handled))
+	}
+}
+
+func TestWorkerPoolRetries(t *testing.T) {
+	pool := NewWorkerPool(PoolConfig{Workers: 1, QueueSize: 1, MinBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond})
+	defer pool.Close()
+
+	for _, tt := range []struct {
+		name     string
+		errs     []error
+		attempts int
+	}{
+		{"transient", []error{errors.New("mongo down"), ErrConflict, nil}, 3},
+		{"invalid transition", []error{fmt.Errorf("%w: paid → paid", ErrInvalidTransition)}, 1},
+		{"unrecognized", []error{fmt.Errorf("%w payment.done: {}", ErrUnrecognized)}, 1},
+	} {
+		var attempts int
+		done := make(chan struct{})
+		pool.Submit(kafka.Message{Key: tt.name}, func([]byte) error {
+			attempts++
+			return tt.errs[min(attempts, len(tt.errs))-1]
+		}, func() { close(done) })
+
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: not done after %d attempts", tt.name, attempts)
+		}
+		if attempts != tt.attempts {
+			t.Errorf("%s: %d attempts, want %d", tt.name, attempts, tt.attempts)
+		}
+	}
+}
+
+func TestWorkerPoolCloseAbandonsRetries(t *testing.T) {
+	pool := NewWorkerPool(PoolConfig{Workers: 1, QueueSize: 1, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond})
+
+	var attempts atomic.Int32
+	var done atomic.Bool
+	pool.Submit(kafka.Message{}, func([]byte) error {
+		attempts.Add(1)
+		return errors.New("mongo down")
+	}, func() { done.Store(true) })
+
+	waitFor(t, "3 attempts", func() bool { return attempts.Load() >= 3 })
+	pool.Close()
+
+	if done.Load() {
+		t.Error("message still failing was marked done")
+	}
+}
+
+func TestWorkerPoolWait(t *testing.T) {
+	pool := NewWorkerPool(PoolConfig{Workers: 2, QueueSize: 1, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond})
+
+	if err := pool.Wait(context.Background()); err != nil {
+		t.Fatalf("idle pool: %v", err)
+	}
+
+	release := make(chan struct{})
+	pool.Submit(kafka.Message{Key: "a"}, func([]byte) error { <-release; return nil }, nil)
+	// Abandoned by Close
+	pool.Submit(kafka.Message{Key: "b"}, func([]byte) error { return errors.New("mongo down") }, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := pool.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wait with a message in progress = %v", err)
+	}
+
+	close(release)
+	go pool.Close()
+	if err := pool.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConsumerCommitsAfterRetries(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	broker := kafka.NewMemoryBroker(1)
+	config := kafka.Config{Broker: broker}
+	producer, err := kafka.NewProducer[OrderPlaced](config, kafka.DefaultProducerConfig("payment.done"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer producer.Close()
+
+	// The store is down until healed
+	var healed atomic.Bool
+	var attempts atomic.Int32
+	handlers := map[string]func([]byte) error{
+		"payment.done": func([]byte) error {
+			attempts.Add(1)
+			if !healed.Load() {
+				return errors.New("mongo down")
+			}
+			return nil
+		},
+	}
+
+	pool := NewWorkerPool(PoolConfig{Workers: 2, QueueSize: 1, MinBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond})
+	defer pool.Close()
+	consumer := NewConsumer(ConsumerConfig{Kafka: config, GroupID: "orders-test", StartOffset: kafka.FIRST_OFFSET}, handlers, pool)
+	if err := consumer.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer consumer.Stop()
+
+	if err := producer.Send(ctx, "123", OrderPlaced{OrderID: "123"}); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, "3 attempts", func() bool { return attempts.Load() >= 3 })
+	offsets, err := consumer.Offsets(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if committed := offsets["payment.done"][0]; committed >= 0 {
+		t.Fatalf("offset %d committed while the handler fails", committed)
+	}
+
+	healed.Store(true)
+	if err := broker.WaitConsumed(ctx, "orders-test", "payment.done"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,132 @@
+package orders
+
+import (
+	"slices"
+	"time"
+)
+
+// Projection orders-by-user: commandes d'un utilisateur et montants
+type UserSummary struct {
+	UserID   string   `json:"userId" bson:"_id"`
+	Orders   int      `json:"orders" bson:"orders"`
+	OrderIDs []string `json:"orderIds" bson:"orderIds"`
+	// Nombre de commandes par statut actuel
+	Statuses    map[Status]int `json:"statuses" bson:"statuses"`
+	Paid        float64        `json:"paid" bson:"paid"`
+	Refunded    float64        `json:"refunded" bson:"refunded"`
+	LastOrderAt time.Time      `json:"lastOrderAt" bson:"lastOrderAt"`
+}
+
+// Projection daily-sales: paiements et remboursements d'un jour (UTC)
+type DailySales struct {
+	// AAAA-MM-JJ
+	Day      string  `json:"day" bson:"_id"`
+	Orders   int     `json:"orders" bson:"orders"`
+	Revenue  float64 `json:"revenue" bson:"revenue"`
+	Refunds  int     `json:"refunds" bson:"refunds"`
+	Refunded float64 `json:"refunded" bson:"refunded"`
+	// Quantités payées par SKU
+	Items map[string]int `json:"items" bson:"items"`
+}
+
+func day(t time.Time) string {
+	return t.UTC().Format(time.DateOnly)
+}
+
+// project calcule les incréments des projections dus à un événement, à
+// partir de la commande avant et après. Les incréments s'additionnent: les
+// projections reconstruites en rejouant les flux dans n'importe quel ordre
+// de commandes sont identiques.
+func project(before, after *Order, e Event) (user *UserSummary, sales []DailySales) {
+	paid := before.PaidAt == nil && after.PaidAt != nil
+	refunded := before.Status != STATUS_REFUNDED && after.Status == STATUS_REFUNDED
+
+	if after.UserID != "" {
+		u := UserSummary{UserID: after.UserID, Statuses: map[Status]int{}}
+
+		switch {
+		case before.UserID == "":
+			// Première apparition de l'utilisateur dans la commande
+			u.Orders = 1
+			u.OrderIDs = []string{after.OrderID}
+			u.LastOrderAt = after.CreatedAt
+			u.Statuses[after.Status]++
+		case before.Status != after.Status:
+			u.Statuses[before.Status]--
+			u.Statuses[after.Status]++
+		}
+
+		if paid {
+			u.Paid = after.Total
+		}
+		if refunded {
+			u.Refunded = after.Total
+		}
+
+		if u.Orders > 0 || len(u.Statuses) > 0 || u.Paid != 0 || u.Refunded != 0 {
+			user = &u
+		}
+	}
+
+	if paid {
+		d := DailySales{Day: day(*after.PaidAt), Orders: 1, Revenue: after.Total, Items: map[string]int{}}
+		for _, it := range after.Items {
+			d.Items[it.SKU] += it.Qty
+		}
+		sales = append(sales, d)
+	}
+	if refunded {
+		sales = append(sales, DailySales{Day: day(after.UpdatedAt), Refunds: 1, Refunded: after.Total})
+	}
+
+	return user, sales
+}
+
+// replay applique les événements à la commande en appelant apply avec
+// l'état avant et après chacun
+func replay(o *Order, events []Event, apply func(before, after *Order, e Event)) {
+	for _, e := range events {
+		before := o.clone()
+		o.Apply(e)
+		apply(&before, o, e)
+	}
+}
+
+func (s *UserSummary) add(d UserSummary) {
+	s.Orders += d.Orders
+	s.OrderIDs = append(s.OrderIDs, d.OrderIDs...)
+	if s.Statuses == nil {
+		s.Statuses = map[Status]int{}
+	}
+	for status, n := range d.Statuses {
+		s.Statuses[status] += n
+	}
+	s.Paid += d.Paid
+	s.Refunded += d.Refunded
+	if d.LastOrderAt.After(s.LastOrderAt) {
+		s.LastOrderAt = d.LastOrderAt
+	}
+}
+
+// normalize trie les commandes et retire les statuts sans commande
+func (s *UserSummary) normalize() {
+	slices.Sort(s.OrderIDs)
+	for status, n := range s.Statuses {
+		if n == 0 {
+			delete(s.Statuses, status)
+		}
+	}
+}
+
+func (d *DailySales) add(x DailySales) {
+	d.Orders += x.Orders
+	d.Revenue += x.Revenue
+	d.Refunds += x.Refunds
+	d.Refunded += x.Refunded
+	if d.Items == nil {
+		d.Items = map[string]int{}
+	}
+	for sku, n := range x.Items {
+		d.Items[sku] += n
+	}
+}
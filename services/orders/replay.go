@@ -0,0 +1,49 @@
+package orders
+
+import (
+	"context"
+	"eda-shared/kafka"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// ReplayConfig reconstruit les commandes à partir des topics consommés
+type ReplayConfig struct {
+	Kafka  kafka.Config
+	Store  Store
+	Topics Topics
+	// Tous les topics consommés si Replay.Topics est vide
+	Replay kafka.ReplayConfig
+}
+
+// Replay relit les topics consommés et passe les messages aux handlers en
+// mode reconstruction: les commandes sont écrites dans config.Store mais
+// rien n'est publié, et le consumer group du service ne bouge pas. Les
+// messages sont rejoués dans l'ordre de leur écriture, tous topics
+// confondus. Un message relu ne change pas une commande qui l'a déjà pris
+// en compte: rejouer deux fois la même période ne duplique rien.
+func Replay(ctx context.Context, config ReplayConfig) (kafka.ReplayStats, error) {
+	// Service sans producteurs ni consumer: rien n'est publié
+	s := &Service{orders: config.Store, replaying: true}
+	handle := s.handlers(config.Topics)
+
+	rc := config.Replay
+	if len(rc.Topics) == 0 {
+		rc.Topics = slices.Sorted(maps.Keys(handle))
+	}
+	for _, topic := range rc.Topics {
+		if _, ok := handle[topic]; !ok {
+			return kafka.ReplayStats{}, fmt.Errorf("topic %s is not consumed by orders-service", topic)
+		}
+	}
+
+	if err := config.Store.EnsureIndexes(ctx); err != nil {
+		return kafka.ReplayStats{}, err
+	}
+
+	return kafka.Replay(ctx, config.Kafka, rc, func(ctx context.Context, m kafka.Message, _ json.RawMessage) error {
+		return handle[m.Topic](m.Value)
+	})
+}
@@ -0,0 +1,96 @@
+package orders
+
+import (
+	"context"
+	"eda-shared/kafka"
+	"testing"
+	"time"
+)
+
+func TestReplayRebuildsOrders(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	broker := kafka.NewMemoryBroker(2)
+	config := kafka.Config{Broker: broker}
+
+	send := func(topic string, value any) {
+		t.Helper()
+		producer, err := kafka.NewProducer[any](config, kafka.DefaultProducerConfig(topic))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer producer.Close()
+		if err := producer.Send(ctx, "o-1", value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	items := []Item{{SKU: "sku-1", Qty: 1}}
+	send(TOPIC_PAYMENT_DONE, OrderPlaced{OrderID: "o-1", UserID: "u-1", Items: items, Total: 10})
+	send(TOPIC_STOCK_RESERVED, StockReserved{OrderID: "o-1", Reserved: items})
+
+	store := NewMemoryStore()
+	rc := ReplayConfig{Kafka: config, Store: store, Topics: DefaultTopics(), Replay: kafka.DefaultReplayConfig()}
+
+	// Rejouer deux fois ne change rien
+	for range 2 {
+		stats, err := Replay(ctx, rc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.Handled != 2 {
+			t.Errorf("stats = %+v", stats)
+		}
+	}
+
+	o, err := store.Get(ctx, "o-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.Status != STATUS_RESERVED || o.UserID != "u-1" || len(o.Reserved) != 1 {
+		t.Errorf("rebuilt order = %+v", o)
+	}
+	if events, _ := store.Events(ctx, "o-1"); len(events) != 3 {
+		t.Errorf("%d events, want placed, paid, reserved", len(events))
+	}
+
+	for _, topic := range []string{TOPIC_STATUS_CHANGED, TOPIC_NOTIFICATIONS} {
+		if messages := broker.Messages(topic); len(messages) != 0 {
+			t.Errorf("replay published %d messages on %s", len(messages), topic)
+		}
+	}
+
+	rc.Replay.Topics = []string{TOPIC_STATUS_CHANGED}
+	if _, err := Replay(ctx, rc); err == nil {
+		t.Error("replayed a topic orders-service doesn't consume")
+	}
+}
+
+func TestReplayAlongsideService(t *testing.T) {
+	s, broker := startContractService(t)
+
+	// Le replay écrit dans son propre store sans rendre le service muet
+	rc := ReplayConfig{Kafka: kafka.Config{Broker: kafka.NewMemoryBroker(1)}, Store: NewMemoryStore(), Topics: DefaultTopics(), Replay: kafka.DefaultReplayConfig()}
+	if _, err := Replay(context.Background(), rc); err != nil {
+		t.Fatal(err)
+	}
+
+	value, err := kafka.JSON.Encode(OrderPlaced{OrderID: "o-1", UserID: "u-1", Total: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.handlePaymentDone(value); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.orders.Get(context.Background(), "o-1"); err != nil {
+		t.Errorf("order missing from the service store: %v", err)
+	}
+	if _, err := rc.Store.Get(context.Background(), "o-1"); err == nil {
+		t.Error("order written to the replay store")
+	}
+	if messages := broker.Messages(TOPIC_STATUS_CHANGED); len(messages) != 2 {
+		t.Errorf("%d status changes published, want pending and paid", len(messages))
+	}
+}
@@ -0,0 +1,441 @@
+package orders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Nombre d'essais quand une écriture concurrente modifie la même commande
+const MAX_UPDATE_ATTEMPTS = 5
+
+var (
+	ErrConflict = errors.New("order modified concurrently")
+	ErrNotFound = errors.New("order not found")
+)
+
+// Store enregistre le flux d'événements de chaque commande et les
+// projections qui en sont tirées: OrderStore sur MongoDB, MemoryStore pour
+// les tests. Les deux passent la même suite de conformité (store_test.go).
+type Store interface {
+	EnsureIndexes(ctx context.Context) error
+	// Get et List lisent la projection de l'état des commandes
+	Get(ctx context.Context, orderID string) (*Order, error)
+	List(ctx context.Context, q OrderQuery) ([]Order, string, error)
+	// Append passe l'état de la commande (pending si elle n'existe pas
+	// encore) à decide et ajoute à son flux les événements retournés. En
+	// cas d'écriture concurrente, decide est rappelé sur le flux à jour.
+	// Retourne l'état obtenu et les changements de statut ajoutés à
+	// l'historique. Si decide ne retourne aucun événement, rien n'est écrit.
+	Append(ctx context.Context, orderID string, decide func(o *Order) ([]Event, error)) (*Order, []StatusChange, error)
+	// Events retourne le flux de la commande, vide si elle n'existe pas
+	Events(ctx context.Context, orderID string) ([]Event, error)
+	// UserSummary lit la projection orders-by-user, ErrNotFound si
+	// l'utilisateur n'a pas de commande
+	UserSummary(ctx context.Context, userID string) (*UserSummary, error)
+	// DailySales lit la projection daily-sales des jours de [from, to), les
+	// bornes nulles ne limitent pas
+	DailySales(ctx context.Context, from, to time.Time) ([]DailySales, error)
+	// Rebuild efface les projections et les reconstruit en rejouant tous
+	// les flux. Les consumers doivent être arrêtés pendant la reconstruction.
+	Rebuild(ctx context.Context) error
+}
+
+// OrderStore enregistre les flux et les projections dans MongoDB. Les
+// événements sont ajoutés sans transaction puis les projections mises à
+// jour: après une erreur entre les deux, Rebuild les remet en cohérence.
+type OrderStore struct {
+	// Projection de l'état, un document par orderId
+	orders *mongo.Collection
+	events *mongo.Collection
+	users  *mongo.Collection
+	sales  *mongo.Collection
+}
+
+func NewOrderStore(db *mongo.Database) *OrderStore {
+	return &OrderStore{
+		orders: db.Collection("orders"),
+		events: db.Collection("order_events"),
+		users:  db.Collection("orders_by_user"),
+		sales:  db.Collection("daily_sales"),
+	}
+}
+
+// EnsureIndexes crée l'index unique du flux (un seul événement par position,
+// ce qui détecte les écritures concurrentes), l'index unique sur orderId de
+// la projection et les index des filtres de GET /orders
+func (s *OrderStore) EnsureIndexes(ctx context.Context) error {
+	_, err := s.events.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "orderId", Value: 1}, {Key: "seq", Value: 1}}, Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = s.orders.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "orderId", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: -1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "createdAt", Value: -1}}},
+	})
+	return err
+}
+
+// Commandes enregistrées avant le suivi de statut: elles n'étaient créées
+// qu'à la réservation du stock
+func normalize(o *Order) {
+	if o.Status == "" {
+		o.Status = STATUS_RESERVED
+		o.History = []StatusChange{{To: STATUS_RESERVED, At: o.CreatedAt}}
+	}
+}
+
+// Get retourne la commande ou ErrNotFound
+func (s *OrderStore) Get(ctx context.Context, orderID string) (*Order, error) {
+	o, err := s.load(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if o == nil {
+		return nil, ErrNotFound
+	}
+	return o, nil
+}
+
+// List retourne une page de commandes et le curseur de la page suivante
+// (vide s'il n'y en a plus). Le tri se fait sur q.Sort puis orderId, ce qui
+// rend le curseur stable même si plusieurs commandes ont la même valeur.
+func (s *OrderStore) List(ctx context.Context, q OrderQuery) ([]Order, string, error) {
+	filter := bson.M{}
+	if q.UserID != "" {
+		filter["userId"] = q.UserID
+	}
+	if len(q.Statuses) > 0 {
+		filter["status"] = bson.M{"$in": q.Statuses}
+	}
+
+	created := bson.M{}
+	if !q.From.IsZero() {
+		created["$gte"] = q.From
+	}
+	if !q.To.IsZero() {
+		created["$lt"] = q.To
+	}
+	if len(created) > 0 {
+		filter["createdAt"] = created
+	}
+
+	direction, op := 1, "$gt"
+	if q.Desc {
+		direction, op = -1, "$lt"
+	}
+
+	if q.After != nil {
+		filter["$or"] = bson.A{
+			bson.M{q.Sort: bson.M{op: q.After.Value}},
+			bson.M{q.Sort: q.After.Value, "orderId": bson.M{op: q.After.OrderID}},
+		}
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: q.Sort, Value: direction}, {Key: "orderId", Value: direction}}).
+		SetLimit(int64(q.Limit + 1))
+
+	cur, err := s.orders.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	orders := []Order{}
+	if err := cur.All(ctx, &orders); err != nil {
+		return nil, "", err
+	}
+	for i := range orders {
+		normalize(&orders[i])
+	}
+
+	next := ""
+	if len(orders) > q.Limit {
+		orders = orders[:q.Limit]
+		next = encodeCursor(q.Sort, orders[q.Limit-1])
+	}
+
+	return orders, next, nil
+}
+
+// load lit la projection de la commande, ou nil si elle n'existe pas encore
+func (s *OrderStore) load(ctx context.Context, orderID string) (*Order, error) {
+	var o Order
+	err := s.orders.FindOne(ctx, bson.M{"orderId": orderID}).Decode(&o)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	normalize(&o)
+	return &o, nil
+}
+
+func (s *OrderStore) Append(ctx context.Context, orderID string, decide func(o *Order) ([]Event, error)) (*Order, []StatusChange, error) {
+	for attempt := 0; attempt < MAX_UPDATE_ATTEMPTS; attempt++ {
+		stream, err := s.Events(ctx, orderID)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var first []Event
+		if len(stream) == 0 {
+			legacy, err := s.load(ctx, orderID)
+			if err != nil {
+				return nil, nil, err
+			}
+			first = []Event{firstEvent(legacy)}
+		}
+
+		o, changes, events, err := decideEvents(orderID, stream, first, decide)
+		if err != nil || len(events) == 0 {
+			return o, nil, err
+		}
+
+		docs := make([]any, 0, len(events))
+		for _, e := range events {
+			docs = append(docs, e)
+		}
+
+		// Une autre écriture a pris la position: on recommence sur le flux à jour
+		_, err = s.events.InsertMany(ctx, docs)
+		if mongo.IsDuplicateKeyError(err) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// Les événements sont enregistrés, une projection en retard sera
+		// corrigée par Rebuild
+		if err := s.project(ctx, Fold(stream), events); err != nil {
+			log.Printf("Error updating projections of order %s: %v\n", orderID, err)
+		}
+
+		return o, changes, nil
+	}
+
+	return nil, nil, fmt.Errorf("%w: %s", ErrConflict, orderID)
+}
+
+// project applique les événements aux projections à partir de l'état o
+// (nil pour une nouvelle commande)
+func (s *OrderStore) project(ctx context.Context, o *Order, events []Event) error {
+	var state Order
+	if o != nil {
+		state = *o
+	}
+
+	var err error
+	imported := false
+	replay(&state, events, func(before, after *Order, e Event) {
+		imported = imported || e.Type == EVENT_IMPORTED
+
+		user, sales := project(before, after, e)
+		if user != nil && err == nil {
+			err = s.addUser(ctx, *user)
+		}
+		for _, d := range sales {
+			if err == nil {
+				err = s.addSales(ctx, d)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	// Le document importé est remplacé quelle que soit sa version
+	return s.saveState(ctx, &state, imported)
+}
+
+// saveState enregistre l'état de la commande sauf si une version plus
+// récente a déjà été projetée
+func (s *OrderStore) saveState(ctx context.Context, o *Order, force bool) error {
+	filter := bson.M{"orderId": o.OrderID}
+	if !force {
+		filter["$or"] = bson.A{
+			bson.M{"version": bson.M{"$lt": o.Version}},
+			bson.M{"version": bson.M{"$exists": false}},
+		}
+	}
+
+	_, err := s.orders.ReplaceOne(ctx, filter, o, options.Replace().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	return err
+}
+
+func (s *OrderStore) addUser(ctx context.Context, d UserSummary) error {
+	inc := bson.M{"orders": d.Orders, "paid": d.Paid, "refunded": d.Refunded}
+	for status, n := range d.Statuses {
+		inc["statuses."+string(status)] = n
+	}
+
+	update := bson.M{"$inc": inc}
+	if len(d.OrderIDs) > 0 {
+		update["$addToSet"] = bson.M{"orderIds": bson.M{"$each": d.OrderIDs}}
+	}
+	if !d.LastOrderAt.IsZero() {
+		update["$max"] = bson.M{"lastOrderAt": d.LastOrderAt}
+	}
+
+	_, err := s.users.UpdateOne(ctx, bson.M{"_id": d.UserID}, update, options.Update().SetUpsert(true))
+	return err
+}
+
+func (s *OrderStore) addSales(ctx context.Context, d DailySales) error {
+	inc := bson.M{"orders": d.Orders, "revenue": d.Revenue, "refunds": d.Refunds, "refunded": d.Refunded}
+	for sku, n := range d.Items {
+		inc["items."+sku] = n
+	}
+
+	_, err := s.sales.UpdateOne(ctx, bson.M{"_id": d.Day}, bson.M{"$inc": inc}, options.Update().SetUpsert(true))
+	return err
+}
+
+func (s *OrderStore) Events(ctx context.Context, orderID string) ([]Event, error) {
+	cur, err := s.events.Find(ctx, bson.M{"orderId": orderID}, options.Find().SetSort(bson.D{{Key: "seq", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	events := []Event{}
+	if err := cur.All(ctx, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (s *OrderStore) UserSummary(ctx context.Context, userID string) (*UserSummary, error) {
+	var u UserSummary
+	err := s.users.FindOne(ctx, bson.M{"_id": userID}).Decode(&u)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	u.normalize()
+	return &u, nil
+}
+
+func (s *OrderStore) DailySales(ctx context.Context, from, to time.Time) ([]DailySales, error) {
+	filter := bson.M{}
+	days := bson.M{}
+	if !from.IsZero() {
+		days["$gte"] = day(from)
+	}
+	if !to.IsZero() {
+		days["$lt"] = day(to)
+	}
+	if len(days) > 0 {
+		filter["_id"] = days
+	}
+
+	cur, err := s.sales.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	sales := []DailySales{}
+	if err := cur.All(ctx, &sales); err != nil {
+		return nil, err
+	}
+	return sales, nil
+}
+
+func (s *OrderStore) Rebuild(ctx context.Context) error {
+	if err := s.importLegacy(ctx); err != nil {
+		return err
+	}
+
+	for _, c := range []*mongo.Collection{s.orders, s.users, s.sales} {
+		if _, err := c.DeleteMany(ctx, bson.M{}); err != nil {
+			return err
+		}
+	}
+
+	cur, err := s.events.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "orderId", Value: 1}, {Key: "seq", Value: 1}}))
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+
+	// Les flux se suivent: chaque commande est projetée à la fin du sien
+	var stream []Event
+	flush := func() error {
+		if len(stream) == 0 {
+			return nil
+		}
+		err := s.project(ctx, nil, stream)
+		stream = nil
+		return err
+	}
+
+	for cur.Next(ctx) {
+		var e Event
+		if err := cur.Decode(&e); err != nil {
+			return err
+		}
+		if len(stream) > 0 && stream[0].OrderID != e.OrderID {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		stream = append(stream, e)
+	}
+	if err := cur.Err(); err != nil {
+		return err
+	}
+	return flush()
+}
+
+// importLegacy ouvre le flux des commandes enregistrées avant l'event
+// sourcing, pour qu'elles survivent à l'effacement des projections
+func (s *OrderStore) importLegacy(ctx context.Context) error {
+	cur, err := s.orders.Find(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var o Order
+		if err := cur.Decode(&o); err != nil {
+			return err
+		}
+
+		n, err := s.events.CountDocuments(ctx, bson.M{"orderId": o.OrderID})
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			continue
+		}
+
+		normalize(&o)
+		e := firstEvent(&o)
+		e.OrderID, e.Seq, e.At = o.OrderID, 1, time.Now().UTC()
+
+		_, err = s.events.InsertOne(ctx, e)
+		if err != nil && !mongo.IsDuplicateKeyError(err) {
+			return err
+		}
+	}
+	return cur.Err()
+}
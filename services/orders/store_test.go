@@ -0,0 +1,459 @@
+package orders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// testStore est la suite de conformité de Store: open retourne un store vide
+func testStore(t *testing.T, open func(t *testing.T) Store) {
+	ctx := context.Background()
+
+	// add ajoute un événement au flux de la commande
+	add := func(t *testing.T, s Store, orderID string, e Event) (*Order, []StatusChange) {
+		t.Helper()
+		o, changes, err := s.Append(ctx, orderID, func(o *Order) ([]Event, error) {
+			return []Event{e}, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return o, changes
+	}
+
+	// create enregistre une commande payée
+	create := func(t *testing.T, s Store, orderID, userID string, total float64) *Order {
+		t.Helper()
+		o, _ := add(t, s, orderID, Event{Type: EVENT_PAID, UserID: userID, Total: total})
+		return o
+	}
+
+	ids := func(orders []Order) []string {
+		var ids []string
+		for _, o := range orders {
+			ids = append(ids, o.OrderID)
+		}
+		return ids
+	}
+
+	types := func(events []Event) string {
+		var types []string
+		for _, e := range events {
+			types = append(types, fmt.Sprintf("%d:%s", e.Seq, e.Type))
+		}
+		return fmt.Sprint(types)
+	}
+
+	t.Run("GetUnknown", func(t *testing.T) {
+		s := open(t)
+		if _, err := s.Get(ctx, "unknown"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("err = %v, want ErrNotFound", err)
+		}
+		events, err := s.Events(ctx, "unknown")
+		if err != nil || len(events) != 0 {
+			t.Errorf("events = %v, %v", events, err)
+		}
+	})
+
+	t.Run("AppendCreatesPending", func(t *testing.T) {
+		s := open(t)
+
+		o, changes, err := s.Append(ctx, "o-1", func(o *Order) ([]Event, error) {
+			if o.Status != STATUS_PENDING {
+				t.Errorf("new order is %q", o.Status)
+			}
+			return []Event{{Type: EVENT_PAID, UserID: "u-1", Total: 10}}, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Création et transition: l'historique complet est nouveau
+		if len(changes) != 2 || changes[0].To != STATUS_PENDING || changes[1].To != STATUS_PAID {
+			t.Errorf("changes = %+v", changes)
+		}
+
+		got, err := s.Get(ctx, "o-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Status != STATUS_PAID || len(got.History) != 2 || got.Version != o.Version || got.PaidAt == nil {
+			t.Errorf("stored order = %+v", got)
+		}
+	})
+
+	t.Run("EventsAreNumbered", func(t *testing.T) {
+		s := open(t)
+		create(t, s, "o-1", "u-1", 10)
+		add(t, s, "o-1", Event{Type: EVENT_RESERVED, Reserved: []Item{{SKU: "sku-1", Qty: 1}}})
+
+		events, err := s.Events(ctx, "o-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := types(events); got != "[1:placed 2:paid 3:reserved]" {
+			t.Errorf("events = %s", got)
+		}
+
+		// Le flux replié donne la commande enregistrée
+		o, err := s.Get(ctx, "o-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		folded := Fold(events)
+		if folded.Status != o.Status || folded.Version != 3 || o.Version != 3 || len(folded.Reserved) != 1 {
+			t.Errorf("folded = %+v, stored = %+v", folded, o)
+		}
+	})
+
+	t.Run("AppendReturnsNewChanges", func(t *testing.T) {
+		s := open(t)
+		create(t, s, "o-1", "u-1", 10)
+
+		_, changes := add(t, s, "o-1", Event{Type: EVENT_RESERVED})
+		if len(changes) != 1 || changes[0].From != STATUS_PAID || changes[0].To != STATUS_RESERVED {
+			t.Errorf("changes = %+v", changes)
+		}
+	})
+
+	t.Run("NothingDecidedWritesNothing", func(t *testing.T) {
+		s := open(t)
+		none := func(o *Order) ([]Event, error) { return nil, nil }
+
+		// Pas même la création de la commande
+		if _, _, err := s.Append(ctx, "o-1", none); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.Get(ctx, "o-1"); !errors.Is(err, ErrNotFound) {
+			t.Error("empty decision created the order")
+		}
+
+		create(t, s, "o-2", "u-1", 10)
+		_, changes, err := s.Append(ctx, "o-2", none)
+		if err != nil || len(changes) != 0 {
+			t.Errorf("changes = %v, %v", changes, err)
+		}
+		if events, _ := s.Events(ctx, "o-2"); len(events) != 2 {
+			t.Errorf("events = %s", types(events))
+		}
+	})
+
+	t.Run("DecideErrorWritesNothing", func(t *testing.T) {
+		s := open(t)
+		create(t, s, "o-1", "u-1", 10)
+
+		failing := errors.New("failing")
+		decide := func(o *Order) ([]Event, error) {
+			o.Total = 99
+			return []Event{{Type: EVENT_CANCELLED}}, failing
+		}
+
+		if _, _, err := s.Append(ctx, "o-1", decide); !errors.Is(err, failing) {
+			t.Errorf("err = %v, want the decide error", err)
+		}
+		if o, _ := s.Get(ctx, "o-1"); o.Total != 10 || o.Status != STATUS_PAID {
+			t.Errorf("order = %+v after a failed append", o)
+		}
+
+		if _, _, err := s.Append(ctx, "o-2", decide); !errors.Is(err, failing) {
+			t.Errorf("err = %v, want the decide error", err)
+		}
+		if _, err := s.Get(ctx, "o-2"); !errors.Is(err, ErrNotFound) {
+			t.Error("failed append created the order")
+		}
+	})
+
+	t.Run("RedeliveredEventIsSkipped", func(t *testing.T) {
+		s := open(t)
+		paid := Event{Type: EVENT_PAID, UserID: "u-1", Total: 10}
+
+		for range 2 {
+			if _, _, err := s.Append(ctx, "o-1", func(o *Order) ([]Event, error) {
+				if !paid.Changes(o) {
+					return nil, nil
+				}
+				return []Event{paid}, nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if events, _ := s.Events(ctx, "o-1"); types(events) != "[1:placed 2:paid]" {
+			t.Errorf("events = %s", types(events))
+		}
+		if u, err := s.UserSummary(ctx, "u-1"); err != nil || u.Paid != 10 {
+			t.Errorf("summary = %+v, %v", u, err)
+		}
+	})
+
+	t.Run("ReturnedOrdersAreCopies", func(t *testing.T) {
+		s := open(t)
+		o := create(t, s, "o-1", "u-1", 10)
+
+		o.Total = 99
+		o.History[0].Reason = "changed"
+
+		got, err := s.Get(ctx, "o-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Total != 10 || got.History[0].Reason != "" {
+			t.Errorf("stored order changed through a returned one: %+v", got)
+		}
+	})
+
+	t.Run("ConcurrentAppends", func(t *testing.T) {
+		s := open(t)
+		create(t, s, "o-1", "u-1", 0)
+
+		// Chaque ajout est enregistré ou échoue en ErrConflict, aucun n'est
+		// perdu
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		applied := 0
+		for range 8 {
+			wg.Go(func() {
+				_, _, err := s.Append(ctx, "o-1", func(o *Order) ([]Event, error) {
+					return []Event{{Type: EVENT_PAID, UserID: o.UserID, Total: o.Total + 1}}, nil
+				})
+				if err != nil && !errors.Is(err, ErrConflict) {
+					t.Error(err)
+				}
+				if err == nil {
+					mu.Lock()
+					applied++
+					mu.Unlock()
+				}
+			})
+		}
+		wg.Wait()
+
+		o, err := s.Get(ctx, "o-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if int(o.Total) != applied {
+			t.Errorf("total = %v, want %d applied appends", o.Total, applied)
+		}
+		if events, _ := s.Events(ctx, "o-1"); len(events) != applied+2 {
+			t.Errorf("%d events for %d applied appends", len(events), applied)
+		}
+	})
+
+	t.Run("Projections", func(t *testing.T) {
+		s := open(t)
+		items := []Item{{SKU: "sku-1", Qty: 2}}
+		add(t, s, "o-1", Event{Type: EVENT_PAID, UserID: "u-1", Items: items, Total: 10})
+		add(t, s, "o-2", Event{Type: EVENT_PAID, UserID: "u-1", Items: items, Total: 30})
+		add(t, s, "o-2", Event{Type: EVENT_CANCELLED, Reason: CANCEL_REASON_USER})
+		add(t, s, "o-2", Event{Type: EVENT_REFUNDED})
+		add(t, s, "o-3", Event{Type: EVENT_PAID, UserID: "u-2", Total: 5})
+
+		u, err := s.UserSummary(ctx, "u-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.Orders != 2 || fmt.Sprint(u.OrderIDs) != "[o-1 o-2]" || u.Paid != 40 || u.Refunded != 30 ||
+			fmt.Sprint(u.Statuses) != "map[paid:1 refunded:1]" || u.LastOrderAt.IsZero() {
+			t.Errorf("summary = %+v", u)
+		}
+
+		if _, err := s.UserSummary(ctx, "unknown"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("err = %v, want ErrNotFound", err)
+		}
+
+		sales, err := s.DailySales(ctx, time.Time{}, time.Time{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		today := day(time.Now())
+		if len(sales) != 1 || sales[0].Day != today || sales[0].Orders != 3 || sales[0].Revenue != 45 ||
+			sales[0].Refunds != 1 || sales[0].Refunded != 30 || sales[0].Items["sku-1"] != 4 {
+			t.Errorf("sales = %+v", sales)
+		}
+
+		tomorrow := time.Now().Add(24 * time.Hour)
+		if sales, _ := s.DailySales(ctx, tomorrow, time.Time{}); len(sales) != 0 {
+			t.Errorf("sales from tomorrow = %+v", sales)
+		}
+		if sales, _ := s.DailySales(ctx, time.Time{}, tomorrow); len(sales) != 1 {
+			t.Errorf("sales until tomorrow = %+v", sales)
+		}
+	})
+
+	t.Run("RebuildMatchesIncremental", func(t *testing.T) {
+		s := open(t)
+		add(t, s, "o-1", Event{Type: EVENT_PAID, UserID: "u-1", Items: []Item{{SKU: "sku-1", Qty: 1}}, Total: 10})
+		add(t, s, "o-1", Event{Type: EVENT_RESERVED})
+		add(t, s, "o-2", Event{Type: EVENT_PAID, UserID: "u-1", Total: 20})
+		add(t, s, "o-2", Event{Type: EVENT_CANCELLED})
+		add(t, s, "o-2", Event{Type: EVENT_REFUNDED})
+
+		snapshot := func() string {
+			t.Helper()
+			u, err := s.UserSummary(ctx, "u-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			sales, err := s.DailySales(ctx, time.Time{}, time.Time{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			list, _, err := s.List(ctx, OrderQuery{Sort: "total", Limit: 10})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var orders []string
+			for _, o := range list {
+				orders = append(orders, fmt.Sprintf("%s:%s:%d", o.OrderID, o.Status, o.Version))
+			}
+			u.LastOrderAt = u.LastOrderAt.Truncate(time.Millisecond)
+			return fmt.Sprintf("%+v %+v %v", *u, sales, orders)
+		}
+
+		before := snapshot()
+		if err := s.Rebuild(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if after := snapshot(); after != before {
+			t.Errorf("rebuilt projections differ:\n%s\n%s", after, before)
+		}
+	})
+
+	t.Run("ListFilters", func(t *testing.T) {
+		s := open(t)
+		create(t, s, "o-1", "u-1", 10)
+		create(t, s, "o-2", "u-2", 20)
+		create(t, s, "o-3", "u-1", 30)
+		add(t, s, "o-3", Event{Type: EVENT_CANCELLED})
+
+		q := OrderQuery{Sort: "total", Limit: 10}
+
+		byUser := q
+		byUser.UserID = "u-1"
+		list, _, err := s.List(ctx, byUser)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := fmt.Sprint(ids(list)); got != "[o-1 o-3]" {
+			t.Errorf("orders of u-1 = %s", got)
+		}
+
+		byStatus := q
+		byStatus.Statuses = []Status{STATUS_PAID}
+		list, _, err = s.List(ctx, byStatus)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := fmt.Sprint(ids(list)); got != "[o-1 o-2]" {
+			t.Errorf("paid orders = %s", got)
+		}
+
+		future := q
+		future.From = time.Now().Add(time.Hour)
+		list, _, err = s.List(ctx, future)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 0 {
+			t.Errorf("orders created in the future = %v", ids(list))
+		}
+
+		past := q
+		past.To = time.Now().Add(time.Hour)
+		list, _, err = s.List(ctx, past)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 3 {
+			t.Errorf("orders created before now = %v", ids(list))
+		}
+	})
+
+	t.Run("ListPages", func(t *testing.T) {
+		s := open(t)
+		// Deux commandes de même total: orderId départage
+		create(t, s, "o-1", "u-1", 20)
+		create(t, s, "o-2", "u-1", 10)
+		create(t, s, "o-3", "u-1", 20)
+		create(t, s, "o-4", "u-1", 30)
+
+		for _, tc := range []struct {
+			desc bool
+			want string
+		}{
+			{false, "[o-2 o-1 o-3 o-4]"},
+			{true, "[o-4 o-3 o-1 o-2]"},
+		} {
+			q := OrderQuery{Sort: "total", Desc: tc.desc, Limit: 3}
+
+			var all []string
+			for pages := 0; ; pages++ {
+				if pages > 2 {
+					t.Fatal("too many pages")
+				}
+
+				list, next, err := s.List(ctx, q)
+				if err != nil {
+					t.Fatal(err)
+				}
+				all = append(all, ids(list)...)
+
+				if next == "" {
+					break
+				}
+				if q.After, err = decodeCursor(q.Sort, next); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if got := fmt.Sprint(all); got != tc.want {
+				t.Errorf("desc=%v: pages = %s, want %s", tc.desc, got, tc.want)
+			}
+		}
+	})
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, func(t *testing.T) Store {
+		return NewMemoryStore()
+	})
+}
+
+// TestOrderStore a besoin d'un serveur MongoDB, par exemple
+// MONGO_TEST_URI=mongodb://localhost:27017
+func TestOrderStore(t *testing.T) {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI not set")
+	}
+
+	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = client.Disconnect(context.Background())
+	})
+
+	testStore(t, func(t *testing.T) Store {
+		db := client.Database(fmt.Sprintf("orders_db_test_%d", time.Now().UnixNano()))
+		t.Cleanup(func() {
+			_ = db.Drop(context.Background())
+		})
+
+		s := NewOrderStore(db)
+		if err := s.EnsureIndexes(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		return s
+	})
+}
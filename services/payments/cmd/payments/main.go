@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	payments "eda-payments"
+	"eda-shared/env"
+	"eda-shared/kafka"
+	"log"
+	"net/http"
+)
+
+func main() {
+
+	config := payments.DefaultConfig()
+
+	var err error
+	config.Kafka, err = kafka.ConfigFromEnv("payments-service")
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	config.SigningKey = []byte(env.Get("JWT_SIGNING_KEY", string(config.SigningKey)))
+
+	service, err := payments.New(config)
+	if err != nil {
+		log.Fatalf("Error creating Kafka client: %v", err)
+	}
+	defer service.Close()
+
+	go func() {
+		if err := service.Run(context.Background()); err != nil {
+			log.Printf("Error consuming cancellations: %v", err)
+		}
+	}()
+
+	log.Println("Starting payment service...")
+
+	err = http.ListenAndServe(payments.PORT, service.Handler())
+
+	if err != nil {
+		log.Fatalf("Failed to start server: %v", err)
+	}
+}
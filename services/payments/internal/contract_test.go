@@ -0,0 +1,64 @@
+package internal
+
+import (
+	"context"
+	"eda-shared/kafka"
+	"flag"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the contracts published in ressources/contracts")
+
+// Contracts of the consumers, see kafka.Contract
+const CONTRACTS = "../../../ressources/contracts"
+
+func TestConsumerContracts(t *testing.T) {
+	// A paid order is refunded, the others are ignored
+	cancelled, err := kafka.NewContract[OrderCancelled]("payments-service", CANCELLED_TOPIC,
+		`{"orderId": "123", "userId": "456", "total": 100, "paid": true}`,
+		`{"orderId": "123", "paid": false}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := kafka.PublishContracts(CONTRACTS, *update, cancelled); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestProducerContracts(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	broker := kafka.NewMemoryBroker(1)
+	client, err := NewKafkaClient(kafka.Config{Broker: broker})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	order := NewOrder("alice", "pro-street", 2, 89.99)
+	if err := client.SendInventory(ctx, order); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.SendNotification(ctx, order); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Refund(ctx, OrderCancelled{OrderID: order.OrderID, UserID: order.UserID, Total: order.Total, Paid: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	topics := []string{INVENTORY_TOPIC, NOTIFICATION_TOPIC, REFUND_TOPIC}
+	contracts, err := kafka.LoadContracts(CONTRACTS, topics...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var messages []kafka.Message
+	for _, topic := range topics {
+		messages = append(messages, broker.Messages(topic)...)
+	}
+	if err := kafka.VerifyContracts(contracts, messages...); err != nil {
+		t.Error(err)
+	}
+}
@@ -2,92 +2,202 @@ package internal
 
 import (
 	"context"
-	"encoding/json"
-
-	"github.com/segmentio/kafka-go"
+	"eda-shared/kafka"
+	"log"
+	"math"
+	"sync"
+	"time"
 )
 
 const (
 	NOTIFICATION_TOPIC = "notifications.central"
 	INVENTORY_TOPIC    = "payment.done"
-	BROKER_ADDRESS     = "kafka:29092"
+	CANCELLED_TOPIC    = "order.cancelled"
+	REFUND_TOPIC       = "payment.refunded"
 	GROUP_ID           = "payment-group"
+
+	// Notification types
+	PAYMENT_PROCESSED = "payment.processed"
+	PAYMENT_REFUNDED  = "payment.refunded"
 )
 
 type Item struct {
-	SKU string `json:"sku"`
-	Qty int    `json:"qty"`
+	SKU string `json:"sku" example:"elite-deck"`
+	Qty int    `json:"qty" example:"2"`
 }
 
 type OrderPlaced struct {
+	OrderID string  `json:"orderId" example:"5f2b8c1e9a7d4e3f8b6a0c2d4e6f8a1b"`
+	UserID  string  `json:"userId" example:"456"`
+	Items   []Item  `json:"items"`
+	Total   float64 `json:"total" example:"259.98"`
+}
+
+// OrderCancelled is published by orders-service. Paid tells whether the
+// order was charged and needs a refund.
+type OrderCancelled struct {
 	OrderID string  `json:"orderId"`
 	UserID  string  `json:"userId"`
-	Items   []Item  `json:"items"`
 	Total   float64 `json:"total"`
+	Paid    bool    `json:"paid"`
+}
+
+type PaymentRefunded struct {
+	EventID   string    `json:"event_id" example:"payment.refunded:5f2b8c1e9a7d4e3f8b6a0c2d4e6f8a1b"`
+	OrderID   string    `json:"orderId" example:"5f2b8c1e9a7d4e3f8b6a0c2d4e6f8a1b"`
+	UserID    string    `json:"userId,omitempty" example:"456"`
+	RefundID  string    `json:"refundId" example:"9f1c2a7e4b3d"`
+	Amount    float64   `json:"amount" example:"259.98"`
+	Timestamp time.Time `json:"timestamp" example:"2025-06-01T10:00:00Z"`
 }
 
+// Notification is rendered by notifications-service from its type and params
 type Notification struct {
-	Action string `json:"action"`
+	// Unique per event, lets notifications-service drop redeliveries
+	EventID string         `json:"event_id" example:"9f1c2a7e4b3d"`
+	Type    string         `json:"type" example:"payment.processed"`
+	UserID  string         `json:"user_id,omitempty" example:"456"`
+	Params  map[string]any `json:"params,omitempty" example:"{\"orderId\":\"5f2b8c1e9a7d4e3f8b6a0c2d4e6f8a1b\",\"total\":259.98}"`
 }
 
 type KafkaClient struct {
-	notification *kafka.Writer
-	inventory    *kafka.Writer
+	notification *kafka.Producer[Notification]
+	inventory    *kafka.Producer[OrderPlaced]
+	refund       *kafka.Producer[PaymentRefunded]
+	cancelled    *kafka.Consumer
+
+	// Orders already refunded, order.cancelled may be redelivered
+	mu       *sync.Mutex
+	refunded map[string]bool
 }
 
-func NewKafkaClient() *KafkaClient {
-	inventory := &kafka.Writer{
-		Addr:  kafka.TCP(BROKER_ADDRESS),
-		Topic: INVENTORY_TOPIC,
-	}
-
-	notification := &kafka.Writer{
-		Addr:  kafka.TCP(BROKER_ADDRESS),
-		Topic: NOTIFICATION_TOPIC,
+func NewKafkaClient(config kafka.Config) (*KafkaClient, error) {
+	inventory, err := kafka.NewProducer[OrderPlaced](config, kafka.DefaultProducerConfig(INVENTORY_TOPIC))
+	if err != nil {
+		return nil, err
 	}
 
-	return &KafkaClient{
-		notification: notification,
-		inventory:    inventory,
+	notification, err := kafka.NewProducer[Notification](config, kafka.DefaultProducerConfig(NOTIFICATION_TOPIC))
+	if err != nil {
+		return nil, err
 	}
-}
 
-func sendKafkaMessage(writer *kafka.Writer, key string, value interface{}) error {
-	data, err := json.Marshal(value)
+	refund, err := kafka.NewProducer[PaymentRefunded](config, kafka.DefaultProducerConfig(REFUND_TOPIC))
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	msg := kafka.Message{
-		Key:   []byte(key),
-		Value: data,
+	cancelled, err := kafka.NewConsumer(config, kafka.DefaultConsumerConfig(GROUP_ID, CANCELLED_TOPIC))
+	if err != nil {
+		return nil, err
 	}
+	kafka.Consumes[OrderCancelled](config.Registry, GROUP_ID, CANCELLED_TOPIC)
 
-	err = writer.WriteMessages(context.TODO(), msg)
+	return &KafkaClient{
+		notification: notification,
+		inventory:    inventory,
+		refund:       refund,
+		cancelled:    cancelled,
+		mu:           &sync.Mutex{},
+		refunded:     make(map[string]bool),
+	}, nil
+}
 
-	if err != nil {
-		return err
+// Unit prices of the catalogue, as shown by the frontend
+func DefaultPrices() map[string]float64 {
+	return map[string]float64{
+		"pro-street":   89.99,
+		"elite-deck":   129.99,
+		"sunset-rider": 99.99,
+		"park-master":  109.99,
 	}
-
-	return nil
 }
 
-func (k KafkaClient) SendInventory(itemName string, itemQuantity int) error {
-	order := OrderPlaced{
-		OrderID: "123",
-		UserID:  "456",
+// NewOrder builds a new order paid for by userID, charged itemQuantity
+// times the unit price
+func NewOrder(userID, itemName string, itemQuantity int, price float64) OrderPlaced {
+	return OrderPlaced{
+		OrderID: kafka.NewID(),
+		UserID:  userID,
 		Items: []Item{
 			{SKU: itemName, Qty: itemQuantity},
 		},
-		Total: 100,
+		// Rounded to the cent
+		Total: math.Round(price*float64(itemQuantity)*100) / 100,
+	}
+}
+
+// SendInventory publishes payment.done, keyed by order so that every event
+// of an order lands on the same partition
+func (k KafkaClient) SendInventory(ctx context.Context, order OrderPlaced) error {
+	return k.inventory.Send(ctx, order.OrderID, order)
+}
+
+func (k KafkaClient) SendNotification(ctx context.Context, order OrderPlaced) error {
+	return k.notification.Send(ctx, order.OrderID, Notification{
+		EventID: kafka.NewID(),
+		Type:    PAYMENT_PROCESSED,
+		UserID:  order.UserID,
+		Params:  map[string]any{"orderId": order.OrderID, "total": order.Total},
+	})
+}
+
+// Refund issues the refund of a cancelled order once, then publishes
+// payment.refunded and notifies the user.
+func (k KafkaClient) Refund(ctx context.Context, evt OrderCancelled) error {
+	k.mu.Lock()
+	if k.refunded[evt.OrderID] {
+		k.mu.Unlock()
+		log.Printf("Order %s already refunded", evt.OrderID)
+		return nil
+	}
+	k.refunded[evt.OrderID] = true
+	k.mu.Unlock()
+
+	refund := PaymentRefunded{
+		EventID:   REFUND_TOPIC + ":" + evt.OrderID,
+		OrderID:   evt.OrderID,
+		UserID:    evt.UserID,
+		RefundID:  kafka.NewID(),
+		Amount:    evt.Total,
+		Timestamp: time.Now().UTC(),
 	}
-	return sendKafkaMessage(k.inventory, "payment_inventory", order)
+
+	if err := k.refund.Send(ctx, evt.OrderID, refund); err != nil {
+		k.mu.Lock()
+		delete(k.refunded, evt.OrderID)
+		k.mu.Unlock()
+		return err
+	}
+
+	return k.notification.Send(ctx, evt.OrderID, Notification{
+		EventID: refund.EventID,
+		Type:    PAYMENT_REFUNDED,
+		UserID:  evt.UserID,
+		Params:  map[string]any{"orderId": evt.OrderID, "amount": evt.Total},
+	})
 }
 
-func (k KafkaClient) SendNotification() error {
-	return sendKafkaMessage(k.notification, "payment_notification", Notification{Action: "Payment processed successfully"})
+// ConsumeCancellations refunds paid orders published on order.cancelled
+func (k KafkaClient) ConsumeCancellations(ctx context.Context) error {
+	return kafka.Run(ctx, k.cancelled, func(ctx context.Context, m kafka.Message, evt OrderCancelled) error {
+		if evt.OrderID == "" {
+			log.Printf("Unrecognized message: %s", string(m.Value))
+			return nil
+		}
+
+		if !evt.Paid {
+			log.Printf("Order %s cancelled before payment, nothing to refund", evt.OrderID)
+			return nil
+		}
+
+		return k.Refund(ctx, evt)
+	})
 }
 
 func (k KafkaClient) Close() {
-	k.Close()
+	_ = k.cancelled.Close()
+	_ = k.inventory.Close()
+	_ = k.notification.Close()
+	_ = k.refund.Close()
 }
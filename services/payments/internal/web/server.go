@@ -2,6 +2,7 @@ package web
 
 import (
 	"eda-payments/internal"
+	"eda-shared/auth"
 	"log"
 	"net/http"
 	"strconv"
@@ -10,10 +11,16 @@ import (
 
 type Server struct {
 	Kakfa *internal.KafkaClient
+	// Simulated before each payment
+	Delay time.Duration
+	// Key of the users-service tokens
+	SigningKey []byte
+	// Unit price of each item that can be paid for
+	Prices map[string]float64
 }
 
 func NewServer(kf *internal.KafkaClient) *Server {
-	return &Server{Kakfa: kf}
+	return &Server{Kakfa: kf, Prices: internal.DefaultPrices()}
 }
 
 func (s Server) Payment(w http.ResponseWriter, r *http.Request) {
@@ -22,6 +29,13 @@ func (s Server) Payment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The order belongs to the user of the users-service token
+	claims, err := auth.Verify(auth.BearerToken(r), s.SigningKey)
+	if err != nil {
+		http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+		return
+	}
+
 	itemName := r.FormValue("itemName")
 	itemQuantity := r.FormValue("itemQuantity")
 
@@ -32,24 +46,30 @@ func (s Server) Payment(w http.ResponseWriter, r *http.Request) {
 
 	itemQuantityInt, err := strconv.Atoi(itemQuantity)
 
-	if err != nil {
+	if err != nil || itemQuantityInt < 1 {
 		http.Error(w, "Invalid item quantity", http.StatusBadRequest)
 		return
 	}
 
-	// Get Token -> verify claims
+	price, ok := s.Prices[itemName]
+	if !ok {
+		http.Error(w, "Unknown item", http.StatusBadRequest)
+		return
+	}
 
 	// Simulate a delay to test the idempotence
-	time.Sleep(2 * time.Second)
+	time.Sleep(s.Delay)
+
+	order := internal.NewOrder(claims.Subject, itemName, itemQuantityInt, price)
 
-	err = s.Kakfa.SendInventory(itemName, itemQuantityInt)
+	err = s.Kakfa.SendInventory(r.Context(), order)
 
 	if err != nil {
 		http.Error(w, "Failed to send inventory message", http.StatusInternalServerError)
 		return
 	}
 
-	err = s.Kakfa.SendNotification()
+	err = s.Kakfa.SendNotification(r.Context(), order)
 
 	if err != nil {
 		http.Error(w, "Failed to send notification message", http.StatusInternalServerError)
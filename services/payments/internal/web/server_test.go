@@ -0,0 +1,134 @@
+package web
+
+import (
+	"eda-payments/internal"
+	"eda-shared/auth"
+	"eda-shared/kafka"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPaymentBelongsToTokenSubject(t *testing.T) {
+	broker := kafka.NewMemoryBroker(1)
+	client, err := internal.NewKafkaClient(kafka.Config{Broker: broker})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	key := []byte("test-key")
+	server := Server{Kakfa: client, SigningKey: key, Prices: internal.DefaultPrices()}
+
+	pay := func(token string) int {
+		form := url.Values{"itemName": {"pro-street"}, "itemQuantity": {"2"}}
+		r := httptest.NewRequest(http.MethodPost, "/pay", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	forged, err := auth.Sign([]byte("other-key"), "alice", "user", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, token := range map[string]string{"anonymous": "", "forged": forged} {
+		if status := pay(token); status != http.StatusUnauthorized {
+			t.Errorf("%s payment: status %d, want 401", name, status)
+		}
+	}
+	if n := len(broker.Messages(internal.INVENTORY_TOPIC)); n != 0 {
+		t.Fatalf("%d orders placed without valid token", n)
+	}
+
+	token, err := auth.Sign(key, "alice", "user", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := pay(token); status != http.StatusOK {
+		t.Fatalf("payment: status %d", status)
+	}
+
+	for _, topic := range []string{internal.INVENTORY_TOPIC, internal.NOTIFICATION_TOPIC} {
+		messages := broker.Messages(topic)
+		if len(messages) != 1 {
+			t.Fatalf("%d messages on %s, want 1", len(messages), topic)
+		}
+		var event struct {
+			UserID         string `json:"userId"`
+			NotificationTo string `json:"user_id"`
+		}
+		if err := messages[0].Decode(&event); err != nil {
+			t.Fatal(err)
+		}
+		if event.UserID != "alice" && event.NotificationTo != "alice" {
+			t.Errorf("%s: %s, want the order of alice", topic, messages[0].Value)
+		}
+	}
+}
+
+func TestPaymentCreatesOrder(t *testing.T) {
+	broker := kafka.NewMemoryBroker(1)
+	client, err := internal.NewKafkaClient(kafka.Config{Broker: broker})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	key := []byte("test-key")
+	server := NewServer(client)
+	server.SigningKey = key
+
+	token, err := auth.Sign(key, "alice", "user", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pay := func(item, quantity string) int {
+		form := url.Values{"itemName": {item}, "itemQuantity": {quantity}}
+		r := httptest.NewRequest(http.MethodPost, "/pay", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	for _, tc := range [][2]string{{"unknown-deck", "1"}, {"pro-street", "0"}} {
+		if status := pay(tc[0], tc[1]); status != http.StatusBadRequest {
+			t.Errorf("payment of %s x%s: status %d, want 400", tc[0], tc[1], status)
+		}
+	}
+
+	for range 2 {
+		if status := pay("pro-street", "2"); status != http.StatusOK {
+			t.Fatalf("payment: status %d", status)
+		}
+	}
+
+	// Each payment is a new order, keyed by its id and charged its price
+	messages := broker.Messages(internal.INVENTORY_TOPIC)
+	if len(messages) != 2 {
+		t.Fatalf("%d orders placed, want 2", len(messages))
+	}
+	ids := map[string]bool{}
+	for _, m := range messages {
+		var order internal.OrderPlaced
+		if err := m.Decode(&order); err != nil {
+			t.Fatal(err)
+		}
+		if order.OrderID == "" || m.Key != order.OrderID || order.Total != 179.98 {
+			t.Errorf("order %+v with key %q, want keyed by its id and a total of 179.98", order, m.Key)
+		}
+		ids[order.OrderID] = true
+	}
+	if len(ids) != 2 {
+		t.Errorf("both payments placed order %v", ids)
+	}
+}
@@ -0,0 +1,72 @@
+// Package payments is payments-service: it takes the payments, then
+// refunds the cancelled orders. cmd/payments runs it with the environment
+// configuration, tests run it in-process.
+package payments
+
+import (
+	"context"
+	"eda-payments/internal"
+	"eda-payments/internal/web"
+	"eda-shared/kafka"
+	"net/http"
+	"time"
+)
+
+const (
+	PORT = ":3002"
+)
+
+type Config struct {
+	Kafka kafka.Config
+	// Delay before each payment, to test the idempotence
+	Delay time.Duration
+	// Key of the users-service tokens
+	SigningKey []byte
+	// Unit price of each item, by SKU
+	Prices map[string]float64
+}
+
+func DefaultConfig() Config {
+	return Config{Delay: 2 * time.Second, SigningKey: []byte("signing-key"), Prices: internal.DefaultPrices()}
+}
+
+type Service struct {
+	client *internal.KafkaClient
+	mux    *http.ServeMux
+}
+
+func New(config Config) (*Service, error) {
+	if config.Kafka.Registry == nil {
+		config.Kafka.Registry = kafka.NewRegistry("payments-service")
+	}
+
+	client, err := internal.NewKafkaClient(config.Kafka)
+	if err != nil {
+		return nil, err
+	}
+
+	server := web.NewServer(client)
+	server.Delay = config.Delay
+	server.SigningKey = config.SigningKey
+	server.Prices = config.Prices
+
+	mux := http.NewServeMux()
+	mux.Handle("/", server)
+	mux.Handle("/topology", config.Kafka.Registry)
+	mux.Handle("/asyncapi", config.Kafka.Registry.AsyncAPIHandler())
+
+	return &Service{client, mux}, nil
+}
+
+func (s *Service) Handler() http.Handler {
+	return s.mux
+}
+
+// Run refunds the cancelled orders until ctx is cancelled.
+func (s *Service) Run(ctx context.Context) error {
+	return s.client.ConsumeCancellations(ctx)
+}
+
+func (s *Service) Close() {
+	s.client.Close()
+}
@@ -0,0 +1,81 @@
+// Package auth verifies the tokens users-service issues on /login, for the
+// services serving users: the token subject is the username, its role
+// grants admin routes.
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	ISSUER     = "users-service"
+	ROLE_ADMIN = "admin"
+)
+
+var (
+	ErrMissingToken = errors.New("missing token")
+	ErrNoSubject    = errors.New("token has no subject")
+)
+
+// Claims issued by users-service on /login. Subject is the username.
+type Claims struct {
+	Role string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+func (c Claims) Admin() bool {
+	return c.Role == ROLE_ADMIN
+}
+
+// Sign issues a token for subject as users-service does.
+func Sign(key []byte, subject, role string, expiresAt time.Time) (string, error) {
+	claims := Claims{
+		Role: role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   subject,
+			Issuer:    ISSUER,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(key)
+}
+
+// Verify checks that raw is a token of users-service signed with key, not
+// expired and with a subject, and returns its claims.
+func Verify(raw string, key []byte) (Claims, error) {
+	if raw == "" {
+		return Claims{}, ErrMissingToken
+	}
+
+	var claims Claims
+	_, err := jwt.ParseWithClaims(raw, &claims, func(t *jwt.Token) (any, error) {
+		return key, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(ISSUER),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	if claims.Subject == "" {
+		return Claims{}, ErrNoSubject
+	}
+
+	return claims, nil
+}
+
+// BearerToken returns the token of the Authorization header, "" without one.
+func BearerToken(r *http.Request) string {
+	if h := r.Header.Get("Authorization"); strings.HasPrefix(h, "Bearer ") {
+		return strings.TrimPrefix(h, "Bearer ")
+	}
+	return ""
+}
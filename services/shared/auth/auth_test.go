@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var key = []byte("test-key")
+
+func TestVerify(t *testing.T) {
+	token, err := Sign(key, "alice", ROLE_ADMIN, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := Verify(token, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "alice" || !claims.Admin() {
+		t.Errorf("claims = %+v, want admin alice", claims)
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	sign := func(claims jwt.Claims, method jwt.SigningMethod, k any) string {
+		t.Helper()
+		token, err := jwt.NewWithClaims(method, claims).SignedString(k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	valid := jwt.RegisteredClaims{Issuer: ISSUER, Subject: "alice", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))}
+	expired, foreign, anonymous, eternal := valid, valid, valid, valid
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	foreign.Issuer = "payments-service"
+	anonymous.Subject = ""
+	eternal.ExpiresAt = nil
+
+	for name, token := range map[string]string{
+		"missing":      "",
+		"garbage":      "not-a-token",
+		"wrong key":    sign(valid, jwt.SigningMethodHS256, []byte("other-key")),
+		"wrong method": sign(valid, jwt.SigningMethodHS512, key),
+		"expired":      sign(expired, jwt.SigningMethodHS256, key),
+		"issuer":       sign(foreign, jwt.SigningMethodHS256, key),
+		"no subject":   sign(anonymous, jwt.SigningMethodHS256, key),
+		"no expiry":    sign(eternal, jwt.SigningMethodHS256, key),
+	} {
+		if _, err := Verify(token, key); err == nil {
+			t.Errorf("%s token verified", name)
+		}
+	}
+
+	if _, err := Verify("", key); !errors.Is(err, ErrMissingToken) {
+		t.Errorf("err = %v, want ErrMissingToken", err)
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	if got := BearerToken(r); got != "" {
+		t.Errorf("token without header = %q", got)
+	}
+
+	r.Header.Set("Authorization", "Basic YWxpY2U6")
+	if got := BearerToken(r); got != "" {
+		t.Errorf("token of basic auth = %q", got)
+	}
+
+	r.Header.Set("Authorization", "Bearer abc")
+	if got := BearerToken(r); got != "abc" {
+		t.Errorf("token = %q, want abc", got)
+	}
+}
@@ -0,0 +1,78 @@
+// Command provision creates the Kafka topics of a topics file and
+// reconciles the existing ones with it:
+//
+//	provision -spec ressources/topics.yaml
+//
+// Missing topics are created, partitions added and topic configs set.
+// Nothing is deleted: topics without spec and drifts Kafka can't fix in
+// place (fewer partitions, replication) are only reported. With -check,
+// nothing changes and the command fails unless the broker matches the file.
+package main
+
+import (
+	"context"
+	"eda-shared/env"
+	"eda-shared/kafka"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+)
+
+// waitKafka retries until the broker answers or wait is over.
+func waitKafka(ctx context.Context, config kafka.Config, wait time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+
+	for {
+		_, err := kafka.Topics(ctx, config)
+		if err == nil {
+			return nil
+		}
+		log.Printf("Kafka not available yet: %v", err)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func main() {
+	spec := flag.String("spec", env.Get("TOPICS_SPEC", "ressources/topics.yaml"), "topics file")
+	dryRun := flag.Bool("dry-run", false, "report the changes without making them")
+	check := flag.Bool("check", false, "like -dry-run, and fail unless the topics match the file")
+	wait := flag.Duration("wait", 0, "wait that long for Kafka to be available")
+	flag.Parse()
+
+	specs, err := kafka.LoadTopicSpecs(*spec)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	config, err := kafka.ConfigFromEnv("provision")
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if *wait > 0 {
+		if err := waitKafka(ctx, config, *wait); err != nil {
+			log.Fatalln("Kafka not available: ", err)
+		}
+	}
+
+	report, err := kafka.Provision(ctx, config, specs, *dryRun || *check)
+	log.Println(report)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	if *check && !report.InSync() {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,86 @@
+// Command topology fetches GET /topology from running services and renders
+// the combined event graph:
+//
+//	topology -format mermaid http://localhost:3001 http://localhost:3003
+//
+// Formats: mermaid, dot (Graphviz), asyncapi (YAML) and json. Without URL
+// the services of docker-compose are queried on localhost.
+package main
+
+import (
+	"eda-shared/kafka"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Ports published by docker-compose
+var defaultURLs = []string{
+	"http://localhost:3000",
+	"http://localhost:3001",
+	"http://localhost:3002",
+	"http://localhost:3003",
+	"http://localhost:3004",
+	"http://localhost:3005",
+}
+
+func fetch(client *http.Client, base string) (kafka.Topology, error) {
+	var topology kafka.Topology
+
+	res, err := client.Get(base + "/topology")
+	if err != nil {
+		return topology, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return topology, fmt.Errorf("%s/topology: %s", base, res.Status)
+	}
+	err = json.NewDecoder(res.Body).Decode(&topology)
+	return topology, err
+}
+
+func main() {
+	format := flag.String("format", "mermaid", "mermaid, dot, asyncapi or json")
+	title := flag.String("title", "Skate shop events", "title of the AsyncAPI document")
+	flag.Parse()
+
+	urls := flag.Args()
+	if len(urls) == 0 {
+		urls = defaultURLs
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	var topologies []kafka.Topology
+	for _, base := range urls {
+		topology, err := fetch(client, base)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		topologies = append(topologies, topology)
+	}
+
+	switch *format {
+	case "mermaid":
+		fmt.Print(kafka.Mermaid(topologies...))
+	case "dot":
+		fmt.Print(kafka.Graphviz(topologies...))
+	case "asyncapi":
+		doc := kafka.NewAsyncAPI(kafka.AsyncAPIInfo{Title: *title, Version: kafka.ASYNCAPI_DOC_VERSION}, topologies...)
+		if err := doc.WriteYAML(os.Stdout); err != nil {
+			log.Fatalln(err)
+		}
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(topologies); err != nil {
+			log.Fatalln(err)
+		}
+	default:
+		log.Fatalf("unknown format %q", *format)
+	}
+}
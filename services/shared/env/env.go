@@ -0,0 +1,14 @@
+// Package env reads the settings of the services from environment
+// variables.
+package env
+
+import "os"
+
+// Get returns the environment variable key, or def when it is unset or
+// empty.
+func Get(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,18 @@
+package env
+
+import "testing"
+
+func TestGet(t *testing.T) {
+	t.Setenv("EDA_ENV_SET", "value")
+	t.Setenv("EDA_ENV_EMPTY", "")
+
+	for key, want := range map[string]string{
+		"EDA_ENV_SET":   "value",
+		"EDA_ENV_EMPTY": "default",
+		"EDA_ENV_UNSET": "default",
+	} {
+		if got := Get(key, "default"); got != want {
+			t.Errorf("Get(%s) = %q, want %q", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+module eda-shared
+
+go 1.25.2
+
+require (
+	github.com/golang-jwt/jwt/v5 v5.3.0
+	github.com/segmentio/kafka-go v0.4.49
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/klauspost/compress v1.15.9 // indirect
+	github.com/pierrec/lz4/v4 v4.1.15 // indirect
+	github.com/xdg-go/pbkdf2 v1.0.0 // indirect
+	github.com/xdg-go/scram v1.1.2 // indirect
+	github.com/xdg-go/stringprep v1.0.4 // indirect
+	golang.org/x/text v0.23.0 // indirect
+)
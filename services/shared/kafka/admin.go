@@ -0,0 +1,286 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// Offsets by topic and partition
+type Offsets map[string]map[int]int64
+
+// CommittedOffsets returns the offsets committed by a consumer group, -1
+// for the partitions without commit.
+func CommittedOffsets(ctx context.Context, config Config, groupID string, topics []string) (Offsets, error) {
+	return config.broker().CommittedOffsets(ctx, groupID, topics)
+}
+
+// ResetOffsets moves a consumer group to the first message at or after at
+// in every partition of the topics, or to the end of the partitions with
+// no such message. The group must have no active member: the coordinator
+// rejects commits made outside a generation otherwise.
+func ResetOffsets(ctx context.Context, config Config, groupID string, topics []string, at time.Time) (Offsets, error) {
+	return config.broker().ResetOffsets(ctx, groupID, topics, at)
+}
+
+// OffsetsAt returns the offset of the first message at or after at in every
+// partition of a topic, or the end offset of the partitions with no such
+// message. A zero at gives the offset of the oldest message kept.
+func OffsetsAt(ctx context.Context, config Config, topic string, at time.Time) (map[int]int64, error) {
+	return config.broker().OffsetsAt(ctx, topic, at)
+}
+
+// EndOffsets returns the offset the next message written to every partition
+// of a topic will get.
+func EndOffsets(ctx context.Context, config Config, topic string) (map[int]int64, error) {
+	return config.broker().EndOffsets(ctx, topic)
+}
+
+func (b kafkaBroker) CommittedOffsets(ctx context.Context, groupID string, topics []string) (Offsets, error) {
+	client := b.config.client()
+
+	offsets := make(Offsets)
+	for _, topic := range topics {
+		partitions, err := client.ConsumerOffsets(ctx, kafkago.TopicAndGroup{Topic: topic, GroupId: groupID})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", topic, err)
+		}
+		offsets[topic] = partitions
+	}
+	return offsets, nil
+}
+
+func (b kafkaBroker) ResetOffsets(ctx context.Context, groupID string, topics []string, at time.Time) (Offsets, error) {
+	client := b.config.client()
+
+	result, err := b.offsetsAt(ctx, client, topics, at)
+	if err != nil {
+		return nil, err
+	}
+
+	commits := make(map[string][]kafkago.OffsetCommit)
+	for topic, partitions := range result {
+		for p, offset := range partitions {
+			commits[topic] = append(commits[topic], kafkago.OffsetCommit{Partition: p, Offset: offset})
+		}
+	}
+
+	res, err := client.OffsetCommit(ctx, &kafkago.OffsetCommitRequest{
+		GroupID:      groupID,
+		GenerationID: -1,
+		Topics:       commits,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for topic, partitions := range res.Topics {
+		for _, p := range partitions {
+			if p.Error != nil {
+				return nil, fmt.Errorf("%s/%d: %w", topic, p.Partition, p.Error)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+func (b kafkaBroker) OffsetsAt(ctx context.Context, topic string, at time.Time) (map[int]int64, error) {
+	offsets, err := b.offsetsAt(ctx, b.config.client(), []string{topic}, at)
+	if err != nil {
+		return nil, err
+	}
+	return offsets[topic], nil
+}
+
+func (b kafkaBroker) EndOffsets(ctx context.Context, topic string) (map[int]int64, error) {
+	res, err := b.listOffsets(ctx, b.config.client(), []string{topic}, kafkago.LastOffsetOf)
+	if err != nil {
+		return nil, err
+	}
+
+	offsets := make(map[int]int64)
+	for _, p := range res[topic] {
+		offsets[p.Partition] = p.LastOffset
+	}
+	return offsets, nil
+}
+
+// offsetsAt implements OffsetsAt for several topics.
+func (b kafkaBroker) offsetsAt(ctx context.Context, client *kafkago.Client, topics []string, at time.Time) (Offsets, error) {
+	request := kafkago.FirstOffsetOf
+	if !at.IsZero() {
+		request = func(partition int) kafkago.OffsetRequest { return kafkago.TimeOffsetOf(partition, at) }
+	}
+
+	// Two requests: a partition can only appear once per request
+	byTime, err := b.listOffsets(ctx, client, topics, request)
+	if err != nil {
+		return nil, err
+	}
+	byEnd, err := b.listOffsets(ctx, client, topics, kafkago.LastOffsetOf)
+	if err != nil {
+		return nil, err
+	}
+
+	last := make(map[string]map[int]int64)
+	for topic, partitions := range byEnd {
+		last[topic] = make(map[int]int64)
+		for _, p := range partitions {
+			last[topic][p.Partition] = p.LastOffset
+		}
+	}
+
+	result := make(Offsets)
+	for topic, partitions := range byTime {
+		result[topic] = make(map[int]int64)
+		for _, p := range partitions {
+			// -1 when no message is that recent
+			offset := last[topic][p.Partition]
+			if at.IsZero() && p.FirstOffset >= 0 {
+				offset = p.FirstOffset
+			}
+			for o := range p.Offsets {
+				if o >= 0 {
+					offset = o
+				}
+			}
+			result[topic][p.Partition] = offset
+		}
+	}
+	return result, nil
+}
+
+// listOffsets sends request for every partition of the topics.
+func (b kafkaBroker) listOffsets(ctx context.Context, client *kafkago.Client, topics []string, request func(partition int) kafkago.OffsetRequest) (map[string][]kafkago.PartitionOffsets, error) {
+	meta, err := client.Metadata(ctx, &kafkago.MetadataRequest{Topics: topics})
+	if err != nil {
+		return nil, err
+	}
+
+	requests := make(map[string][]kafkago.OffsetRequest)
+	for _, topic := range meta.Topics {
+		if topic.Error != nil {
+			return nil, fmt.Errorf("%s: %w", topic.Name, topic.Error)
+		}
+		for _, p := range topic.Partitions {
+			requests[topic.Name] = append(requests[topic.Name], request(p.ID))
+		}
+	}
+
+	res, err := client.ListOffsets(ctx, &kafkago.ListOffsetsRequest{Topics: requests})
+	if err != nil {
+		return nil, err
+	}
+
+	for topic, partitions := range res.Topics {
+		for _, p := range partitions {
+			if p.Error != nil {
+				return nil, fmt.Errorf("%s/%d: %w", topic, p.Partition, p.Error)
+			}
+		}
+	}
+	return res.Topics, nil
+}
+
+func (b kafkaBroker) Topics(ctx context.Context) (map[string]TopicState, error) {
+	client := b.config.client()
+
+	meta, err := client.Metadata(ctx, &kafkago.MetadataRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	topics := make(map[string]TopicState)
+	var resources []kafkago.DescribeConfigRequestResource
+	for _, topic := range meta.Topics {
+		if topic.Internal || internalTopic(topic.Name) {
+			continue
+		}
+		if topic.Error != nil {
+			return nil, fmt.Errorf("%s: %w", topic.Name, topic.Error)
+		}
+
+		state := TopicState{Name: topic.Name, Partitions: len(topic.Partitions), Configs: make(map[string]string)}
+		if len(topic.Partitions) > 0 {
+			state.Replication = len(topic.Partitions[0].Replicas)
+		}
+		topics[topic.Name] = state
+		resources = append(resources, kafkago.DescribeConfigRequestResource{
+			ResourceType: kafkago.ResourceTypeTopic,
+			ResourceName: topic.Name,
+		})
+	}
+	if len(resources) == 0 {
+		return topics, nil
+	}
+
+	res, err := client.DescribeConfigs(ctx, &kafkago.DescribeConfigsRequest{Resources: resources})
+	if err != nil {
+		return nil, err
+	}
+	for _, resource := range res.Resources {
+		if resource.Error != nil {
+			return nil, fmt.Errorf("%s: %w", resource.ResourceName, resource.Error)
+		}
+		for _, entry := range resource.ConfigEntries {
+			topics[resource.ResourceName].Configs[entry.ConfigName] = entry.ConfigValue
+		}
+	}
+	return topics, nil
+}
+
+func (b kafkaBroker) CreateTopic(ctx context.Context, topic TopicState) error {
+	tc := kafkago.TopicConfig{
+		Topic:             topic.Name,
+		NumPartitions:     topic.Partitions,
+		ReplicationFactor: topic.Replication,
+	}
+	for name, value := range topic.Configs {
+		tc.ConfigEntries = append(tc.ConfigEntries, kafkago.ConfigEntry{ConfigName: name, ConfigValue: value})
+	}
+
+	res, err := b.config.client().CreateTopics(ctx, &kafkago.CreateTopicsRequest{Topics: []kafkago.TopicConfig{tc}})
+	if err != nil {
+		return err
+	}
+	return res.Errors[topic.Name]
+}
+
+func (b kafkaBroker) CreatePartitions(ctx context.Context, topic string, count int) error {
+	res, err := b.config.client().CreatePartitions(ctx, &kafkago.CreatePartitionsRequest{
+		Topics: []kafkago.TopicPartitionsConfig{{Name: topic, Count: int32(count)}},
+	})
+	if err != nil {
+		return err
+	}
+	return res.Errors[topic]
+}
+
+func (b kafkaBroker) AlterTopicConfig(ctx context.Context, topic string, configs map[string]string) error {
+	resource := kafkago.IncrementalAlterConfigsRequestResource{
+		ResourceType: kafkago.ResourceTypeTopic,
+		ResourceName: topic,
+	}
+	for name, value := range configs {
+		resource.Configs = append(resource.Configs, kafkago.IncrementalAlterConfigsRequestConfig{
+			Name:            name,
+			Value:           value,
+			ConfigOperation: kafkago.ConfigOperationSet,
+		})
+	}
+
+	res, err := b.config.client().IncrementalAlterConfigs(ctx, &kafkago.IncrementalAlterConfigsRequest{
+		Resources: []kafkago.IncrementalAlterConfigsRequestResource{resource},
+	})
+	if err != nil {
+		return err
+	}
+	for _, r := range res.Resources {
+		if r.Error != nil {
+			return r.Error
+		}
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package kafka
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const ASYNCAPI_VERSION = "3.0.0"
+
+// Version of the documents served by the services
+const ASYNCAPI_DOC_VERSION = "1.0.0"
+
+// AsyncAPI is an AsyncAPI 3.0 document, encoded with encoding/json or
+// yaml.v3.
+type AsyncAPI struct {
+	AsyncAPI           string                       `json:"asyncapi" yaml:"asyncapi"`
+	Info               AsyncAPIInfo                 `json:"info" yaml:"info"`
+	DefaultContentType string                       `json:"defaultContentType" yaml:"defaultContentType"`
+	Channels           map[string]*AsyncAPIChannel  `json:"channels" yaml:"channels"`
+	Operations         map[string]AsyncAPIOperation `json:"operations" yaml:"operations"`
+	Components         AsyncAPIComponents           `json:"components" yaml:"components"`
+}
+
+type AsyncAPIInfo struct {
+	Title       string `json:"title" yaml:"title"`
+	Version     string `json:"version" yaml:"version"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+// AsyncAPIRef is a $ref to another part of the document.
+type AsyncAPIRef struct {
+	Ref string `json:"$ref" yaml:"$ref"`
+}
+
+type AsyncAPIChannel struct {
+	Address  string                 `json:"address" yaml:"address"`
+	Messages map[string]AsyncAPIRef `json:"messages" yaml:"messages"`
+}
+
+type AsyncAPIOperation struct {
+	// send or receive
+	Action   string           `json:"action" yaml:"action"`
+	Channel  AsyncAPIRef      `json:"channel" yaml:"channel"`
+	Summary  string           `json:"summary,omitempty" yaml:"summary,omitempty"`
+	Messages []AsyncAPIRef    `json:"messages" yaml:"messages"`
+	Bindings *AsyncAPIBinding `json:"bindings,omitempty" yaml:"bindings,omitempty"`
+}
+
+// AsyncAPIBinding holds the Kafka consumer group of a receive operation.
+type AsyncAPIBinding struct {
+	Kafka struct {
+		GroupID struct {
+			Type string   `json:"type" yaml:"type"`
+			Enum []string `json:"enum" yaml:"enum"`
+		} `json:"groupId" yaml:"groupId"`
+	} `json:"kafka" yaml:"kafka"`
+}
+
+type AsyncAPIComponents struct {
+	Messages map[string]AsyncAPIMessage `json:"messages" yaml:"messages"`
+}
+
+type AsyncAPIMessage struct {
+	Name     string            `json:"name" yaml:"name"`
+	Payload  *Schema           `json:"payload,omitempty" yaml:"payload,omitempty"`
+	Examples []AsyncAPIExample `json:"examples,omitempty" yaml:"examples,omitempty"`
+}
+
+type AsyncAPIExample struct {
+	Payload any `json:"payload" yaml:"payload"`
+}
+
+// NewAsyncAPI describes the topologies: one channel per topic, one send
+// operation per producer and one receive operation per consumer. The
+// messages of a channel are the events of its producers, or of its
+// consumers for the topics written outside the platform. Messages are named
+// after the Go type of the events, suffixed with the service when two
+// services publish different payloads under the same name.
+func NewAsyncAPI(info AsyncAPIInfo, topologies ...Topology) *AsyncAPI {
+	doc := &AsyncAPI{
+		AsyncAPI:           ASYNCAPI_VERSION,
+		Info:               info,
+		DefaultContentType: JSON.ContentType(),
+		Channels:           make(map[string]*AsyncAPIChannel),
+		Operations:         make(map[string]AsyncAPIOperation),
+		Components:         AsyncAPIComponents{Messages: make(map[string]AsyncAPIMessage)},
+	}
+
+	produced := make(map[string]bool)
+	for _, t := range topologies {
+		for _, e := range t.Endpoints {
+			if e.Role == ROLE_PRODUCER {
+				produced[e.Topic] = true
+			}
+		}
+	}
+
+	for _, t := range topologies {
+		for _, e := range t.Endpoints {
+			channel := doc.channel(e.Topic)
+			if e.Event == "" || (e.Role == ROLE_CONSUMER && produced[e.Topic]) {
+				continue
+			}
+			id := doc.addMessage(t.Service, e)
+			channel.Messages[id] = AsyncAPIRef{"#/components/messages/" + id}
+		}
+	}
+
+	for _, t := range topologies {
+		for _, e := range t.Endpoints {
+			doc.addOperation(t.Service, e)
+		}
+	}
+	return doc
+}
+
+// addMessage adds the message of an endpoint to the components and returns
+// its id. A message with the same name and payload takes the example of the
+// endpoint instead.
+func (doc *AsyncAPI) addMessage(service string, e Endpoint) string {
+	var example []AsyncAPIExample
+	if payload := e.Payload.Example(); payload != nil {
+		example = []AsyncAPIExample{{Payload: payload}}
+	}
+
+	for _, id := range []string{e.Event, e.Event + "." + service} {
+		existing, ok := doc.Components.Messages[id]
+		if !ok {
+			doc.Components.Messages[id] = AsyncAPIMessage{Name: e.Event, Payload: e.Payload, Examples: example}
+			return id
+		}
+		if existing.Payload.Equal(e.Payload) {
+			for _, ex := range example {
+				if !slices.ContainsFunc(existing.Examples, ex.equal) {
+					existing.Examples = append(existing.Examples, ex)
+				}
+			}
+			doc.Components.Messages[id] = existing
+			return id
+		}
+	}
+	// Same service, same name, different payloads: the last one wins
+	id := e.Event + "." + service
+	doc.Components.Messages[id] = AsyncAPIMessage{Name: e.Event, Payload: e.Payload, Examples: example}
+	return id
+}
+
+func (ex AsyncAPIExample) equal(other AsyncAPIExample) bool {
+	a, _ := json.Marshal(ex.Payload)
+	b, _ := json.Marshal(other.Payload)
+	return bytes.Equal(a, b)
+}
+
+// WriteYAML encodes the document as YAML.
+func (doc *AsyncAPI) WriteYAML(w io.Writer) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// channel returns the channel of a topic, created on first use.
+func (doc *AsyncAPI) channel(topic string) *AsyncAPIChannel {
+	id := asyncAPIID(topic)
+	if doc.Channels[id] == nil {
+		doc.Channels[id] = &AsyncAPIChannel{Address: topic, Messages: make(map[string]AsyncAPIRef)}
+	}
+	return doc.Channels[id]
+}
+
+// addOperation adds the operation of a service on a topic. Operations
+// reference every message of their channel.
+func (doc *AsyncAPI) addOperation(service string, e Endpoint) {
+	channel := asyncAPIID(e.Topic)
+
+	action := "send"
+	if e.Role == ROLE_CONSUMER {
+		action = "receive"
+	}
+
+	op := AsyncAPIOperation{
+		Action:   action,
+		Channel:  AsyncAPIRef{"#/channels/" + channel},
+		Summary:  service,
+		Messages: []AsyncAPIRef{},
+	}
+	for _, name := range slices.Sorted(maps.Keys(doc.Channels[channel].Messages)) {
+		op.Messages = append(op.Messages, AsyncAPIRef{"#/channels/" + channel + "/messages/" + name})
+	}
+	if e.Group != "" {
+		op.Bindings = &AsyncAPIBinding{}
+		op.Bindings.Kafka.GroupID.Type = "string"
+		op.Bindings.Kafka.GroupID.Enum = []string{e.Group}
+	}
+
+	doc.Operations[asyncAPIID(service+"."+action+"."+e.Topic)] = op
+}
+
+// asyncAPIID turns a name into a key of channels or operations.
+func asyncAPIID(name string) string {
+	return strings.ReplaceAll(name, ".", "_")
+}
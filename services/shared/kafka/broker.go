@@ -0,0 +1,183 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// Broker is the transport behind producers and consumers: Kafka, or a
+// MemoryBroker in tests.
+type Broker interface {
+	Writer(pc ProducerConfig) (BrokerWriter, error)
+	Reader(cc ConsumerConfig) (BrokerReader, error)
+	CommittedOffsets(ctx context.Context, groupID string, topics []string) (Offsets, error)
+	ResetOffsets(ctx context.Context, groupID string, topics []string, at time.Time) (Offsets, error)
+	OffsetsAt(ctx context.Context, topic string, at time.Time) (map[int]int64, error)
+	EndOffsets(ctx context.Context, topic string) (map[int]int64, error)
+	// PartitionReader reads one partition from offset, outside any group.
+	PartitionReader(topic string, partition int, offset int64) (BrokerReader, error)
+	Topics(ctx context.Context) (map[string]TopicState, error)
+	CreateTopic(ctx context.Context, topic TopicState) error
+	// CreatePartitions adds partitions to a topic until it has count.
+	CreatePartitions(ctx context.Context, topic string, count int) error
+	// AlterTopicConfig sets configs of a topic, leaving the others as is.
+	AlterTopicConfig(ctx context.Context, topic string, configs map[string]string) error
+}
+
+// BrokerWriter writes encoded messages to the topic of its ProducerConfig.
+type BrokerWriter interface {
+	WriteMessages(ctx context.Context, msgs ...Message) error
+	Close() error
+}
+
+// BrokerReader reads the topics of its ConsumerConfig, in order within a
+// partition. FetchMessage and CommitMessages fail with ErrClosed once the
+// reader is closed.
+type BrokerReader interface {
+	FetchMessage(ctx context.Context) (Message, error)
+	CommitMessages(ctx context.Context, msgs ...Message) error
+	Close() error
+}
+
+// kafkaBroker connects to the brokers of its config.
+type kafkaBroker struct {
+	config Config
+}
+
+func (b kafkaBroker) Writer(pc ProducerConfig) (BrokerWriter, error) {
+	codec, err := compression(pc.Compression)
+	if err != nil {
+		return nil, err
+	}
+
+	return kafkaWriter{&kafkago.Writer{
+		Addr:                   kafkago.TCP(b.config.Brokers...),
+		Topic:                  pc.Topic,
+		Balancer:               &kafkago.Hash{},
+		BatchSize:              pc.BatchSize,
+		BatchTimeout:           pc.BatchTimeout,
+		MaxAttempts:            pc.MaxAttempts,
+		WriteBackoffMin:        pc.BackoffMin,
+		WriteBackoffMax:        pc.BackoffMax,
+		Compression:            codec,
+		RequiredAcks:           kafkago.RequireAll,
+		AllowAutoTopicCreation: true,
+		Transport:              b.config.transport(),
+	}}, nil
+}
+
+func (b kafkaBroker) Reader(cc ConsumerConfig) (BrokerReader, error) {
+	rc := kafkago.ReaderConfig{
+		Brokers:        b.config.Brokers,
+		GroupID:        cc.GroupID,
+		StartOffset:    cc.StartOffset,
+		MinBytes:       cc.MinBytes,
+		MaxBytes:       cc.MaxBytes,
+		MaxWait:        cc.MaxWait,
+		CommitInterval: cc.CommitInterval,
+		Dialer:         b.config.dialer(),
+	}
+
+	if cc.GroupID != "" {
+		rc.GroupTopics = cc.Topics
+	} else {
+		rc.Topic = cc.Topics[0]
+	}
+
+	reader := kafkago.NewReader(rc)
+	if cc.GroupID == "" && cc.StartOffset != 0 {
+		if err := reader.SetOffset(cc.StartOffset); err != nil {
+			return nil, err
+		}
+	}
+
+	return kafkaReader{reader}, nil
+}
+
+func (b kafkaBroker) PartitionReader(topic string, partition int, offset int64) (BrokerReader, error) {
+	reader := kafkago.NewReader(kafkago.ReaderConfig{
+		Brokers:   b.config.Brokers,
+		Topic:     topic,
+		Partition: partition,
+		MinBytes:  1,
+		MaxBytes:  10e6,
+		MaxWait:   500 * time.Millisecond,
+		Dialer:    b.config.dialer(),
+	})
+	if err := reader.SetOffset(offset); err != nil {
+		return nil, err
+	}
+
+	return kafkaReader{reader}, nil
+}
+
+type kafkaWriter struct {
+	writer *kafkago.Writer
+}
+
+func (w kafkaWriter) WriteMessages(ctx context.Context, msgs ...Message) error {
+	messages := make([]kafkago.Message, len(msgs))
+	for i, m := range msgs {
+		headers := make([]kafkago.Header, 0, len(m.Headers))
+		for k, v := range m.Headers {
+			headers = append(headers, kafkago.Header{Key: k, Value: []byte(v)})
+		}
+		messages[i] = kafkago.Message{Key: []byte(m.Key), Value: m.Value, Headers: headers}
+	}
+	return w.writer.WriteMessages(ctx, messages...)
+}
+
+func (w kafkaWriter) Close() error {
+	return w.writer.Close()
+}
+
+type kafkaReader struct {
+	reader *kafkago.Reader
+}
+
+func (r kafkaReader) FetchMessage(ctx context.Context) (Message, error) {
+	m, err := r.reader.FetchMessage(ctx)
+	if errors.Is(err, io.EOF) {
+		return Message{}, ErrClosed
+	}
+	if err != nil {
+		return Message{}, err
+	}
+
+	headers := make(map[string]string, len(m.Headers))
+	for _, h := range m.Headers {
+		headers[h.Key] = string(h.Value)
+	}
+
+	return Message{
+		Topic:     m.Topic,
+		Partition: m.Partition,
+		Offset:    m.Offset,
+		Key:       string(m.Key),
+		Value:     m.Value,
+		Headers:   headers,
+		Time:      m.Time,
+	}, nil
+}
+
+// CommitMessages only needs the position of the messages.
+func (r kafkaReader) CommitMessages(ctx context.Context, msgs ...Message) error {
+	raw := make([]kafkago.Message, len(msgs))
+	for i, m := range msgs {
+		raw[i] = kafkago.Message{Topic: m.Topic, Partition: m.Partition, Offset: m.Offset}
+	}
+
+	err := r.reader.CommitMessages(ctx, raw...)
+	if errors.Is(err, io.ErrClosedPipe) {
+		return ErrClosed
+	}
+	return err
+}
+
+func (r kafkaReader) Close() error {
+	return r.reader.Close()
+}
@@ -0,0 +1,53 @@
+package kafka
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// Codec turns values into message payloads and back. Producers write the
+// codec name in the content-type header, consumers pick the codec from it.
+type Codec interface {
+	ContentType() string
+	Encode(v any) ([]byte, error)
+	Decode(data []byte, v any) error
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string             { return "application/json" }
+func (jsonCodec) Encode(v any) ([]byte, error)    { return json.Marshal(v) }
+func (jsonCodec) Decode(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+// JSON is the default codec
+var JSON Codec = jsonCodec{}
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = map[string]Codec{JSON.ContentType(): JSON}
+)
+
+// RegisterCodec makes a codec available to consumers by content type.
+func RegisterCodec(c Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	codecs[c.ContentType()] = c
+}
+
+// codecFor returns the codec of a content type, or def when the message
+// has no content-type header (producers predating the shared client).
+func codecFor(contentType string, def Codec) (Codec, error) {
+	if contentType == "" {
+		return def, nil
+	}
+
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+
+	c, ok := codecs[contentType]
+	if !ok {
+		return nil, fmt.Errorf("no codec registered for %q", contentType)
+	}
+	return c, nil
+}
@@ -0,0 +1,147 @@
+// Package kafka is the Kafka client shared by every service: typed
+// producers, consumer runners, codecs and connection options.
+package kafka
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+)
+
+const DEFAULT_BROKER = "kafka:29092"
+
+// SASL mechanisms
+const (
+	SASL_PLAIN         = "plain"
+	SASL_SCRAM_SHA_256 = "scram-sha-256"
+	SASL_SCRAM_SHA_512 = "scram-sha-512"
+)
+
+type SASLConfig struct {
+	Mechanism string
+	Username  string
+	Password  string
+}
+
+// Config holds the connection options shared by producers and consumers.
+type Config struct {
+	Brokers  []string
+	ClientID string
+	// Enables TLS when set
+	TLS  *tls.Config
+	SASL *SASLConfig
+	// Kafka with the options above when nil
+	Broker Broker
+	// Records the producers and consumers created with this config
+	Registry *Registry
+}
+
+// ConfigFromEnv reads KAFKA_BROKER (comma separated), KAFKA_CLIENT_ID,
+// KAFKA_TLS, KAFKA_TLS_CA_FILE, KAFKA_TLS_INSECURE, KAFKA_SASL_MECHANISM,
+// KAFKA_SASL_USERNAME and KAFKA_SASL_PASSWORD.
+func ConfigFromEnv(clientID string) (Config, error) {
+	config := Config{Brokers: []string{DEFAULT_BROKER}, ClientID: clientID}
+
+	if v := os.Getenv("KAFKA_BROKER"); v != "" {
+		config.Brokers = strings.Split(v, ",")
+	}
+	if v := os.Getenv("KAFKA_CLIENT_ID"); v != "" {
+		config.ClientID = v
+	}
+
+	if os.Getenv("KAFKA_TLS") == "true" {
+		config.TLS = &tls.Config{
+			MinVersion:         tls.VersionTLS12,
+			InsecureSkipVerify: os.Getenv("KAFKA_TLS_INSECURE") == "true",
+		}
+
+		if file := os.Getenv("KAFKA_TLS_CA_FILE"); file != "" {
+			pem, err := os.ReadFile(file)
+			if err != nil {
+				return config, fmt.Errorf("reading KAFKA_TLS_CA_FILE: %w", err)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				return config, fmt.Errorf("no certificate found in %s", file)
+			}
+			config.TLS.RootCAs = pool
+		}
+	}
+
+	if mechanism := os.Getenv("KAFKA_SASL_MECHANISM"); mechanism != "" {
+		config.SASL = &SASLConfig{
+			Mechanism: mechanism,
+			Username:  os.Getenv("KAFKA_SASL_USERNAME"),
+			Password:  os.Getenv("KAFKA_SASL_PASSWORD"),
+		}
+		if _, err := config.SASL.mechanism(); err != nil {
+			return config, err
+		}
+	}
+
+	return config, nil
+}
+
+func (s *SASLConfig) mechanism() (sasl.Mechanism, error) {
+	switch strings.ToLower(s.Mechanism) {
+	case SASL_PLAIN:
+		return plain.Mechanism{Username: s.Username, Password: s.Password}, nil
+	case SASL_SCRAM_SHA_256:
+		return scram.Mechanism(scram.SHA256, s.Username, s.Password)
+	case SASL_SCRAM_SHA_512:
+		return scram.Mechanism(scram.SHA512, s.Username, s.Password)
+	}
+	return nil, fmt.Errorf("unknown SASL mechanism %q", s.Mechanism)
+}
+
+func (c Config) saslMechanism() sasl.Mechanism {
+	if c.SASL == nil {
+		return nil
+	}
+	// Validated by ConfigFromEnv, a hand built config fails at connection time
+	m, _ := c.SASL.mechanism()
+	return m
+}
+
+// transport is used by writers and admin requests.
+func (c Config) transport() *kafkago.Transport {
+	return &kafkago.Transport{
+		ClientID: c.ClientID,
+		TLS:      c.TLS,
+		SASL:     c.saslMechanism(),
+	}
+}
+
+// dialer is used by readers, which don't accept a transport.
+func (c Config) dialer() *kafkago.Dialer {
+	return &kafkago.Dialer{
+		ClientID:      c.ClientID,
+		Timeout:       10 * time.Second,
+		DualStack:     true,
+		TLS:           c.TLS,
+		SASLMechanism: c.saslMechanism(),
+	}
+}
+
+func (c Config) broker() Broker {
+	if c.Broker != nil {
+		return c.Broker
+	}
+	return kafkaBroker{c}
+}
+
+func (c Config) client() *kafkago.Client {
+	return &kafkago.Client{
+		Addr:      kafkago.TCP(c.Brokers...),
+		Timeout:   10 * time.Second,
+		Transport: c.transport(),
+	}
+}
@@ -0,0 +1,219 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// ErrClosed is returned by Fetch once the consumer is closed.
+var ErrClosed = errors.New("consumer closed")
+
+// Start offsets of a group with no committed offset
+const (
+	FIRST_OFFSET = kafkago.FirstOffset
+	LAST_OFFSET  = kafkago.LastOffset
+)
+
+func ParseStartOffset(s string) (int64, error) {
+	switch s {
+	case "first":
+		return FIRST_OFFSET, nil
+	case "last":
+		return LAST_OFFSET, nil
+	}
+	return 0, fmt.Errorf("unknown start offset %q (first, last)", s)
+}
+
+// RetryPolicy of a handler: Attempts tries in total, waiting Backoff,
+// doubled after each failure up to MaxBackoff.
+type RetryPolicy struct {
+	Attempts   int
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+type ConsumerConfig struct {
+	Topics []string
+	// Consumer group sharing the partitions. Without one, a single topic is
+	// read from StartOffset and nothing is committed.
+	GroupID     string
+	StartOffset int64
+	// Used for messages without content-type header, JSON when nil
+	Codec          Codec
+	MinBytes       int
+	MaxBytes       int
+	MaxWait        time.Duration
+	CommitInterval time.Duration
+	Retry          RetryPolicy
+	// Called with messages that can't be decoded or whose handler still
+	// fails after the retries. They are committed anyway, so one bad message
+	// doesn't block its partition.
+	OnError func(Message, error)
+}
+
+func DefaultConsumerConfig(groupID string, topics ...string) ConsumerConfig {
+	return ConsumerConfig{
+		Topics:      topics,
+		GroupID:     groupID,
+		StartOffset: FIRST_OFFSET,
+		Codec:       JSON,
+		MinBytes:    1,
+		MaxBytes:    10e6,
+		MaxWait:     500 * time.Millisecond,
+		Retry:       RetryPolicy{Attempts: 3, Backoff: 200 * time.Millisecond, MaxBackoff: 5 * time.Second},
+		OnError: func(m Message, err error) {
+			log.Printf("Error handling %s/%d@%d: %v", m.Topic, m.Partition, m.Offset, err)
+		},
+	}
+}
+
+// Consumer reads messages from one or more topics.
+type Consumer struct {
+	config ConsumerConfig
+	reader BrokerReader
+}
+
+func NewConsumer(config Config, cc ConsumerConfig) (*Consumer, error) {
+	if cc.Codec == nil {
+		cc.Codec = JSON
+	}
+	if cc.OnError == nil {
+		cc.OnError = DefaultConsumerConfig("").OnError
+	}
+	if len(cc.Topics) == 0 {
+		return nil, errors.New("consumer needs at least one topic")
+	}
+	if cc.GroupID == "" && len(cc.Topics) > 1 {
+		return nil, errors.New("reading several topics needs a consumer group")
+	}
+
+	reader, err := config.broker().Reader(cc)
+	if err != nil {
+		return nil, err
+	}
+	// The event type is declared by the service, see Consumes
+	for _, topic := range cc.Topics {
+		config.Registry.add(Endpoint{Topic: topic, Role: ROLE_CONSUMER, Group: cc.GroupID}, nil)
+	}
+
+	return &Consumer{config: cc, reader: reader}, nil
+}
+
+func (c *Consumer) GroupID() string {
+	return c.config.GroupID
+}
+
+func (c *Consumer) Topics() []string {
+	return c.config.Topics
+}
+
+// Fetch returns the next message without committing it. A message whose
+// content type has no registered codec is returned with the error.
+func (c *Consumer) Fetch(ctx context.Context) (Message, error) {
+	m, err := c.reader.FetchMessage(ctx)
+	if err != nil {
+		return Message{}, err
+	}
+
+	m.codec, err = codecFor(m.Headers[HEADER_CONTENT_TYPE], c.config.Codec)
+	return m, err
+}
+
+// Commit marks the messages, and the ones before them in their partition,
+// as processed by the group. Fails with ErrClosed once the consumer is
+// closed, the messages are then read again by the next group member.
+func (c *Consumer) Commit(ctx context.Context, msgs ...Message) error {
+	if c.config.GroupID == "" {
+		return nil
+	}
+	return c.reader.CommitMessages(ctx, msgs...)
+}
+
+// Close leaves the group and stops Fetch.
+func (c *Consumer) Close() error {
+	return c.reader.Close()
+}
+
+// Handler processes one decoded message. ctx carries the correlation id of
+// the message, propagated to the messages the handler produces.
+type Handler[T any] func(ctx context.Context, msg Message, value T) error
+
+// Run decodes each message into T, hands it to handle with retries and
+// commits it. It returns nil once ctx is cancelled or the consumer closed,
+// and the error of a failed fetch or commit otherwise.
+func Run[T any](ctx context.Context, c *Consumer, handle Handler[T]) error {
+	for {
+		m, err := c.Fetch(ctx)
+		if errors.Is(err, ErrClosed) || ctx.Err() != nil {
+			return nil
+		}
+
+		if err == nil {
+			var value T
+			if err = m.Decode(&value); err != nil {
+				err = fmt.Errorf("decoding: %w", err)
+			} else {
+				err = handleMessage(ctx, c.config.Retry, m, value, handle)
+			}
+		}
+
+		if err != nil {
+			if m.Topic == "" {
+				return err
+			}
+			c.config.OnError(m, err)
+		}
+
+		if err := c.Commit(ctx, m); err != nil {
+			if errors.Is(err, ErrClosed) || ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// handleMessage calls handle with retries, in a context carrying the
+// correlation id of the message (a new one if it has none).
+func handleMessage[T any](ctx context.Context, policy RetryPolicy, m Message, value T, handle Handler[T]) error {
+	id := m.Headers[HEADER_CORRELATION_ID]
+	if id == "" {
+		id = NewID()
+	}
+	ctx = WithCorrelationID(ctx, id)
+
+	return retry(ctx, policy, func() error {
+		return handle(ctx, m, value)
+	})
+}
+
+// retry calls fn until it succeeds, the policy is exhausted or ctx is done.
+func retry(ctx context.Context, policy RetryPolicy, fn func() error) error {
+	backoff := policy.Backoff
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		if attempt >= policy.Attempts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+			backoff = policy.MaxBackoff
+		}
+	}
+}
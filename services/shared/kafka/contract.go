@@ -0,0 +1,262 @@
+package kafka
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Contract is what a consumer expects of the events of a topic, as examples
+// of messages it handles. Consumers publish their contracts from their
+// tests, see PublishContracts, and producers check the events they emit
+// against them, see VerifyContracts.
+//
+// An event honours a contract when it matches one of the examples: every
+// field of the example is in the event with the same JSON type, objects and
+// array items compared the same way. Other fields of the event are ignored,
+// a null in the example accepts anything.
+type Contract struct {
+	Consumer string            `json:"consumer"`
+	Topic    string            `json:"topic"`
+	Event    string            `json:"event"`
+	Examples []json.RawMessage `json:"examples"`
+}
+
+// NewContract checks that the JSON examples decode into T, the type the
+// consumer reads from topic, without unknown fields. Examples hold only the
+// fields the consumer relies on: a field renamed in T breaks its own
+// contract, a field left out of every example is none of the producers'
+// business.
+func NewContract[T any](consumer, topic string, examples ...string) (Contract, error) {
+	c := Contract{Consumer: consumer, Topic: topic, Event: typeName(reflect.TypeFor[T]())}
+	if len(examples) == 0 {
+		return c, fmt.Errorf("contract of %s on %s has no example", consumer, topic)
+	}
+
+	for i, example := range examples {
+		decoder := json.NewDecoder(strings.NewReader(example))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(new(T)); err != nil {
+			return c, fmt.Errorf("example %d of %s on %s: %w", i, consumer, topic, err)
+		}
+
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, []byte(example)); err != nil {
+			return c, err
+		}
+		c.Examples = append(c.Examples, compact.Bytes())
+	}
+	return c, nil
+}
+
+// Messages returns the examples as consumed messages, for the consumer to
+// check that its handler accepts them.
+func (c Contract) Messages() []Message {
+	var messages []Message
+	for i, example := range c.Examples {
+		messages = append(messages, Message{
+			Topic:   c.Topic,
+			Offset:  int64(i),
+			Value:   slices.Clone(example),
+			Headers: map[string]string{HEADER_CONTENT_TYPE: JSON.ContentType()},
+		})
+	}
+	return messages
+}
+
+// Verify checks that value matches one of the examples, the error lists how
+// it differs from each of them.
+func (c Contract) Verify(value []byte) error {
+	var event any
+	if err := json.Unmarshal(value, &event); err != nil {
+		return fmt.Errorf("%s expects %s on %s: %w", c.Consumer, c.Event, c.Topic, err)
+	}
+
+	var mismatches []string
+	for i, example := range c.Examples {
+		var expected any
+		if err := json.Unmarshal(example, &expected); err != nil {
+			return fmt.Errorf("example %d of %s on %s: %w", i, c.Consumer, c.Topic, err)
+		}
+
+		diffs := matchExample("", expected, event)
+		if len(diffs) == 0 {
+			return nil
+		}
+		mismatches = append(mismatches, fmt.Sprintf("example %d: %s", i, strings.Join(diffs, ", ")))
+	}
+	return fmt.Errorf("%s expects %s on %s: %s", c.Consumer, c.Event, c.Topic, strings.Join(mismatches, "; "))
+}
+
+// matchExample compares an event decoded as JSON to an example, path is
+// the field being compared.
+func matchExample(path string, expected, got any) []string {
+	if expected == nil {
+		return nil
+	}
+	if got == nil {
+		return []string{fmt.Sprintf("%s is null", fieldPath(path))}
+	}
+	if jsonType(expected) != jsonType(got) {
+		return []string{fmt.Sprintf("%s is %s, want %s", fieldPath(path), jsonType(got), jsonType(expected))}
+	}
+
+	var diffs []string
+	switch expected := expected.(type) {
+	case map[string]any:
+		obj := got.(map[string]any)
+		for _, name := range slices.Sorted(maps.Keys(expected)) {
+			value, ok := obj[name]
+			if !ok && expected[name] != nil {
+				diffs = append(diffs, fmt.Sprintf("%s is missing", fieldPath(path+"."+name)))
+				continue
+			}
+			diffs = append(diffs, matchExample(path+"."+name, expected[name], value)...)
+		}
+	case []any:
+		if len(expected) == 0 {
+			break
+		}
+		for i, item := range got.([]any) {
+			diffs = append(diffs, matchExample(fmt.Sprintf("%s[%d]", path, i), expected[0], item)...)
+		}
+	}
+	return diffs
+}
+
+func fieldPath(path string) string {
+	if path == "" {
+		return "the event"
+	}
+	return strings.TrimPrefix(path, ".")
+}
+
+func jsonType(v any) string {
+	switch v.(type) {
+	case map[string]any:
+		return "an object"
+	case []any:
+		return "an array"
+	case string:
+		return "a string"
+	case float64:
+		return "a number"
+	case bool:
+		return "a boolean"
+	}
+	return "null"
+}
+
+// path of a contract in dir: one directory per topic, one file per consumer.
+func (c Contract) path(dir string) string {
+	return filepath.Join(dir, c.Topic, c.Consumer+".json")
+}
+
+// PublishContracts checks that dir holds the contracts of a consumer, all of
+// them, or writes them when update is set. Contracts of the consumer on
+// topics it no longer reads are stale: reported, or removed on update.
+func PublishContracts(dir string, update bool, contracts ...Contract) error {
+	if len(contracts) == 0 {
+		return errors.New("no contract to publish")
+	}
+	consumer := contracts[0].Consumer
+
+	var errs []error
+	published := make(map[string]bool)
+	for _, c := range contracts {
+		if c.Consumer != consumer {
+			return fmt.Errorf("contracts of %s and %s published together", consumer, c.Consumer)
+		}
+		path := c.path(dir)
+		published[path] = true
+
+		encoded, err := json.MarshalIndent(c, "", "  ")
+		if err != nil {
+			return err
+		}
+		encoded = append(encoded, '\n')
+
+		if update {
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				return err
+			}
+			if err := os.WriteFile(path, encoded, 0o644); err != nil {
+				return err
+			}
+			continue
+		}
+		if current, err := os.ReadFile(path); err != nil || !bytes.Equal(current, encoded) {
+			errs = append(errs, fmt.Errorf("%s is out of date, run go test with -update", path))
+		}
+	}
+
+	stale, err := filepath.Glob(filepath.Join(dir, "*", consumer+".json"))
+	if err != nil {
+		return err
+	}
+	for _, path := range stale {
+		switch {
+		case published[path]:
+		case update:
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+		default:
+			errs = append(errs, fmt.Errorf("%s is stale, run go test with -update", path))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// LoadContracts reads the contracts published in dir, those on topics only
+// when given.
+func LoadContracts(dir string, topics ...string) ([]Contract, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*", "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var contracts []Contract
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		var c Contract
+		if err := json.Unmarshal(data, &c); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if c.path(dir) != path {
+			return nil, fmt.Errorf("%s holds the contract of %s on %s", path, c.Consumer, c.Topic)
+		}
+		if len(topics) == 0 || slices.Contains(topics, c.Topic) {
+			contracts = append(contracts, c)
+		}
+	}
+	return contracts, nil
+}
+
+// VerifyContracts checks produced messages against the contracts of their
+// topic, the error lists every message breaking one.
+func VerifyContracts(contracts []Contract, messages ...Message) error {
+	var errs []error
+	for _, m := range messages {
+		for _, c := range contracts {
+			if c.Topic != m.Topic {
+				continue
+			}
+			if err := c.Verify(m.Value); err != nil {
+				errs = append(errs, fmt.Errorf("%s partition %d offset %d: %w", m.Topic, m.Partition, m.Offset, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,119 @@
+package kafka
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type placed struct {
+	OrderID string `json:"orderId"`
+	Items   []struct {
+		SKU string `json:"sku"`
+		Qty int    `json:"qty"`
+	} `json:"items"`
+}
+
+func placedContract(t *testing.T) Contract {
+	t.Helper()
+
+	c, err := NewContract[placed]("inventory-service", "payment.done", `{"orderId": "42", "items": [{"sku": "deck", "qty": 2}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestContractVerify(t *testing.T) {
+	c := placedContract(t)
+
+	for _, tt := range []struct {
+		event string
+		diff  string
+	}{
+		{event: `{"orderId":"1","items":[{"sku":"a","qty":1}],"total":10}`},
+		{event: `{"orderId":"1","items":[]}`},
+		{event: `{"order_id":"1","items":[]}`, diff: "orderId is missing"},
+		{event: `{"orderId":1,"items":[]}`, diff: "orderId is a number, want a string"},
+		{event: `{"orderId":"1","items":[{"sku":"a","quantity":1}]}`, diff: "items[0].qty is missing"},
+		{event: `{"orderId":"1","items":null}`, diff: "items is null"},
+		{event: `[]`, diff: "the event is an array, want an object"},
+	} {
+		err := c.Verify([]byte(tt.event))
+		switch {
+		case tt.diff == "" && err != nil:
+			t.Errorf("%s: %v", tt.event, err)
+		case tt.diff != "" && (err == nil || !strings.Contains(err.Error(), tt.diff)):
+			t.Errorf("%s: error %v, want %q", tt.event, err, tt.diff)
+		}
+	}
+
+	// Examples are fields of the consumer's type
+	if _, err := NewContract[placed]("inventory-service", "payment.done", `{"order_id": "42"}`); err == nil {
+		t.Error("example with an unknown field accepted")
+	}
+	if _, err := NewContract[placed]("inventory-service", "payment.done", `{"orderId": 42}`); err == nil {
+		t.Error("example of the wrong type accepted")
+	}
+
+	// The handler of the consumer gets the examples
+	var got placed
+	if err := c.Messages()[0].Decode(&got); err != nil || got.OrderID != "42" {
+		t.Errorf("message = %+v, %v", got, err)
+	}
+}
+
+func TestPublishAndVerifyContracts(t *testing.T) {
+	dir := t.TempDir()
+	c := placedContract(t)
+
+	if err := PublishContracts(dir, false, c); err == nil {
+		t.Error("unpublished contract accepted")
+	}
+	stale := filepath.Join(dir, "order.cancelled", "inventory-service.json")
+	if err := os.MkdirAll(filepath.Dir(stale), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(stale, []byte("{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := PublishContracts(dir, true, c); err != nil {
+		t.Fatal(err)
+	}
+	if err := PublishContracts(dir, false, c); err != nil {
+		t.Error(err)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Error("stale contract kept")
+	}
+
+	contracts, err := LoadContracts(dir, "payment.done")
+	if err != nil || len(contracts) != 1 || contracts[0].Event != "placed" {
+		t.Fatalf("contracts = %+v, %v", contracts, err)
+	}
+
+	// A producer renaming a field breaks the contract
+	config, broker := newMemoryConfig(1)
+	producer, err := NewProducer[map[string]any](config, DefaultProducerConfig("payment.done"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer producer.Close()
+
+	ctx := context.Background()
+	if err := producer.Send(ctx, "1", map[string]any{"orderId": "1", "items": []any{}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyContracts(contracts, broker.Messages("payment.done")...); err != nil {
+		t.Error(err)
+	}
+	if err := producer.Send(ctx, "2", map[string]any{"id": "2", "items": []any{}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyContracts(contracts, broker.Messages("payment.done")...); err == nil || !strings.Contains(err.Error(), "orderId is missing") {
+		t.Errorf("err = %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package kafka
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// edge is an arrow of the topology graph: a producer to its topic or a topic
+// to its consumer.
+type edge struct {
+	from, to string
+	events   []string
+}
+
+// graph merges the topologies into the services, the topics and the edges
+// between them, in a stable order.
+func graph(topologies []Topology) (services, topics []string, edges []*edge) {
+	index := make(map[[2]string]*edge)
+
+	for _, t := range topologies {
+		services = append(services, t.Service)
+		for _, e := range t.Endpoints {
+			if !slices.Contains(topics, e.Topic) {
+				topics = append(topics, e.Topic)
+			}
+
+			key := [2]string{t.Service, e.Topic}
+			if e.Role == ROLE_CONSUMER {
+				key = [2]string{e.Topic, t.Service}
+			}
+
+			ed, ok := index[key]
+			if !ok {
+				ed = &edge{from: key[0], to: key[1]}
+				index[key] = ed
+				edges = append(edges, ed)
+			}
+			if e.Event != "" && !slices.Contains(ed.events, e.Event) {
+				ed.events = append(ed.events, e.Event)
+			}
+		}
+	}
+
+	slices.Sort(services)
+	slices.Sort(topics)
+	slices.SortFunc(edges, func(a, b *edge) int {
+		return strings.Compare(a.from+"\x00"+a.to, b.from+"\x00"+b.to)
+	})
+	return services, topics, edges
+}
+
+// Mermaid renders the topologies as a Mermaid flowchart: services are boxes,
+// topics are stadiums, arrows are labelled with the event types.
+func Mermaid(topologies ...Topology) string {
+	services, topics, edges := graph(topologies)
+
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for _, s := range services {
+		fmt.Fprintf(&b, "  %s[%s]\n", mermaidID("s", s), s)
+	}
+	for _, t := range topics {
+		fmt.Fprintf(&b, "  %s([%s])\n", mermaidID("t", t), t)
+	}
+
+	id := func(name string) string {
+		if slices.Contains(services, name) {
+			return mermaidID("s", name)
+		}
+		return mermaidID("t", name)
+	}
+	for _, e := range edges {
+		if len(e.events) == 0 {
+			fmt.Fprintf(&b, "  %s --> %s\n", id(e.from), id(e.to))
+			continue
+		}
+		fmt.Fprintf(&b, "  %s -- %s --> %s\n", id(e.from), strings.Join(e.events, ", "), id(e.to))
+	}
+	return b.String()
+}
+
+// mermaidID turns a name into a node id, dots and dashes aren't allowed.
+func mermaidID(prefix, name string) string {
+	return prefix + "_" + strings.NewReplacer(".", "_", "-", "_").Replace(name)
+}
+
+// Graphviz renders the topologies as a Graphviz digraph, for dot -Tsvg.
+func Graphviz(topologies ...Topology) string {
+	services, topics, edges := graph(topologies)
+
+	var b strings.Builder
+	b.WriteString("digraph topology {\n  rankdir=LR;\n")
+	for _, s := range services {
+		fmt.Fprintf(&b, "  %q [shape=box];\n", s)
+	}
+	for _, t := range topics {
+		fmt.Fprintf(&b, "  %q [shape=cds];\n", t)
+	}
+	for _, e := range edges {
+		if len(e.events) == 0 {
+			fmt.Fprintf(&b, "  %q -> %q;\n", e.from, e.to)
+			continue
+		}
+		fmt.Fprintf(&b, "  %q -> %q [label=%q];\n", e.from, e.to, strings.Join(e.events, ", "))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
@@ -0,0 +1,544 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// MemoryBroker keeps topics in memory so services can be tested without
+// Kafka. Like Kafka, messages are spread over partitions by key, consumer
+// groups share the partitions of their topics and resume from their
+// committed offsets after a rebalance.
+type MemoryBroker struct {
+	partitions int
+
+	mu     sync.Mutex
+	topics map[string][][]Message
+	// Replication and configs of the topics made by CreateTopic
+	settings map[string]TopicState
+	groups   map[string]*memoryGroup
+	// Closed and replaced on every write, commit and rebalance
+	changed chan struct{}
+	// Partition of the next message without key
+	next int
+}
+
+type topicPartition struct {
+	topic     string
+	partition int
+}
+
+type memoryGroup struct {
+	committed map[topicPartition]int64
+	// In join order, partitions are assigned round robin
+	members []*memoryReader
+}
+
+// NewMemoryBroker creates a broker whose topics have the given number of
+// partitions (at least one).
+func NewMemoryBroker(partitions int) *MemoryBroker {
+	return &MemoryBroker{
+		partitions: max(partitions, 1),
+		topics:     make(map[string][][]Message),
+		settings:   make(map[string]TopicState),
+		groups:     make(map[string]*memoryGroup),
+		changed:    make(chan struct{}),
+	}
+}
+
+// Messages returns a copy of the messages written to a topic, partition
+// after partition.
+func (b *MemoryBroker) Messages(topic string) []Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var messages []Message
+	for _, partition := range b.topic(topic) {
+		for _, m := range partition {
+			messages = append(messages, m.copy())
+		}
+	}
+	return messages
+}
+
+// WaitMessages waits until a topic holds at least n messages and returns
+// them, or fails once ctx is done.
+func (b *MemoryBroker) WaitMessages(ctx context.Context, topic string, n int) ([]Message, error) {
+	for {
+		b.mu.Lock()
+		count := 0
+		for _, partition := range b.topic(topic) {
+			count += len(partition)
+		}
+		changed := b.changed
+		b.mu.Unlock()
+
+		if count >= n {
+			return b.Messages(topic), nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%s has %d messages, waiting for %d: %w", topic, count, n, ctx.Err())
+		case <-changed:
+		}
+	}
+}
+
+// WaitConsumed waits until a consumer group has committed every message
+// of the topics, or fails once ctx is done.
+func (b *MemoryBroker) WaitConsumed(ctx context.Context, groupID string, topics ...string) error {
+	for {
+		b.mu.Lock()
+		g := b.group(groupID)
+		lag := int64(0)
+		for _, topic := range topics {
+			for p, partition := range b.topic(topic) {
+				lag += int64(len(partition)) - g.committed[topicPartition{topic, p}]
+			}
+		}
+		changed := b.changed
+		b.mu.Unlock()
+
+		if lag <= 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("group %s has %d messages left: %w", groupID, lag, ctx.Err())
+		case <-changed:
+		}
+	}
+}
+
+func (b *MemoryBroker) Writer(pc ProducerConfig) (BrokerWriter, error) {
+	if pc.Topic == "" {
+		return nil, errors.New("producer needs a topic")
+	}
+
+	// Created with the writer, like Kafka does on the producer's first
+	// metadata request
+	b.mu.Lock()
+	b.topic(pc.Topic)
+	b.mu.Unlock()
+
+	return memoryWriter{b, pc.Topic}, nil
+}
+
+func (b *MemoryBroker) Reader(cc ConsumerConfig) (BrokerReader, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	r := &memoryReader{broker: b, config: cc, positions: make(map[topicPartition]int64)}
+
+	if cc.GroupID == "" {
+		for p, partition := range b.topic(cc.Topics[0]) {
+			r.positions[topicPartition{cc.Topics[0], p}] = startOffset(cc.StartOffset, partition)
+		}
+		return r, nil
+	}
+
+	for _, topic := range cc.Topics {
+		b.topic(topic)
+	}
+
+	r.group = b.group(cc.GroupID)
+	r.group.members = append(r.group.members, r)
+	b.rebalance(r.group)
+	return r, nil
+}
+
+func (b *MemoryBroker) CommittedOffsets(ctx context.Context, groupID string, topics []string) (Offsets, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	g := b.group(groupID)
+	offsets := make(Offsets)
+	for _, topic := range topics {
+		offsets[topic] = make(map[int]int64)
+		for p := range b.topic(topic) {
+			offset, ok := g.committed[topicPartition{topic, p}]
+			if !ok {
+				offset = -1
+			}
+			offsets[topic][p] = offset
+		}
+	}
+	return offsets, nil
+}
+
+func (b *MemoryBroker) ResetOffsets(ctx context.Context, groupID string, topics []string, at time.Time) (Offsets, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	g := b.group(groupID)
+	if len(g.members) > 0 {
+		return nil, fmt.Errorf("group %s has %d active members", groupID, len(g.members))
+	}
+
+	offsets := make(Offsets)
+	for _, topic := range topics {
+		offsets[topic] = b.offsetsAt(topic, at)
+		for p, offset := range offsets[topic] {
+			g.committed[topicPartition{topic, p}] = offset
+		}
+	}
+
+	b.notify()
+	return offsets, nil
+}
+
+func (b *MemoryBroker) OffsetsAt(ctx context.Context, topic string, at time.Time) (map[int]int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.offsetsAt(topic, at), nil
+}
+
+// Called with the lock held.
+func (b *MemoryBroker) offsetsAt(topic string, at time.Time) map[int]int64 {
+	offsets := make(map[int]int64)
+	for p, partition := range b.topic(topic) {
+		offset := int64(len(partition))
+		for _, m := range partition {
+			if !m.Time.Before(at) {
+				offset = m.Offset
+				break
+			}
+		}
+		offsets[p] = offset
+	}
+	return offsets
+}
+
+func (b *MemoryBroker) EndOffsets(ctx context.Context, topic string) (map[int]int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	offsets := make(map[int]int64)
+	for p, partition := range b.topic(topic) {
+		offsets[p] = int64(len(partition))
+	}
+	return offsets, nil
+}
+
+func (b *MemoryBroker) PartitionReader(topic string, partition int, offset int64) (BrokerReader, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if partition < 0 || partition >= len(b.topic(topic)) {
+		return nil, fmt.Errorf("%s has no partition %d", topic, partition)
+	}
+
+	r := &memoryReader{broker: b, positions: make(map[topicPartition]int64)}
+	r.positions[topicPartition{topic, partition}] = offset
+	return r, nil
+}
+
+func (b *MemoryBroker) Topics(ctx context.Context) (map[string]TopicState, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	topics := make(map[string]TopicState)
+	for name, partitions := range b.topics {
+		state := TopicState{Name: name, Partitions: len(partitions), Replication: 1, Configs: make(map[string]string)}
+		if settings, ok := b.settings[name]; ok {
+			state.Replication = settings.Replication
+			maps.Copy(state.Configs, settings.Configs)
+		}
+		topics[name] = state
+	}
+	return topics, nil
+}
+
+func (b *MemoryBroker) CreateTopic(ctx context.Context, topic TopicState) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.topics[topic.Name]; ok {
+		return fmt.Errorf("topic %s already exists", topic.Name)
+	}
+	if topic.Partitions < 1 {
+		return fmt.Errorf("topic %s needs partitions", topic.Name)
+	}
+
+	b.topics[topic.Name] = make([][]Message, topic.Partitions)
+	b.settings[topic.Name] = TopicState{Replication: max(topic.Replication, 1), Configs: maps.Clone(topic.Configs)}
+	return nil
+}
+
+// CreatePartitions rebalances the groups reading the topic so the new
+// partitions get a member.
+func (b *MemoryBroker) CreatePartitions(ctx context.Context, topic string, count int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	partitions, ok := b.topics[topic]
+	if !ok {
+		return fmt.Errorf("unknown topic %s", topic)
+	}
+	if count <= len(partitions) {
+		return fmt.Errorf("%s already has %d partitions", topic, len(partitions))
+	}
+	b.topics[topic] = append(partitions, make([][]Message, count-len(partitions))...)
+
+	for _, g := range b.groups {
+		if slices.ContainsFunc(g.members, func(r *memoryReader) bool { return slices.Contains(r.config.Topics, topic) }) {
+			b.rebalance(g)
+		}
+	}
+	return nil
+}
+
+func (b *MemoryBroker) AlterTopicConfig(ctx context.Context, topic string, configs map[string]string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.topics[topic]; !ok {
+		return fmt.Errorf("unknown topic %s", topic)
+	}
+
+	settings, ok := b.settings[topic]
+	if !ok {
+		settings.Replication = 1
+	}
+	settings.Configs = maps.Clone(settings.Configs)
+	if settings.Configs == nil {
+		settings.Configs = make(map[string]string)
+	}
+	maps.Copy(settings.Configs, configs)
+	b.settings[topic] = settings
+	return nil
+}
+
+// topic returns the partitions of a topic, created on first use like with
+// auto topic creation. Called with the lock held.
+func (b *MemoryBroker) topic(name string) [][]Message {
+	partitions, ok := b.topics[name]
+	if !ok {
+		partitions = make([][]Message, b.partitions)
+		b.topics[name] = partitions
+	}
+	return partitions
+}
+
+// Called with the lock held.
+func (b *MemoryBroker) group(id string) *memoryGroup {
+	g, ok := b.groups[id]
+	if !ok {
+		g = &memoryGroup{committed: make(map[topicPartition]int64)}
+		b.groups[id] = g
+	}
+	return g
+}
+
+// rebalance assigns the partitions of each topic to the members reading it
+// and moves every member back to the committed offsets: messages fetched
+// but not committed are read again, as after a Kafka rebalance. Called
+// with the lock held.
+func (b *MemoryBroker) rebalance(g *memoryGroup) {
+	for _, member := range g.members {
+		clear(member.positions)
+	}
+
+	topics := make(map[string]bool)
+	for _, member := range g.members {
+		for _, topic := range member.config.Topics {
+			topics[topic] = true
+		}
+	}
+
+	for topic := range topics {
+		var readers []*memoryReader
+		for _, member := range g.members {
+			if slices.Contains(member.config.Topics, topic) {
+				readers = append(readers, member)
+			}
+		}
+
+		for p, partition := range b.topic(topic) {
+			tp := topicPartition{topic, p}
+			reader := readers[p%len(readers)]
+
+			offset, ok := g.committed[tp]
+			if !ok {
+				offset = startOffset(reader.config.StartOffset, partition)
+			}
+			reader.positions[tp] = offset
+		}
+	}
+
+	b.notify()
+}
+
+// Called with the lock held.
+func (b *MemoryBroker) notify() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+// startOffset of a partition for a reader with nothing committed.
+func startOffset(start int64, partition []Message) int64 {
+	if start == LAST_OFFSET {
+		return int64(len(partition))
+	}
+	return 0
+}
+
+func (m Message) copy() Message {
+	m.Headers = maps.Clone(m.Headers)
+	m.Value = slices.Clone(m.Value)
+	// Decode reads the content-type like a consumer would
+	if codec, err := codecFor(m.Headers[HEADER_CONTENT_TYPE], JSON); err == nil {
+		m.codec = codec
+	}
+	return m
+}
+
+type memoryWriter struct {
+	broker *MemoryBroker
+	topic  string
+}
+
+func (w memoryWriter) WriteMessages(ctx context.Context, msgs ...Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	b := w.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	partitions := b.topic(w.topic)
+	for _, m := range msgs {
+		p := b.partition(m.Key, len(partitions))
+
+		m = m.copy()
+		m.Topic = w.topic
+		m.Partition = p
+		m.Offset = int64(len(partitions[p]))
+		m.Time = time.Now()
+		partitions[p] = append(partitions[p], m)
+	}
+
+	b.notify()
+	return nil
+}
+
+func (w memoryWriter) Close() error {
+	return nil
+}
+
+// partition hashes the key like the Hash balancer of the Kafka producers,
+// messages without key are spread round robin. Called with the lock held.
+func (b *MemoryBroker) partition(key string, partitions int) int {
+	if key == "" {
+		b.next = (b.next + 1) % partitions
+		return b.next
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum32() % uint32(partitions))
+}
+
+type memoryReader struct {
+	broker *MemoryBroker
+	config ConsumerConfig
+	// Nil without consumer group
+	group *memoryGroup
+	// Next offset of each assigned partition
+	positions map[topicPartition]int64
+	closed    bool
+}
+
+// FetchMessage returns the oldest message not yet fetched from the assigned
+// partitions, waiting for one if needed.
+func (r *memoryReader) FetchMessage(ctx context.Context) (Message, error) {
+	b := r.broker
+
+	for {
+		b.mu.Lock()
+		if r.closed {
+			b.mu.Unlock()
+			return Message{}, ErrClosed
+		}
+
+		var next *Message
+		for tp, offset := range r.positions {
+			partition := b.topics[tp.topic][tp.partition]
+			if offset >= int64(len(partition)) {
+				continue
+			}
+			if m := &partition[offset]; next == nil || m.Time.Before(next.Time) {
+				next = m
+			}
+		}
+
+		if next != nil {
+			r.positions[topicPartition{next.Topic, next.Partition}] = next.Offset + 1
+			m := next.copy()
+			b.mu.Unlock()
+			return m, nil
+		}
+
+		changed := b.changed
+		b.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return Message{}, ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+func (r *memoryReader) CommitMessages(ctx context.Context, msgs ...Message) error {
+	b := r.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if r.closed {
+		return ErrClosed
+	}
+	if r.group == nil {
+		return nil
+	}
+
+	for _, m := range msgs {
+		tp := topicPartition{m.Topic, m.Partition}
+		if m.Offset+1 > r.group.committed[tp] {
+			r.group.committed[tp] = m.Offset + 1
+		}
+	}
+
+	b.notify()
+	return nil
+}
+
+// Close leaves the group, its partitions go to the remaining members.
+func (r *memoryReader) Close() error {
+	b := r.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+
+	if r.group != nil {
+		r.group.members = slices.DeleteFunc(r.group.members, func(m *memoryReader) bool { return m == r })
+		b.rebalance(r.group)
+	}
+
+	b.notify()
+	return nil
+}
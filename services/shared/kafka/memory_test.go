@@ -0,0 +1,310 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type event struct {
+	ID    string `json:"id"`
+	Count int    `json:"count"`
+}
+
+func newMemoryConfig(partitions int) (Config, *MemoryBroker) {
+	broker := NewMemoryBroker(partitions)
+	return Config{Broker: broker}, broker
+}
+
+func testContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func send(t *testing.T, ctx context.Context, config Config, topic string, events ...event) {
+	t.Helper()
+
+	producer, err := NewProducer[event](config, DefaultProducerConfig(topic))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer producer.Close()
+
+	for _, e := range events {
+		if err := producer.Send(ctx, e.ID, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// collect runs a consumer until it has handled n messages.
+func collect(t *testing.T, ctx context.Context, consumer *Consumer, n int) []event {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var events []event
+	err := Run(ctx, consumer, func(ctx context.Context, m Message, e event) error {
+		events = append(events, e)
+		if len(events) == n {
+			cancel()
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != n {
+		t.Fatalf("handled %d messages, want %d", len(events), n)
+	}
+	return events
+}
+
+func TestMemoryBrokerProduceConsume(t *testing.T) {
+	ctx := testContext(t)
+	config, broker := newMemoryConfig(3)
+
+	send(t, ctx, config, "orders", event{"a", 1}, event{"b", 2}, event{"a", 3})
+
+	messages := broker.Messages("orders")
+	if len(messages) != 3 {
+		t.Fatalf("topic holds %d messages, want 3", len(messages))
+	}
+	for _, m := range messages {
+		if m.Headers[HEADER_CONTENT_TYPE] != JSON.ContentType() {
+			t.Errorf("content-type = %q", m.Headers[HEADER_CONTENT_TYPE])
+		}
+	}
+
+	consumer, err := NewConsumer(config, DefaultConsumerConfig("group", "orders"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer consumer.Close()
+
+	events := collect(t, ctx, consumer, 3)
+
+	// Same key, same partition: "a" events keep their order
+	var counts []int
+	for _, e := range events {
+		if e.ID == "a" {
+			counts = append(counts, e.Count)
+		}
+	}
+	if len(counts) != 2 || counts[0] != 1 || counts[1] != 3 {
+		t.Errorf("events of key a handled as %v, want [1 3]", counts)
+	}
+}
+
+func TestMemoryBrokerGroupResumesFromCommit(t *testing.T) {
+	ctx := testContext(t)
+	config, broker := newMemoryConfig(2)
+
+	send(t, ctx, config, "orders", event{"a", 1}, event{"b", 2})
+
+	consumer, err := NewConsumer(config, DefaultConsumerConfig("group", "orders"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	collect(t, ctx, consumer, 2)
+	consumer.Close()
+
+	send(t, ctx, config, "orders", event{"c", 3})
+
+	consumer, err = NewConsumer(config, DefaultConsumerConfig("group", "orders"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer consumer.Close()
+
+	if events := collect(t, ctx, consumer, 1); events[0].ID != "c" {
+		t.Errorf("resumed with %v, want c", events[0])
+	}
+
+	offsets, err := broker.CommittedOffsets(ctx, "group", []string{"orders"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var committed int64
+	for _, offset := range offsets["orders"] {
+		committed += max(offset, 0)
+	}
+	if committed != 3 {
+		t.Errorf("committed %v, want 3 messages in total", offsets)
+	}
+}
+
+func TestMemoryBrokerGroupSharesPartitions(t *testing.T) {
+	ctx := testContext(t)
+	config, _ := newMemoryConfig(4)
+
+	first, err := NewConsumer(config, DefaultConsumerConfig("group", "orders"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+	second, err := NewConsumer(config, DefaultConsumerConfig("group", "orders"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+
+	var events []event
+	for i := range 20 {
+		events = append(events, event{string(rune('a' + i)), i})
+	}
+	send(t, ctx, config, "orders", events...)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	seen := make(map[string]int)
+	partitions := map[*Consumer]map[int]bool{first: {}, second: {}}
+
+	var wg sync.WaitGroup
+	for _, consumer := range []*Consumer{first, second} {
+		wg.Go(func() {
+			_ = Run(ctx, consumer, func(ctx context.Context, m Message, e event) error {
+				mu.Lock()
+				defer mu.Unlock()
+				seen[e.ID]++
+				partitions[consumer][m.Partition] = true
+				if len(seen) == len(events) {
+					cancel()
+				}
+				return nil
+			})
+		})
+	}
+	wg.Wait()
+
+	for id, n := range seen {
+		if n != 1 {
+			t.Errorf("%s handled %d times", id, n)
+		}
+	}
+	for p := range partitions[first] {
+		if partitions[second][p] {
+			t.Errorf("partition %d read by both members", p)
+		}
+	}
+}
+
+func TestMemoryBrokerRetriesAndSurfacesErrors(t *testing.T) {
+	ctx := testContext(t)
+	config, _ := newMemoryConfig(1)
+
+	send(t, ctx, config, "orders", event{"a", 1}, event{"b", 2})
+
+	var failed []string
+	cc := DefaultConsumerConfig("group", "orders")
+	cc.Retry = RetryPolicy{Attempts: 3, Backoff: time.Millisecond}
+	cc.OnError = func(m Message, err error) {
+		failed = append(failed, m.Key)
+	}
+
+	consumer, err := NewConsumer(config, cc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer consumer.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	attempts := make(map[string]int)
+	err = Run(ctx, consumer, func(ctx context.Context, m Message, e event) error {
+		attempts[e.ID]++
+		if e.ID == "a" {
+			return errors.New("failing")
+		}
+		cancel()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if attempts["a"] != 3 || attempts["b"] != 1 {
+		t.Errorf("attempts = %v, want a:3 b:1", attempts)
+	}
+	if len(failed) != 1 || failed[0] != "a" {
+		t.Errorf("OnError called for %v, want [a]", failed)
+	}
+}
+
+func TestMemoryBrokerPropagatesCorrelationID(t *testing.T) {
+	ctx := testContext(t)
+	config, broker := newMemoryConfig(1)
+
+	send(t, WithCorrelationID(ctx, "request-1"), config, "in", event{"a", 1})
+
+	out, err := NewProducer[event](config, DefaultProducerConfig("out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	consumer, err := NewConsumer(config, DefaultConsumerConfig("group", "in"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer consumer.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go Run(ctx, consumer, func(ctx context.Context, m Message, e event) error {
+		return out.Send(ctx, e.ID, e)
+	})
+
+	messages, err := broker.WaitMessages(ctx, "out", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id := messages[0].Headers[HEADER_CORRELATION_ID]; id != "request-1" {
+		t.Errorf("correlation-id = %q, want request-1", id)
+	}
+}
+
+func TestMemoryBrokerResetOffsets(t *testing.T) {
+	ctx := testContext(t)
+	config, broker := newMemoryConfig(2)
+
+	send(t, ctx, config, "orders", event{"a", 1})
+	at := time.Now()
+	send(t, ctx, config, "orders", event{"b", 2}, event{"c", 3})
+
+	consumer, err := NewConsumer(config, DefaultConsumerConfig("group", "orders"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	collect(t, ctx, consumer, 3)
+
+	if _, err := broker.ResetOffsets(ctx, "group", []string{"orders"}, at); err == nil {
+		t.Error("reset of a group with an active member succeeded")
+	}
+	consumer.Close()
+
+	if _, err := ResetOffsets(ctx, config, "group", []string{"orders"}, at); err != nil {
+		t.Fatal(err)
+	}
+
+	consumer, err = NewConsumer(config, DefaultConsumerConfig("group", "orders"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer consumer.Close()
+
+	seen := make(map[string]bool)
+	for _, e := range collect(t, ctx, consumer, 2) {
+		seen[e.ID] = true
+	}
+	if !seen["b"] || !seen["c"] {
+		t.Errorf("replayed %v, want b and c", seen)
+	}
+}
@@ -0,0 +1,58 @@
+package kafka
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// Headers set by producers
+const (
+	HEADER_CONTENT_TYPE   = "content-type"
+	HEADER_CORRELATION_ID = "correlation-id"
+)
+
+// Message is a consumed record.
+type Message struct {
+	Topic     string
+	Partition int
+	Offset    int64
+	Key       string
+	Value     []byte
+	Headers   map[string]string
+	Time      time.Time
+
+	codec Codec
+}
+
+// Decode reads the value with the codec named by its content-type header.
+func (m Message) Decode(v any) error {
+	codec := m.codec
+	if codec == nil {
+		codec = JSON
+	}
+	return codec.Decode(m.Value, v)
+}
+
+type correlationKey struct{}
+
+// WithCorrelationID returns a context whose produced messages carry id in
+// their correlation-id header.
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationKey{}, id)
+}
+
+// CorrelationID returns the id set by WithCorrelationID, or by the consumer
+// runner from the message being handled.
+func CorrelationID(ctx context.Context) string {
+	id, _ := ctx.Value(correlationKey{}).(string)
+	return id
+}
+
+// NewID returns a random hex id, for event and correlation ids.
+func NewID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
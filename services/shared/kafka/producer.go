@@ -0,0 +1,131 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// Compression codecs accepted by ProducerConfig
+const (
+	COMPRESSION_NONE   = ""
+	COMPRESSION_GZIP   = "gzip"
+	COMPRESSION_SNAPPY = "snappy"
+	COMPRESSION_LZ4    = "lz4"
+	COMPRESSION_ZSTD   = "zstd"
+)
+
+type ProducerConfig struct {
+	Topic string
+	// JSON when nil
+	Codec       Codec
+	Compression string
+	// Messages grouped in one request, and the longest a message waits for
+	// its batch to fill. Send blocks until its batch is written.
+	BatchSize    int
+	BatchTimeout time.Duration
+	// Attempts per batch before Send fails, with exponential backoff
+	MaxAttempts int
+	BackoffMin  time.Duration
+	BackoffMax  time.Duration
+}
+
+func DefaultProducerConfig(topic string) ProducerConfig {
+	return ProducerConfig{
+		Topic:        topic,
+		Codec:        JSON,
+		BatchSize:    100,
+		BatchTimeout: 10 * time.Millisecond,
+		MaxAttempts:  5,
+		BackoffMin:   100 * time.Millisecond,
+		BackoffMax:   2 * time.Second,
+	}
+}
+
+func compression(name string) (kafkago.Compression, error) {
+	switch name {
+	case COMPRESSION_NONE:
+		return 0, nil
+	case COMPRESSION_GZIP:
+		return kafkago.Gzip, nil
+	case COMPRESSION_SNAPPY:
+		return kafkago.Snappy, nil
+	case COMPRESSION_LZ4:
+		return kafkago.Lz4, nil
+	case COMPRESSION_ZSTD:
+		return kafkago.Zstd, nil
+	}
+	return 0, fmt.Errorf("unknown compression %q", name)
+}
+
+// Record is a message to produce.
+type Record[T any] struct {
+	Key     string
+	Value   T
+	Headers map[string]string
+}
+
+// Producer writes values of type T to one topic.
+type Producer[T any] struct {
+	topic  string
+	codec  Codec
+	writer BrokerWriter
+}
+
+func NewProducer[T any](config Config, pc ProducerConfig) (*Producer[T], error) {
+	if pc.Codec == nil {
+		pc.Codec = JSON
+	}
+
+	writer, err := config.broker().Writer(pc)
+	if err != nil {
+		return nil, err
+	}
+	Produces[T](config.Registry, pc.Topic)
+
+	return &Producer[T]{topic: pc.Topic, codec: pc.Codec, writer: writer}, nil
+}
+
+func (p *Producer[T]) Topic() string {
+	return p.topic
+}
+
+// Send writes one value. Messages with the same key go to the same
+// partition, so they are consumed in order.
+func (p *Producer[T]) Send(ctx context.Context, key string, value T) error {
+	return p.SendBatch(ctx, Record[T]{Key: key, Value: value})
+}
+
+// SendBatch writes the records in one call, blocking until they are all
+// acknowledged or the retries are exhausted.
+func (p *Producer[T]) SendBatch(ctx context.Context, records ...Record[T]) error {
+	messages := make([]Message, 0, len(records))
+
+	for _, r := range records {
+		value, err := p.codec.Encode(r.Value)
+		if err != nil {
+			return fmt.Errorf("encoding message for %s: %w", p.topic, err)
+		}
+
+		headers := map[string]string{HEADER_CONTENT_TYPE: p.codec.ContentType()}
+		if id := CorrelationID(ctx); id != "" {
+			headers[HEADER_CORRELATION_ID] = id
+		}
+		for k, v := range r.Headers {
+			headers[k] = v
+		}
+
+		messages = append(messages, Message{Topic: p.topic, Key: r.Key, Value: value, Headers: headers})
+	}
+
+	if err := p.writer.WriteMessages(ctx, messages...); err != nil {
+		return fmt.Errorf("writing to %s: %w", p.topic, err)
+	}
+	return nil
+}
+
+func (p *Producer[T]) Close() error {
+	return p.writer.Close()
+}
@@ -0,0 +1,161 @@
+package kafka
+
+import (
+	"cmp"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"slices"
+	"sync"
+)
+
+// Roles of a service on a topic
+const (
+	ROLE_PRODUCER = "producer"
+	ROLE_CONSUMER = "consumer"
+)
+
+// Endpoint is a topic a service produces to or consumes from.
+type Endpoint struct {
+	Topic string `json:"topic"`
+	Role  string `json:"role"`
+	// Name of the Go type of the events, empty when undeclared
+	Event string `json:"event,omitempty"`
+	// Consumer group, consumers only
+	Group string `json:"group,omitempty"`
+	// JSON Schema of the events, see SchemaOf
+	Payload *Schema `json:"payload,omitempty"`
+	// Go type of the events, nil when undeclared
+	Type reflect.Type `json:"-"`
+}
+
+// Topology is what a service declared in its Registry.
+type Topology struct {
+	Service   string     `json:"service"`
+	Endpoints []Endpoint `json:"endpoints"`
+}
+
+// Registry records the topics of a service and the event types on each.
+// Producers register themselves when created with a Config holding the
+// registry. Consumers register their topics too, but their event type is
+// only known by their handler: services declare it with Consumes.
+type Registry struct {
+	service string
+
+	mu        sync.Mutex
+	endpoints []Endpoint
+}
+
+func NewRegistry(service string) *Registry {
+	return &Registry{service: service}
+}
+
+func (r *Registry) Service() string {
+	return r.service
+}
+
+// Produces declares that the service writes T to topic. A nil registry
+// ignores the declaration.
+func Produces[T any](r *Registry, topic string) {
+	r.add(Endpoint{Topic: topic, Role: ROLE_PRODUCER}, reflect.TypeFor[T]())
+}
+
+// Consumes declares that the service reads T from topic in a consumer
+// group. A nil registry ignores the declaration.
+func Consumes[T any](r *Registry, group, topic string) {
+	r.add(Endpoint{Topic: topic, Role: ROLE_CONSUMER, Group: group}, reflect.TypeFor[T]())
+}
+
+// add records e with its event type t, nil when unknown. An undeclared
+// endpoint takes the type of the first declaration, a topic can carry
+// several types.
+func (r *Registry) add(e Endpoint, t reflect.Type) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if t != nil {
+		e.Type, e.Event, e.Payload = t, typeName(t), SchemaOf(t)
+	}
+
+	for i, existing := range r.endpoints {
+		if existing.Topic != e.Topic || existing.Role != e.Role || existing.Group != e.Group {
+			continue
+		}
+		switch {
+		case existing.Type == e.Type || e.Type == nil:
+			return
+		case existing.Type == nil:
+			r.endpoints[i] = e
+			return
+		}
+	}
+	r.endpoints = append(r.endpoints, e)
+}
+
+// typeName is the name of a Go type without its package, e.g. StockReserved
+// or map[string]interface {}.
+func typeName(t reflect.Type) string {
+	if t.Name() != "" {
+		return t.Name()
+	}
+	return t.String()
+}
+
+// Topology returns the endpoints sorted by topic, role and event.
+func (r *Registry) Topology() Topology {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	endpoints := slices.Clone(r.endpoints)
+	slices.SortFunc(endpoints, func(a, b Endpoint) int {
+		return cmp.Or(cmp.Compare(a.Topic, b.Topic), cmp.Compare(a.Role, b.Role), cmp.Compare(a.Event, b.Event))
+	})
+	return Topology{Service: r.service, Endpoints: endpoints}
+}
+
+// AsyncAPI describes the topics of the service alone, see NewAsyncAPI to
+// combine several services.
+func (r *Registry) AsyncAPI() *AsyncAPI {
+	return NewAsyncAPI(AsyncAPIInfo{Title: r.service, Version: ASYNCAPI_DOC_VERSION}, r.Topology())
+}
+
+// ServeHTTP serves the topology as JSON, at GET /topology in every service.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if !allowGet(w, req) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(r.Topology())
+}
+
+// AsyncAPIHandler serves AsyncAPI as JSON, or YAML with ?format=yaml, at
+// GET /asyncapi in every service.
+func (r *Registry) AsyncAPIHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !allowGet(w, req) {
+			return
+		}
+
+		if req.URL.Query().Get("format") == "yaml" {
+			w.Header().Set("Content-Type", "application/yaml")
+			_ = r.AsyncAPI().WriteYAML(w)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(r.AsyncAPI())
+	})
+}
+
+func allowGet(w http.ResponseWriter, req *http.Request) bool {
+	if req.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,138 @@
+package kafka
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRegistryRecordsProducersAndConsumers(t *testing.T) {
+	registry := NewRegistry("orders-service")
+	config := Config{Broker: NewMemoryBroker(1), Registry: registry}
+
+	producer, err := NewProducer[event](config, DefaultProducerConfig("orders"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer producer.Close()
+
+	consumer, err := NewConsumer(config, DefaultConsumerConfig("group", "paid", "shipped"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer consumer.Close()
+	Consumes[event](registry, "group", "paid")
+	// Declared twice, kept once
+	Consumes[event](registry, "group", "paid")
+
+	res := httptest.NewRecorder()
+	registry.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/topology", nil))
+
+	var topology Topology
+	if err := json.Unmarshal(res.Body.Bytes(), &topology); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Endpoint{
+		{Topic: "orders", Role: ROLE_PRODUCER, Event: "event"},
+		{Topic: "paid", Role: ROLE_CONSUMER, Event: "event", Group: "group"},
+		{Topic: "shipped", Role: ROLE_CONSUMER, Group: "group"},
+	}
+	if topology.Service != "orders-service" || len(topology.Endpoints) != len(want) {
+		t.Fatalf("topology = %+v", topology)
+	}
+	for i, e := range topology.Endpoints {
+		// The payload is described for the typed endpoints only
+		if (e.Payload != nil) != (e.Event != "") {
+			t.Errorf("endpoint %d payload = %+v", i, e.Payload)
+		}
+		e.Payload = nil
+		if e != want[i] {
+			t.Errorf("endpoint %d = %+v, want %+v", i, e, want[i])
+		}
+	}
+
+	// A nil registry ignores the declarations
+	Consumes[event](nil, "group", "paid")
+}
+
+func TestTopologyRendering(t *testing.T) {
+	topologies := []Topology{
+		{Service: "payments-service", Endpoints: []Endpoint{
+			{Topic: "payment.done", Role: ROLE_PRODUCER, Event: "OrderPlaced"},
+		}},
+		{Service: "orders-service", Endpoints: []Endpoint{
+			{Topic: "order.shipment", Role: ROLE_CONSUMER, Event: "ShipmentEvent", Group: "orders-group"},
+			{Topic: "payment.done", Role: ROLE_CONSUMER, Event: "PaidOrder", Group: "orders-group"},
+		}},
+	}
+
+	mermaid := Mermaid(topologies...)
+	for _, line := range []string{
+		"s_payments_service -- OrderPlaced --> t_payment_done",
+		"t_payment_done -- PaidOrder --> s_orders_service",
+		"t_order_shipment([order.shipment])",
+	} {
+		if !strings.Contains(mermaid, line) {
+			t.Errorf("mermaid misses %q:\n%s", line, mermaid)
+		}
+	}
+
+	if dot := Graphviz(topologies...); !strings.Contains(dot, `"payments-service" -> "payment.done" [label="OrderPlaced"];`) {
+		t.Errorf("graphviz:\n%s", dot)
+	}
+
+	doc := NewAsyncAPI(AsyncAPIInfo{Title: "test", Version: "1"}, topologies...)
+	// The producer's event describes the channel, the consumer's own type
+	// is only used for a topic produced outside
+	paid := doc.Channels["payment_done"]
+	if paid == nil || paid.Address != "payment.done" || len(paid.Messages) != 1 || paid.Messages["OrderPlaced"].Ref == "" {
+		t.Errorf("payment.done channel = %+v", paid)
+	}
+	if shipment := doc.Channels["order_shipment"]; shipment == nil || len(shipment.Messages) != 1 {
+		t.Errorf("order.shipment channel = %+v", shipment)
+	}
+
+	receive, ok := doc.Operations["orders-service_receive_payment_done"]
+	if !ok || receive.Action != "receive" || len(receive.Messages) != 1 || receive.Bindings.Kafka.GroupID.Enum[0] != "orders-group" {
+		t.Errorf("operations = %+v", doc.Operations)
+	}
+}
+
+func TestAsyncAPIMessages(t *testing.T) {
+	payments := NewRegistry("payments-service")
+	Produces[event](payments, "payment.done")
+	Produces[event](payments, "payment.refunded")
+
+	// Another event type under the same name
+	inventories := Topology{Service: "inventory-service", Endpoints: []Endpoint{
+		{Topic: "stock.reserved", Role: ROLE_PRODUCER, Event: "event", Payload: &Schema{Type: "object"}},
+	}}
+
+	// The same event type, with its own example
+	orders := Topology{Service: "orders-service", Endpoints: []Endpoint{
+		{Topic: "order.created", Role: ROLE_PRODUCER, Event: "event", Payload: SchemaOf(reflect.TypeFor[event]())},
+	}}
+	orders.Endpoints[0].Payload.Properties["id"].Examples = []any{"42"}
+
+	doc := NewAsyncAPI(AsyncAPIInfo{Title: "test", Version: "1"}, payments.Topology(), inventories, orders)
+	if len(doc.Components.Messages) != 2 {
+		t.Fatalf("messages = %+v", doc.Components.Messages)
+	}
+	msg, ok := doc.Components.Messages["event"]
+	if !ok || msg.Payload.Properties["count"].Type != "integer" || len(msg.Examples) != 1 {
+		t.Errorf("event = %+v", msg)
+	}
+	if _, ok := doc.Channels["stock_reserved"].Messages["event.inventory-service"]; !ok {
+		t.Errorf("stock.reserved channel = %+v", doc.Channels["stock_reserved"])
+	}
+
+	res := httptest.NewRecorder()
+	payments.AsyncAPIHandler().ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/asyncapi?format=yaml", nil))
+	if body := res.Body.String(); !strings.HasPrefix(body, "asyncapi: 3.0.0") || !strings.Contains(body, "title: payments-service") {
+		t.Errorf("yaml =\n%s", body)
+	}
+}
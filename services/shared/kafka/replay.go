@@ -0,0 +1,238 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+)
+
+// ReplayConfig selects the messages Replay reads again.
+type ReplayConfig struct {
+	Topics []string
+	// First offset read in every partition when not negative. Otherwise the
+	// first message at or after From, the oldest message kept when From is
+	// zero.
+	Offset int64
+	From   time.Time
+	// Messages at or after To are not read, nor the messages written after
+	// the replay started.
+	To time.Time
+	// Only the messages with this key when set
+	Key string
+	// Only the messages for which Filter returns true when set
+	Filter func(Message) bool
+	// Used for messages without content-type header, JSON when nil
+	Codec Codec
+	// Called with the running stats every ProgressEvery messages read, and
+	// once at the end
+	Progress      func(ReplayStats)
+	ProgressEvery int64
+	// Called with messages that can't be decoded or whose handler fails.
+	// The replay goes on without them.
+	OnError func(Message, error)
+}
+
+func DefaultReplayConfig(topics ...string) ReplayConfig {
+	return ReplayConfig{
+		Topics:        topics,
+		Offset:        -1,
+		Codec:         JSON,
+		ProgressEvery: 1000,
+		OnError:       DefaultConsumerConfig("").OnError,
+	}
+}
+
+// ReplayStats counts the messages of a replay.
+type ReplayStats struct {
+	// Messages between the start and end offsets, filtered or not
+	Total   int64
+	Read    int64
+	Skipped int64
+	Handled int64
+	Failed  int64
+}
+
+func (s ReplayStats) String() string {
+	percent := 100.0
+	if s.Total > 0 {
+		percent = float64(s.Read) * 100 / float64(s.Total)
+	}
+	return fmt.Sprintf("%d/%d read (%.1f%%), %d handled, %d skipped, %d failed",
+		s.Read, s.Total, percent, s.Handled, s.Skipped, s.Failed)
+}
+
+// replayPartition is a partition being replayed, up to end (excluded).
+type replayPartition struct {
+	reader BrokerReader
+	end    int64
+	// Next message to hand over, nil once the partition is done
+	head *Message
+}
+
+// next fetches the following message of the partition into head.
+func (p *replayPartition) next(ctx context.Context) error {
+	p.head = nil
+
+	m, err := p.reader.FetchMessage(ctx)
+	if err != nil {
+		return err
+	}
+	if m.Offset < p.end {
+		p.head = &m
+	}
+	return nil
+}
+
+// Replay reads the selected messages once, outside any consumer group:
+// nothing is committed and no group moves. Messages are handed to handle
+// oldest first across topics and partitions, and in order within a
+// partition, so handlers see the events of a key in the order they were
+// produced. Replay returns once every partition is read, or with the error
+// of a failed fetch or ctx.
+func Replay[T any](ctx context.Context, config Config, rc ReplayConfig, handle Handler[T]) (ReplayStats, error) {
+	if rc.Codec == nil {
+		rc.Codec = JSON
+	}
+	if rc.OnError == nil {
+		rc.OnError = DefaultReplayConfig().OnError
+	}
+	if len(rc.Topics) == 0 {
+		return ReplayStats{}, errors.New("replay needs at least one topic")
+	}
+
+	broker := config.broker()
+
+	var stats ReplayStats
+	var partitions []*replayPartition
+	defer func() {
+		for _, p := range partitions {
+			_ = p.reader.Close()
+		}
+	}()
+
+	for _, topic := range rc.Topics {
+		start, end, err := replayRange(ctx, broker, topic, rc)
+		if err != nil {
+			return stats, fmt.Errorf("%s: %w", topic, err)
+		}
+
+		// Sorted, so messages written at the same time are replayed in the
+		// same order every time
+		for _, partition := range slices.Sorted(maps.Keys(start)) {
+			from := start[partition]
+			if from >= end[partition] {
+				continue
+			}
+
+			reader, err := broker.PartitionReader(topic, partition, from)
+			if err != nil {
+				return stats, fmt.Errorf("%s/%d: %w", topic, partition, err)
+			}
+			p := &replayPartition{reader: reader, end: end[partition]}
+			partitions = append(partitions, p)
+			stats.Total += p.end - from
+
+			if err := p.next(ctx); err != nil {
+				return stats, err
+			}
+		}
+	}
+
+	for {
+		// Oldest head across the partitions
+		var oldest *replayPartition
+		for _, p := range partitions {
+			if p.head != nil && (oldest == nil || p.head.Time.Before(oldest.head.Time)) {
+				oldest = p
+			}
+		}
+		if oldest == nil {
+			break
+		}
+
+		m := *oldest.head
+		stats.Read++
+
+		switch {
+		case rc.Key != "" && m.Key != rc.Key, rc.Filter != nil && !rc.Filter(m):
+			stats.Skipped++
+		default:
+			if err := replayMessage(ctx, rc, m, handle); err != nil {
+				stats.Failed++
+				rc.OnError(m, err)
+			} else {
+				stats.Handled++
+			}
+		}
+
+		if rc.Progress != nil && rc.ProgressEvery > 0 && stats.Read%rc.ProgressEvery == 0 {
+			rc.Progress(stats)
+		}
+
+		if m.Offset+1 < oldest.end {
+			if err := oldest.next(ctx); err != nil {
+				return stats, err
+			}
+		} else {
+			oldest.head = nil
+		}
+	}
+
+	if rc.Progress != nil {
+		rc.Progress(stats)
+	}
+	return stats, nil
+}
+
+// replayRange returns the offsets a replay reads in every partition of a
+// topic: from start (included) to end (excluded).
+func replayRange(ctx context.Context, broker Broker, topic string, rc ReplayConfig) (start, end map[int]int64, err error) {
+	end, err = broker.EndOffsets(ctx, topic)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !rc.To.IsZero() {
+		to, err := broker.OffsetsAt(ctx, topic, rc.To)
+		if err != nil {
+			return nil, nil, err
+		}
+		for p, offset := range to {
+			end[p] = min(end[p], offset)
+		}
+	}
+
+	if rc.Offset < 0 {
+		start, err = broker.OffsetsAt(ctx, topic, rc.From)
+		return start, end, err
+	}
+
+	// Offsets already deleted by retention start at the oldest message kept
+	first, err := broker.OffsetsAt(ctx, topic, time.Time{})
+	if err != nil {
+		return nil, nil, err
+	}
+	start = make(map[int]int64)
+	for p, offset := range first {
+		start[p] = max(offset, rc.Offset)
+	}
+	return start, end, nil
+}
+
+// replayMessage decodes the message and hands it to handle once, in a
+// context carrying its correlation id.
+func replayMessage[T any](ctx context.Context, rc ReplayConfig, m Message, handle Handler[T]) error {
+	var err error
+	if m.codec, err = codecFor(m.Headers[HEADER_CONTENT_TYPE], rc.Codec); err != nil {
+		return err
+	}
+
+	var value T
+	if err := m.Decode(&value); err != nil {
+		return fmt.Errorf("decoding: %w", err)
+	}
+	return handleMessage(ctx, RetryPolicy{Attempts: 1}, m, value, handle)
+}
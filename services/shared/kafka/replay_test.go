@@ -0,0 +1,129 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// replay collects the events replayed with rc
+func replay(t *testing.T, ctx context.Context, config Config, rc ReplayConfig) ([]string, ReplayStats) {
+	t.Helper()
+
+	var ids []string
+	stats, err := Replay(ctx, config, rc, func(ctx context.Context, m Message, e event) error {
+		ids = append(ids, fmt.Sprintf("%s%d", e.ID, e.Count))
+		if e.Count < 0 {
+			return errors.New("negative count")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ids, stats
+}
+
+func TestReplayMergesTopicsInTimeOrder(t *testing.T) {
+	ctx := testContext(t)
+	config, broker := newMemoryConfig(3)
+
+	send(t, ctx, config, "paid", event{"a", 1})
+	send(t, ctx, config, "reserved", event{"a", 2})
+	send(t, ctx, config, "paid", event{"b", 3})
+	send(t, ctx, config, "reserved", event{"b", 4})
+
+	ids, stats := replay(t, ctx, config, DefaultReplayConfig("reserved", "paid"))
+	if got := fmt.Sprint(ids); got != "[a1 a2 b3 b4]" {
+		t.Errorf("replayed %s", got)
+	}
+	if stats.Total != 4 || stats.Read != 4 || stats.Handled != 4 {
+		t.Errorf("stats = %+v", stats)
+	}
+
+	// No group moved: a new group still reads everything
+	offsets, err := broker.CommittedOffsets(ctx, "group", []string{"paid"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for p, offset := range offsets["paid"] {
+		if offset != -1 {
+			t.Errorf("partition %d committed at %d", p, offset)
+		}
+	}
+}
+
+func TestReplaySelectsMessages(t *testing.T) {
+	ctx := testContext(t)
+	config, _ := newMemoryConfig(1)
+
+	send(t, ctx, config, "orders", event{"a", 1}, event{"b", 2})
+	mid := time.Now()
+	send(t, ctx, config, "orders", event{"a", 3}, event{"b", 4})
+	end := time.Now()
+	send(t, ctx, config, "orders", event{"a", 5})
+
+	for _, tc := range []struct {
+		name  string
+		setup func(rc *ReplayConfig)
+		want  string
+	}{
+		{"all", func(rc *ReplayConfig) {}, "[a1 b2 a3 b4 a5]"},
+		{"offset", func(rc *ReplayConfig) { rc.Offset = 3 }, "[b4 a5]"},
+		{"from", func(rc *ReplayConfig) { rc.From = mid }, "[a3 b4 a5]"},
+		{"range", func(rc *ReplayConfig) { rc.From, rc.To = mid, end }, "[a3 b4]"},
+		{"key", func(rc *ReplayConfig) { rc.Key = "b" }, "[b2 b4]"},
+		{"filter", func(rc *ReplayConfig) {
+			rc.Filter = func(m Message) bool { return m.Offset%2 == 0 }
+		}, "[a1 a3 a5]"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rc := DefaultReplayConfig("orders")
+			tc.setup(&rc)
+
+			ids, stats := replay(t, ctx, config, rc)
+			if got := fmt.Sprint(ids); got != tc.want {
+				t.Errorf("replayed %s, want %s", got, tc.want)
+			}
+			if stats.Handled != int64(len(ids)) || stats.Read != stats.Handled+stats.Skipped {
+				t.Errorf("stats = %+v", stats)
+			}
+		})
+	}
+}
+
+func TestReplayStopsAtStartEnd(t *testing.T) {
+	ctx := testContext(t)
+	config, _ := newMemoryConfig(2)
+
+	send(t, ctx, config, "orders", event{"a", 1}, event{"b", 2}, event{"c", -1})
+
+	var progress []ReplayStats
+	rc := DefaultReplayConfig("orders")
+	rc.ProgressEvery = 2
+	rc.Progress = func(s ReplayStats) { progress = append(progress, s) }
+	rc.OnError = func(Message, error) {}
+
+	stats, err := Replay(ctx, config, rc, func(ctx context.Context, m Message, e event) error {
+		// Written during the replay: not read
+		if e.ID == "a" {
+			send(t, ctx, config, "orders", event{"d", 4})
+		}
+		if e.Count < 0 {
+			return errors.New("negative count")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stats.Total != 3 || stats.Handled != 2 || stats.Failed != 1 {
+		t.Errorf("stats = %+v", stats)
+	}
+	if len(progress) != 2 || progress[1] != stats {
+		t.Errorf("progress = %+v", progress)
+	}
+}
@@ -0,0 +1,193 @@
+package kafka
+
+import (
+	"encoding"
+	"encoding/json"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema is the JSON Schema of an event payload, as AsyncAPI embeds it. An
+// empty schema accepts any value.
+type Schema struct {
+	Type                 string             `json:"type,omitempty" yaml:"type,omitempty"`
+	Format               string             `json:"format,omitempty" yaml:"format,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty" yaml:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty" yaml:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty" yaml:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty" yaml:"additionalProperties,omitempty"`
+	Examples             []any              `json:"examples,omitempty" yaml:"examples,omitempty"`
+}
+
+var (
+	timeType      = reflect.TypeFor[time.Time]()
+	marshalerType = reflect.TypeFor[json.Marshaler]()
+	textType      = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// SchemaOf describes the JSON encoding of t: properties named by the json
+// tags, required unless omitempty, and the example tag of each field, e.g.
+//
+//	OrderID string `json:"orderId" example:"42"`
+//
+// Examples of numbers and booleans are parsed, the ones of arrays, maps and
+// structs are JSON.
+func SchemaOf(t reflect.Type) *Schema {
+	return schemaOf(t, make(map[reflect.Type]bool))
+}
+
+// schemaOf stops on the types being described, recursive types end with an
+// empty schema.
+func schemaOf(t reflect.Type, seen map[reflect.Type]bool) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Implements(marshalerType) || reflect.PointerTo(t).Implements(marshalerType):
+		// Custom encoding, e.g. json.RawMessage
+		return &Schema{}
+	case t.Implements(textType) || reflect.PointerTo(t).Implements(textType):
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: schemaOf(t.Elem(), seen)}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: schemaOf(t.Elem(), seen)}
+	case reflect.Struct:
+		if seen[t] {
+			return &Schema{}
+		}
+		seen[t] = true
+		defer delete(seen, t)
+
+		s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+		addFields(s, t, seen)
+		return s
+	}
+	return &Schema{}
+}
+
+// addFields adds the fields of t to s, those of embedded structs included.
+func addFields(s *Schema, t reflect.Type, seen map[reflect.Type]bool) {
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" || !field.IsExported() && !field.Anonymous {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				addFields(s, embedded, seen)
+				continue
+			}
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := schemaOf(field.Type, seen)
+		if example, ok := field.Tag.Lookup("example"); ok {
+			property.Examples = []any{parseExample(property, example)}
+		}
+		s.Properties[name] = property
+
+		if !strings.Contains(options, "omitempty") && !strings.Contains(options, "omitzero") {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// parseExample reads the example tag of a field described by s.
+func parseExample(s *Schema, example string) any {
+	switch s.Type {
+	case "integer":
+		if n, err := strconv.ParseInt(example, 10, 64); err == nil {
+			return n
+		}
+	case "number":
+		if f, err := strconv.ParseFloat(example, 64); err == nil {
+			return f
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(example); err == nil {
+			return b
+		}
+	case "string":
+		return example
+	}
+
+	var v any
+	if err := json.Unmarshal([]byte(example), &v); err == nil {
+		return v
+	}
+	return example
+}
+
+// Equal tells whether s and other describe the same payload, whatever their
+// examples.
+func (s *Schema) Equal(other *Schema) bool {
+	if s == nil || other == nil {
+		return s == other
+	}
+	if s.Type != other.Type || s.Format != other.Format || !slices.Equal(s.Required, other.Required) ||
+		!s.Items.Equal(other.Items) || !s.AdditionalProperties.Equal(other.AdditionalProperties) {
+		return false
+	}
+	return maps.EqualFunc(s.Properties, other.Properties, (*Schema).Equal)
+}
+
+// Example assembles an example payload from the examples of the schema and
+// of its properties, nil when the schema has none.
+func (s *Schema) Example() any {
+	if s == nil {
+		return nil
+	}
+	if len(s.Examples) > 0 {
+		return s.Examples[0]
+	}
+
+	switch s.Type {
+	case "object":
+		example := make(map[string]any)
+		for name, property := range s.Properties {
+			if v := property.Example(); v != nil {
+				example[name] = v
+			}
+		}
+		if len(example) > 0 {
+			return example
+		}
+	case "array":
+		if v := s.Items.Example(); v != nil {
+			return []any{v}
+		}
+	}
+	return nil
+}
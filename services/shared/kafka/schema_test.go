@@ -0,0 +1,64 @@
+package kafka
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+type schemaItem struct {
+	SKU      string `json:"sku" example:"deck-8"`
+	Quantity int    `json:"quantity" example:"2"`
+}
+
+type schemaBase struct {
+	ID string `json:"id" example:"42"`
+}
+
+type schemaEvent struct {
+	schemaBase
+	Total    float64           `json:"total" example:"59.9"`
+	Paid     bool              `json:"paid,omitempty" example:"true"`
+	Items    []schemaItem      `json:"items"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	At       time.Time         `json:"at"`
+	Internal string            `json:"-"`
+	Parent   *schemaEvent      `json:"parent,omitempty"`
+}
+
+func TestSchemaOf(t *testing.T) {
+	s := SchemaOf(reflect.TypeFor[schemaEvent]())
+
+	if s.Type != "object" || len(s.Properties) != 7 {
+		t.Fatalf("schema = %+v", s)
+	}
+	if want := []string{"id", "total", "items", "at"}; !slices.Equal(s.Required, want) {
+		t.Errorf("required = %v, want %v", s.Required, want)
+	}
+	if at := s.Properties["at"]; at.Type != "string" || at.Format != "date-time" {
+		t.Errorf("at = %+v", at)
+	}
+	if items := s.Properties["items"]; items.Type != "array" || items.Items.Properties["sku"].Type != "string" {
+		t.Errorf("items = %+v", items)
+	}
+	if labels := s.Properties["labels"]; labels.AdditionalProperties.Type != "string" {
+		t.Errorf("labels = %+v", labels)
+	}
+	// Recursive types end with an empty schema
+	if parent := s.Properties["parent"]; parent.Type != "" {
+		t.Errorf("parent = %+v", parent)
+	}
+
+	example, ok := s.Example().(map[string]any)
+	if !ok {
+		t.Fatalf("example = %#v", s.Example())
+	}
+	if example["id"] != "42" || example["total"] != 59.9 || example["paid"] != true {
+		t.Errorf("example = %#v", example)
+	}
+	items, _ := example["items"].([]any)
+	if len(items) != 1 || items[0].(map[string]any)["quantity"] != int64(2) {
+		t.Errorf("example items = %#v", example["items"])
+	}
+}
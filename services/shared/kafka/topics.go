@@ -0,0 +1,446 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Cleanup policies
+const (
+	CLEANUP_DELETE  = "delete"
+	CLEANUP_COMPACT = "compact"
+	// Compacted, and segments older than the retention deleted
+	CLEANUP_COMPACT_DELETE = "compact,delete"
+)
+
+// RETENTION_FOREVER keeps every message of a topic.
+const RETENTION_FOREVER = "forever"
+
+// TopicSpec declares a topic. The zero fields of a topic take the value of
+// the defaults of its TopicSpecs.
+type TopicSpec struct {
+	Name        string `yaml:"name"`
+	Partitions  int    `yaml:"partitions"`
+	Replication int    `yaml:"replication"`
+	// Duration like 12h or 7d, or RETENTION_FOREVER
+	Retention string `yaml:"retention"`
+	// CLEANUP_DELETE, CLEANUP_COMPACT or CLEANUP_COMPACT_DELETE
+	Cleanup    string          `yaml:"cleanup"`
+	Compaction *CompactionSpec `yaml:"compaction"`
+	// Other topic configs, e.g. max.message.bytes
+	Config map[string]string `yaml:"config"`
+}
+
+// CompactionSpec tunes the compaction of a compacted topic.
+type CompactionSpec struct {
+	// Age before a message can be compacted away (min.compaction.lag.ms)
+	MinLag string `yaml:"minLag"`
+	// Time tombstones are kept once compacted (delete.retention.ms)
+	DeleteRetention string `yaml:"deleteRetention"`
+	// Share of the log not compacted yet that triggers a compaction
+	// (min.cleanable.dirty.ratio)
+	DirtyRatio float64 `yaml:"dirtyRatio"`
+}
+
+// TopicSpecs is a topics file, see ressources/topics.yaml.
+type TopicSpecs struct {
+	Defaults TopicSpec   `yaml:"defaults"`
+	Topics   []TopicSpec `yaml:"topics"`
+}
+
+// LoadTopicSpecs reads a topics file.
+func LoadTopicSpecs(path string) (TopicSpecs, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return TopicSpecs{}, err
+	}
+
+	specs, err := ParseTopicSpecs(data)
+	if err != nil {
+		return TopicSpecs{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return specs, nil
+}
+
+// ParseTopicSpecs decodes and validates a topics file. The topics of the
+// result have their defaults applied.
+func ParseTopicSpecs(data []byte) (TopicSpecs, error) {
+	var specs TopicSpecs
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&specs); err != nil {
+		return TopicSpecs{}, err
+	}
+
+	seen := make(map[string]bool)
+	for i, t := range specs.Topics {
+		t = t.withDefaults(specs.Defaults)
+		if err := t.validate(); err != nil {
+			return TopicSpecs{}, err
+		}
+		if seen[t.Name] {
+			return TopicSpecs{}, fmt.Errorf("topic %s declared twice", t.Name)
+		}
+		seen[t.Name] = true
+		specs.Topics[i] = t
+	}
+	return specs, nil
+}
+
+// Names returns the declared topics.
+func (s TopicSpecs) Names() []string {
+	names := make([]string, len(s.Topics))
+	for i, t := range s.Topics {
+		names[i] = t.Name
+	}
+	return names
+}
+
+// Lookup returns the spec of a topic.
+func (s TopicSpecs) Lookup(name string) (TopicSpec, bool) {
+	for _, t := range s.Topics {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return TopicSpec{}, false
+}
+
+// Undeclared returns the topics no spec declares, sorted. Internal topics
+// (__consumer_offsets...) are left out.
+func (s TopicSpecs) Undeclared(topics ...string) []string {
+	var undeclared []string
+	for _, topic := range topics {
+		if _, ok := s.Lookup(topic); !ok && !internalTopic(topic) && !slices.Contains(undeclared, topic) {
+			undeclared = append(undeclared, topic)
+		}
+	}
+	slices.Sort(undeclared)
+	return undeclared
+}
+
+func internalTopic(name string) bool {
+	return strings.HasPrefix(name, "__")
+}
+
+// withDefaults fills the zero fields of t.
+func (t TopicSpec) withDefaults(d TopicSpec) TopicSpec {
+	if t.Partitions == 0 {
+		t.Partitions = d.Partitions
+	}
+	if t.Replication == 0 {
+		t.Replication = d.Replication
+	}
+	if t.Retention == "" {
+		t.Retention = d.Retention
+	}
+	if t.Cleanup == "" {
+		t.Cleanup = d.Cleanup
+	}
+	if t.Compaction == nil && t.compacted() {
+		t.Compaction = d.Compaction
+	}
+
+	config := maps.Clone(d.Config)
+	if config == nil {
+		config = t.Config
+	} else {
+		maps.Copy(config, t.Config)
+	}
+	t.Config = config
+	return t
+}
+
+func (t TopicSpec) compacted() bool {
+	return strings.Contains(t.Cleanup, CLEANUP_COMPACT)
+}
+
+func (t TopicSpec) validate() error {
+	if t.Name == "" {
+		return errors.New("topic without name")
+	}
+	if t.Partitions < 1 {
+		return fmt.Errorf("%s: partitions must be at least 1", t.Name)
+	}
+	if t.Replication < 1 {
+		return fmt.Errorf("%s: replication must be at least 1", t.Name)
+	}
+	if t.Compaction != nil && !t.compacted() {
+		return fmt.Errorf("%s: compaction set on a topic that isn't compacted", t.Name)
+	}
+	if _, err := t.Configs(); err != nil {
+		return fmt.Errorf("%s: %w", t.Name, err)
+	}
+	return nil
+}
+
+// Configs returns the Kafka topic configs of the spec.
+func (t TopicSpec) Configs() (map[string]string, error) {
+	configs := make(map[string]string)
+
+	if t.Retention != "" {
+		ms, err := milliseconds(t.Retention)
+		if err != nil {
+			return nil, fmt.Errorf("retention: %w", err)
+		}
+		configs["retention.ms"] = ms
+	}
+
+	switch t.Cleanup {
+	case "":
+	case CLEANUP_DELETE, CLEANUP_COMPACT, CLEANUP_COMPACT_DELETE:
+		configs["cleanup.policy"] = t.Cleanup
+	case "delete,compact":
+		configs["cleanup.policy"] = CLEANUP_COMPACT_DELETE
+	default:
+		return nil, fmt.Errorf("unknown cleanup policy %q", t.Cleanup)
+	}
+
+	if c := t.Compaction; c != nil {
+		if c.MinLag != "" {
+			ms, err := milliseconds(c.MinLag)
+			if err != nil {
+				return nil, fmt.Errorf("minLag: %w", err)
+			}
+			configs["min.compaction.lag.ms"] = ms
+		}
+		if c.DeleteRetention != "" {
+			ms, err := milliseconds(c.DeleteRetention)
+			if err != nil {
+				return nil, fmt.Errorf("deleteRetention: %w", err)
+			}
+			configs["delete.retention.ms"] = ms
+		}
+		if c.DirtyRatio != 0 {
+			if c.DirtyRatio < 0 || c.DirtyRatio > 1 {
+				return nil, fmt.Errorf("dirtyRatio %v not between 0 and 1", c.DirtyRatio)
+			}
+			configs["min.cleanable.dirty.ratio"] = strconv.FormatFloat(c.DirtyRatio, 'f', -1, 64)
+		}
+	}
+
+	for name, value := range t.Config {
+		if _, ok := configs[name]; ok {
+			return nil, fmt.Errorf("config %s is set by the spec fields", name)
+		}
+		configs[name] = value
+	}
+	return configs, nil
+}
+
+// milliseconds converts a duration like 90m or 7d to milliseconds, -1 for
+// RETENTION_FOREVER.
+func milliseconds(s string) (string, error) {
+	if s == RETENTION_FOREVER {
+		return "-1", nil
+	}
+
+	var d time.Duration
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return "", fmt.Errorf("invalid duration %q", s)
+		}
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if d, err = time.ParseDuration(s); err != nil {
+			return "", err
+		}
+	}
+
+	if d < 0 {
+		return "", fmt.Errorf("negative duration %q", s)
+	}
+	return strconv.FormatInt(d.Milliseconds(), 10), nil
+}
+
+// TopicState is a topic as the broker describes it.
+type TopicState struct {
+	Name        string
+	Partitions  int
+	Replication int
+	// Every config of the topic, defaults included
+	Configs map[string]string
+}
+
+// Topics describes the topics of the broker, internal topics excluded.
+func Topics(ctx context.Context, config Config) (map[string]TopicState, error) {
+	return config.broker().Topics(ctx)
+}
+
+// TopicDrift is a setting of a topic that differs from its spec.
+type TopicDrift struct {
+	Topic   string
+	Setting string
+	Want    string
+	Got     string
+	// False when Kafka can't change the setting in place: partitions can
+	// only be added, the replication needs a reassignment.
+	Fixable bool
+}
+
+func (d TopicDrift) String() string {
+	s := fmt.Sprintf("%s: %s is %s, want %s", d.Topic, d.Setting, d.Got, d.Want)
+	if !d.Fixable {
+		s += " (fix by hand)"
+	}
+	return s
+}
+
+// ProvisionReport lists what Provision did, or would do in a dry run.
+type ProvisionReport struct {
+	DryRun  bool
+	Created []string
+	// Fixed unless DryRun, except the drifts that aren't Fixable
+	Drift []TopicDrift
+	// Topics of the broker that no spec declares, e.g. created on first use
+	// by a producer
+	Undeclared []string
+}
+
+// InSync tells whether the broker already matched the specs.
+func (r ProvisionReport) InSync() bool {
+	return len(r.Created) == 0 && len(r.Drift) == 0 && len(r.Undeclared) == 0
+}
+
+func (r ProvisionReport) String() string {
+	if r.InSync() {
+		return "topics in sync"
+	}
+
+	created, fixed := "created", "fixed"
+	if r.DryRun {
+		created, fixed = "to create", "to fix"
+	}
+
+	var b strings.Builder
+	for _, topic := range r.Created {
+		fmt.Fprintf(&b, "%s: %s\n", topic, created)
+	}
+	for _, d := range r.Drift {
+		if d.Fixable {
+			fmt.Fprintf(&b, "%s, %s\n", d, fixed)
+		} else {
+			fmt.Fprintf(&b, "%s\n", d)
+		}
+	}
+	for _, topic := range r.Undeclared {
+		fmt.Fprintf(&b, "%s: not declared\n", topic)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// Provision reconciles the topics of the broker with specs: missing topics
+// are created, partitions added and configs set. Nothing is deleted, the
+// topics without spec and the drifts Kafka can't fix in place are only
+// reported. A dry run changes nothing.
+func Provision(ctx context.Context, config Config, specs TopicSpecs, dryRun bool) (ProvisionReport, error) {
+	broker := config.broker()
+
+	state, err := broker.Topics(ctx)
+	if err != nil {
+		return ProvisionReport{}, err
+	}
+
+	report, err := planTopics(specs, state)
+	if err != nil {
+		return ProvisionReport{}, err
+	}
+	report.DryRun = dryRun
+	if dryRun {
+		return report, nil
+	}
+
+	for _, name := range report.Created {
+		t, _ := specs.Lookup(name)
+		configs, _ := t.Configs()
+		topic := TopicState{Name: t.Name, Partitions: t.Partitions, Replication: t.Replication, Configs: configs}
+		if err := broker.CreateTopic(ctx, topic); err != nil {
+			return report, fmt.Errorf("creating %s: %w", name, err)
+		}
+	}
+
+	alter := make(map[string]map[string]string)
+	for _, d := range report.Drift {
+		switch {
+		case !d.Fixable:
+		case d.Setting == "partitions":
+			count, _ := strconv.Atoi(d.Want)
+			if err := broker.CreatePartitions(ctx, d.Topic, count); err != nil {
+				return report, fmt.Errorf("adding partitions to %s: %w", d.Topic, err)
+			}
+		default:
+			if alter[d.Topic] == nil {
+				alter[d.Topic] = make(map[string]string)
+			}
+			alter[d.Topic][d.Setting] = d.Want
+		}
+	}
+
+	for _, topic := range slices.Sorted(maps.Keys(alter)) {
+		if err := broker.AlterTopicConfig(ctx, topic, alter[topic]); err != nil {
+			return report, fmt.Errorf("configuring %s: %w", topic, err)
+		}
+	}
+	return report, nil
+}
+
+// planTopics compares specs with the state of the broker.
+func planTopics(specs TopicSpecs, state map[string]TopicState) (ProvisionReport, error) {
+	var report ProvisionReport
+
+	for _, t := range specs.Topics {
+		want, err := t.Configs()
+		if err != nil {
+			return ProvisionReport{}, fmt.Errorf("%s: %w", t.Name, err)
+		}
+
+		got, ok := state[t.Name]
+		if !ok {
+			report.Created = append(report.Created, t.Name)
+			continue
+		}
+
+		if got.Partitions != t.Partitions {
+			report.Drift = append(report.Drift, TopicDrift{
+				Topic:   t.Name,
+				Setting: "partitions",
+				Want:    strconv.Itoa(t.Partitions),
+				Got:     strconv.Itoa(got.Partitions),
+				Fixable: got.Partitions < t.Partitions,
+			})
+		}
+		if got.Replication != t.Replication {
+			report.Drift = append(report.Drift, TopicDrift{
+				Topic:   t.Name,
+				Setting: "replication",
+				Want:    strconv.Itoa(t.Replication),
+				Got:     strconv.Itoa(got.Replication),
+			})
+		}
+
+		for _, name := range slices.Sorted(maps.Keys(want)) {
+			if value, ok := got.Configs[name]; !ok || value != want[name] {
+				if !ok {
+					value = "unset"
+				}
+				report.Drift = append(report.Drift, TopicDrift{Topic: t.Name, Setting: name, Want: want[name], Got: value, Fixable: true})
+			}
+		}
+	}
+
+	report.Undeclared = specs.Undeclared(slices.Collect(maps.Keys(state))...)
+	return report, nil
+}
@@ -0,0 +1,134 @@
+package kafka
+
+import (
+	"fmt"
+	"testing"
+)
+
+const testSpecs = `
+defaults:
+  partitions: 3
+  replication: 1
+  retention: 7d
+  cleanup: delete
+  config:
+    max.message.bytes: "1048576"
+
+topics:
+  - name: orders
+    partitions: 6
+    retention: forever
+  - name: stock
+    cleanup: compact
+    compaction:
+      minLag: 1h
+      dirtyRatio: 0.2
+    config:
+      segment.ms: "3600000"
+`
+
+func TestParseTopicSpecs(t *testing.T) {
+	specs, err := ParseTopicSpecs([]byte(testSpecs))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stock, ok := specs.Lookup("stock")
+	if !ok || stock.Partitions != 3 || stock.Replication != 1 {
+		t.Fatalf("stock = %+v", stock)
+	}
+	configs, err := stock.Configs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "map[cleanup.policy:compact max.message.bytes:1048576 min.cleanable.dirty.ratio:0.2 min.compaction.lag.ms:3600000 retention.ms:604800000 segment.ms:3600000]"
+	if got := fmt.Sprint(configs); got != want {
+		t.Errorf("stock configs = %s", got)
+	}
+
+	orders, _ := specs.Lookup("orders")
+	if configs, _ := orders.Configs(); orders.Partitions != 6 || configs["retention.ms"] != "-1" {
+		t.Errorf("orders = %+v, configs %v", orders, configs)
+	}
+
+	if got := fmt.Sprint(specs.Undeclared("orders", "logs", "__consumer_offsets", "logs")); got != "[logs]" {
+		t.Errorf("undeclared = %s", got)
+	}
+
+	for _, invalid := range []string{
+		"topics: [{name: a}]",
+		"defaults: {partitions: 1, replication: 1}\ntopics: [{name: a}, {name: a}]",
+		"defaults: {partitions: 1, replication: 1}\ntopics: [{name: a, retention: 7 days}]",
+		"defaults: {partitions: 1, replication: 1}\ntopics: [{name: a, cleanup: delete, compaction: {minLag: 1h}}]",
+		"defaults: {partitions: 1, replication: 1}\ntopics: [{name: a, config: {retention.ms: '1'}, retention: 1d}]",
+		"defaults: {partitions: 1, replication: 1}\ntopics: [{name: a, partition: 2}]",
+	} {
+		if _, err := ParseTopicSpecs([]byte(invalid)); err == nil {
+			t.Errorf("accepted %q", invalid)
+		}
+	}
+}
+
+func TestProvision(t *testing.T) {
+	ctx := testContext(t)
+	config, broker := newMemoryConfig(1)
+
+	specs, err := ParseTopicSpecs([]byte(testSpecs))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Created on first use with the broker's defaults
+	send(t, ctx, config, "stock", event{"a", 1})
+	send(t, ctx, config, "audit", event{"a", 1})
+
+	dry, err := Provision(ctx, config, specs, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(dry.Created) != "[orders]" || len(dry.Drift) != 7 || fmt.Sprint(dry.Undeclared) != "[audit]" {
+		t.Errorf("dry run = %s", dry)
+	}
+
+	report, err := Provision(ctx, config, specs, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(report.Created) != fmt.Sprint(dry.Created) || len(report.Drift) != len(dry.Drift) {
+		t.Errorf("provisioned %s\nafter a dry run of %s", report, dry)
+	}
+
+	topics, err := broker.Topics(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if topics["orders"].Partitions != 6 || topics["stock"].Partitions != 3 || topics["stock"].Configs["cleanup.policy"] != CLEANUP_COMPACT {
+		t.Errorf("topics = %+v", topics)
+	}
+	if messages := broker.Messages("stock"); len(messages) != 1 {
+		t.Errorf("stock has %d messages", len(messages))
+	}
+
+	// Only the undeclared topic is left
+	report, err = Provision(ctx, config, specs, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Created) != 0 || len(report.Drift) != 0 || fmt.Sprint(report.Undeclared) != "[audit]" {
+		t.Errorf("second run = %s", report)
+	}
+
+	// Kafka can't remove partitions nor change the replication in place
+	specs.Topics[0].Partitions = 4
+	specs.Topics[0].Replication = 3
+	report, err = Provision(ctx, config, specs, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Drift) != 2 || report.Drift[0].Fixable || report.Drift[1].Fixable {
+		t.Errorf("drift = %s", report)
+	}
+	if topics, _ := broker.Topics(ctx); topics["orders"].Partitions != 6 {
+		t.Errorf("orders has %d partitions", topics["orders"].Partitions)
+	}
+}
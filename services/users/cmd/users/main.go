@@ -0,0 +1,30 @@
+package main
+
+import (
+	"eda-shared/kafka"
+	users "eda-users"
+	"log"
+	"net/http"
+)
+
+func main() {
+
+	config, err := kafka.ConfigFromEnv("users-service")
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	service, err := users.New(users.Config{Kafka: config})
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer service.Close()
+
+	log.Println("Serveur up and running...")
+	err = http.ListenAndServe(users.PORT, service.Handler())
+
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+}
@@ -2,13 +2,16 @@ module eda-users
 
 go 1.25.2
 
+require go.mongodb.org/mongo-driver v1.17.6
+
 require (
-	github.com/golang-jwt/jwt/v5 v5.3.0
-	github.com/segmentio/kafka-go v0.4.49
-	go.mongodb.org/mongo-driver v1.17.6
+	github.com/golang-jwt/jwt/v5 v5.3.0 // indirect
+	github.com/segmentio/kafka-go v0.4.49 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
 
 require (
+	eda-shared v0.0.0
 	github.com/golang/snappy v0.0.4 // indirect
 	github.com/klauspost/compress v1.16.7 // indirect
 	github.com/montanaflynn/stats v0.7.1 // indirect
@@ -21,3 +24,5 @@ require (
 	golang.org/x/sync v0.12.0 // indirect
 	golang.org/x/text v0.23.0 // indirect
 )
+
+replace eda-shared => ../shared
@@ -16,9 +16,11 @@ db.users.insertMany([
   {
     username: "rick sanchez",
     passwordHash: "cbfdac6008f9cab4083784cbd1874f76618d2a97",
+    role: "admin",
   },
   {
     username: "morty smith",
     passwordHash: "cbfdac6008f9cab4083784cbd1874f76618d2a97",
+    role: "user",
   },
 ]);
@@ -2,56 +2,51 @@ package internal
 
 import (
 	"context"
-	"encoding/json"
 
-	"github.com/segmentio/kafka-go"
+	"eda-shared/kafka"
 )
 
 const (
-	TOPIC          = "notifications.central"
-	BROKER_ADDRESS = "kafka:29092"
+	TOPIC = "notifications.central"
+
+	// Notification types
+	USER_REGISTERED = "user.registered"
+	USER_LOGGED_IN  = "user.logged_in"
 )
 
+// Notification is rendered by notifications-service from its type and params
 type Notification struct {
-	Action string `json:"action"`
+	// Unique per event, lets notifications-service drop redeliveries
+	EventID string         `json:"event_id" example:"9f1c2a7e4b3d"`
+	Type    string         `json:"type" example:"user.registered"`
+	UserID  string         `json:"user_id,omitempty" example:"alice"`
+	Params  map[string]any `json:"params,omitempty" example:"{\"username\":\"alice\"}"`
 }
 
-type KafkaClient struct {
-	writer *kafka.Writer
+// Notifier publishes user notifications
+type Notifier struct {
+	producer *kafka.Producer[Notification]
 }
 
-func NewKafkaClient() *KafkaClient {
-	writer := &kafka.Writer{
-		Addr:  kafka.TCP(BROKER_ADDRESS),
-		Topic: TOPIC,
-	}
-
-	return &KafkaClient{
-		writer,
-	}
-}
-
-func (k KafkaClient) Send(message string) error {
-
-	notification, err := json.Marshal(Notification{Action: message})
+func NewNotifier(config kafka.Config) (*Notifier, error) {
+	producer, err := kafka.NewProducer[Notification](config, kafka.DefaultProducerConfig(TOPIC))
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	msg := kafka.Message{
-		Key:   []byte("notification"),
-		Value: notification,
-	}
-
-	err = k.writer.WriteMessages(context.Background(), msg)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return &Notifier{producer}, nil
+}
 
+// Send is keyed by username so the notifications of a user stay in order
+func (n Notifier) Send(ctx context.Context, notificationType, username string, params map[string]any) error {
+	return n.producer.Send(ctx, username, Notification{
+		EventID: kafka.NewID(),
+		Type:    notificationType,
+		UserID:  username,
+		Params:  params,
+	})
 }
 
-func (k KafkaClient) Close() {
-	k.Close()
+func (n Notifier) Close() {
+	_ = n.producer.Close()
 }
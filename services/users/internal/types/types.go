@@ -1,11 +1,16 @@
 package types
 
+import "context"
+
+// IDatabase stores the users. web.Database (MongoDB) and web.MemoryDatabase
+// pass the same conformance suite.
 type IDatabase interface {
 	Register(username, password string) error
-	Login(username, password string) error
+	// Login returns the role of the authenticated user
+	Login(username, password string) (string, error)
 }
 
 type IBroker interface {
-	Send(message string) error
+	Send(ctx context.Context, notificationType, username string, params map[string]any) error
 	Close()
 }
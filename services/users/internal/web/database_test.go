@@ -0,0 +1,86 @@
+package web
+
+import (
+	"context"
+	. "eda-users/internal/types"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+// testDatabase is the conformance suite of IDatabase: open returns an empty
+// database.
+func testDatabase(t *testing.T, open func(t *testing.T) IDatabase) {
+	t.Run("RegisterThenLogin", func(t *testing.T) {
+		db := open(t)
+
+		if err := db.Register("alice", "secret"); err != nil {
+			t.Fatal(err)
+		}
+
+		role, err := db.Login("alice", "secret")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if role != ROLE_USER {
+			t.Errorf("role = %q, want %q", role, ROLE_USER)
+		}
+	})
+
+	t.Run("RegisterTwice", func(t *testing.T) {
+		db := open(t)
+
+		if err := db.Register("alice", "secret"); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Register("alice", "other"); err == nil {
+			t.Error("second registration succeeded")
+		}
+
+		// The first password still works
+		if _, err := db.Login("alice", "secret"); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("LoginFailures", func(t *testing.T) {
+		db := open(t)
+
+		if err := db.Register("alice", "secret"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Login("alice", "wrong"); err == nil {
+			t.Error("login with a wrong password succeeded")
+		}
+		if _, err := db.Login("bob", "secret"); err == nil {
+			t.Error("login of an unknown user succeeded")
+		}
+	})
+}
+
+func TestMemoryDatabase(t *testing.T) {
+	testDatabase(t, func(t *testing.T) IDatabase {
+		return NewMemoryDatabase()
+	})
+}
+
+// TestMongoDatabase needs a MongoDB server, e.g.
+// MONGO_TEST_URI=mongodb://localhost:27017
+func TestMongoDatabase(t *testing.T) {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI not set")
+	}
+
+	testDatabase(t, func(t *testing.T) IDatabase {
+		db, err := connect(uri, fmt.Sprintf("user_db_test_%d", time.Now().UnixNano()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			_ = db.conn.Drop(context.Background())
+		})
+		return db
+	})
+}
@@ -1,27 +1,23 @@
 package web
 
 import (
+	"eda-shared/auth"
+	"eda-shared/env"
 	"fmt"
 	"time"
-
-	"github.com/golang-jwt/jwt/v5"
 )
 
 const (
 	SIGNING_KEY = "signing-key"
 )
 
-func CreateClaims() (string, error) {
-	key := []byte(SIGNING_KEY)
-
-	claims := &jwt.RegisteredClaims{
-		ExpiresAt: jwt.NewNumericDate(time.Unix(1962978897, 0)),
-		Issuer:    "test",
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	ss, err := token.SignedString(key)
+func signingKey() []byte {
+	return []byte(env.Get("JWT_SIGNING_KEY", SIGNING_KEY))
+}
 
+func CreateClaims(username, role string) (string, error) {
+	// Verified by the other services with auth.Verify
+	ss, err := auth.Sign(signingKey(), username, role, time.Unix(1962978897, 0))
 	if err != nil {
 		return "", fmt.Errorf("error signing token: %v", err)
 	}
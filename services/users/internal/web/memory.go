@@ -0,0 +1,43 @@
+package web
+
+import (
+	"fmt"
+	"sync"
+)
+
+// MemoryDatabase keeps the users in memory, for tests and local runs
+type MemoryDatabase struct {
+	mu    sync.Mutex
+	users map[string]User
+}
+
+func NewMemoryDatabase() *MemoryDatabase {
+	return &MemoryDatabase{users: make(map[string]User)}
+}
+
+func (db *MemoryDatabase) Register(username, password string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if _, ok := db.users[username]; ok {
+		return fmt.Errorf("user already exists")
+	}
+
+	db.users[username] = User{Username: username, Password: hashPassword(password), Role: ROLE_USER}
+	return nil
+}
+
+func (db *MemoryDatabase) Login(username, password string) (string, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	user, ok := db.users[username]
+	if !ok || user.Password != hashPassword(password) {
+		return "", fmt.Errorf("user not found")
+	}
+
+	if user.Role == "" {
+		return ROLE_USER, nil
+	}
+	return user.Role, nil
+}
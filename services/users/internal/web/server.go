@@ -1,6 +1,7 @@
 package web
 
 import (
+	"eda-users/internal"
 	. "eda-users/internal/types"
 	"errors"
 	"fmt"
@@ -57,9 +58,14 @@ func (s Server) Register(w http.ResponseWriter, r *http.Request) {
 
 	output := fmt.Sprintf("User %s registered successfully", username)
 
-	log.Println(output)
+	err = s.Kakfa.Send(r.Context(), internal.USER_REGISTERED, username, map[string]any{"username": username})
 
-	s.Kakfa.Send(output)
+	if err != nil {
+		http.Error(w, "Error sending log to Kafka", http.StatusInternalServerError)
+		return
+	}
+
+	log.Println(output)
 
 	w.WriteHeader(http.StatusCreated)
 	w.Write([]byte(output))
@@ -78,14 +84,14 @@ func (s Server) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = s.Db.Login(username, password)
+	role, err := s.Db.Login(username, password)
 
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	token, err := CreateClaims()
+	token, err := CreateClaims(username, role)
 
 	if err != nil {
 		http.Error(w, "Error creating token", http.StatusInternalServerError)
@@ -94,7 +100,7 @@ func (s Server) Login(w http.ResponseWriter, r *http.Request) {
 
 	output := fmt.Sprintf("User %s successfully logged\n", username)
 
-	err = s.Kakfa.Send(output)
+	err = s.Kakfa.Send(r.Context(), internal.USER_LOGGED_IN, username, map[string]any{"username": username})
 
 	if err != nil {
 		http.Error(w, "Error sending log to Kafka", http.StatusInternalServerError)
@@ -0,0 +1,66 @@
+// Package users is users-service: registration and login, publishing a
+// notification for each. cmd/users runs it with the environment
+// configuration, tests run it in-process against in-memory dependencies.
+package users
+
+import (
+	"eda-shared/kafka"
+	"eda-users/internal"
+	. "eda-users/internal/types"
+	"eda-users/internal/web"
+	"net/http"
+)
+
+const (
+	PORT = ":3001"
+)
+
+type Config struct {
+	Kafka kafka.Config
+	// Connects to MongoDB when nil
+	Database IDatabase
+}
+
+// NewMemoryDatabase returns a database keeping the users in memory
+func NewMemoryDatabase() IDatabase {
+	return web.NewMemoryDatabase()
+}
+
+type Service struct {
+	notifier *internal.Notifier
+	mux      *http.ServeMux
+}
+
+func New(config Config) (*Service, error) {
+	if config.Kafka.Registry == nil {
+		config.Kafka.Registry = kafka.NewRegistry("users-service")
+	}
+
+	notifier, err := internal.NewNotifier(config.Kafka)
+	if err != nil {
+		return nil, err
+	}
+
+	database := config.Database
+	if database == nil {
+		if database, err = web.NewDatabase(); err != nil {
+			notifier.Close()
+			return nil, err
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/", web.NewServer(database, notifier))
+	mux.Handle("/topology", config.Kafka.Registry)
+	mux.Handle("/asyncapi", config.Kafka.Registry.AsyncAPIHandler())
+
+	return &Service{notifier, mux}, nil
+}
+
+func (s *Service) Handler() http.Handler {
+	return s.mux
+}
+
+func (s *Service) Close() {
+	s.notifier.Close()
+}